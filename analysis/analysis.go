@@ -0,0 +1,121 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package analysis reviews sets of rules matching strings with patterns,
+// such as the rules of web application firewalls, for rules that are
+// redundant, never apply or contradict each other.
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/intersection"
+	"github.com/oulinbao/regexinter/reinter"
+)
+
+// Action is what a rule does with the strings it matches.
+type Action int
+
+const (
+	Deny Action = iota
+	Allow
+)
+
+func (a Action) String() string {
+	if a == Allow {
+		return "allow"
+	}
+	return "deny"
+}
+
+// NamedPattern is a rule of a rule set. Rules are tried in order, the first
+// one matching a string deciding its fate.
+type NamedPattern struct {
+	Name   string
+	Expr   string
+	Action Action
+}
+
+// Redundant is a rule matching only strings another rule with the same
+// action matches as well.
+type Redundant struct {
+	Rule, By string // names of the rules
+	Witness  string // the shortest string both match
+}
+
+// Conflict is a pair of rules with different actions matching a common
+// string.
+type Conflict struct {
+	Allow, Deny string // names of the rules
+	Witness     string // the shortest string both match
+	Winner      string // the rule applying to it, the earlier one
+}
+
+// Report is the outcome of RuleSetReport. The rules of Shadowed are given
+// by their indexes in the rule set.
+type Report struct {
+	Redundant []Redundant
+	Shadowed  []Shadow
+	Conflicts []Conflict
+}
+
+// RuleSetReport compiles the patterns of the rules with the given options
+// and reports the rules that are redundant, shadowed or conflicting. Rules
+// with the same strings are reported once, the later one being redundant.
+func RuleSetReport(patterns []NamedPattern, opts ...reinter.Option) (Report, error) {
+	nodes := make([]*dfa.Node, len(patterns))
+	for i, p := range patterns {
+		var err error
+		if nodes[i], err = reinter.Compile(p.Expr, opts...); err != nil {
+			return Report{}, fmt.Errorf("analysis: rule %s: %w", p.Name, err)
+		}
+	}
+
+	report := Report{Shadowed: shadowed(nodes)}
+	for i, p := range patterns {
+		if dfa.IsEmpty(nodes[i]) {
+			continue
+		}
+		for j, q := range patterns {
+			if j == i {
+				continue
+			}
+			r := intersection.CheckDFA(nodes[i], nodes[j])
+			if !r.Intersects {
+				continue
+			}
+			if p.Action != q.Action {
+				if j > i {
+					c := Conflict{Allow: p.Name, Deny: q.Name, Witness: r.Witness, Winner: p.Name}
+					if p.Action == Deny {
+						c.Allow, c.Deny = q.Name, p.Name
+					}
+					report.Conflicts = append(report.Conflicts, c)
+				}
+				continue
+			}
+			// Of two rules with the same strings, the later one is redundant.
+			if dfa.IsEmpty(dfa.Subtract(nodes[i], nodes[j])) && (j < i || !dfa.IsEmpty(dfa.Subtract(nodes[j], nodes[i]))) {
+				report.Redundant = append(report.Redundant, Redundant{Rule: p.Name, By: q.Name, Witness: r.Witness})
+			}
+		}
+	}
+	return report, nil
+}
+
+// shortest returns the shortest string the automaton accepts, the witness of
+// its intersection with itself.
+func shortest(n *dfa.Node) string {
+	return intersection.CheckDFA(n, n).Witness
+}
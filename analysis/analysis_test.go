@@ -0,0 +1,205 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/reinter"
+)
+
+func TestRuleSetReport(t *testing.T) {
+	rules := []NamedPattern{
+		{"sqli", "(?i).*union +select.*", Deny},
+		{"sqli-upper", ".*UNION SELECT.*", Deny},
+		{"admin", "/admin/.*", Deny},
+		{"admin-login", "/admin/login", Allow},
+		{"health", "/health", Allow},
+		{"health-again", "/health", Allow},
+		{"admin-png", "/admin/.*\\.png", Deny},
+		{"path", "/[a-z]+", Allow},
+	}
+	report, err := RuleSetReport(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantRedundant := []Redundant{
+		{"sqli-upper", "sqli", "UNION SELECT"},
+		{"health", "path", "/health"},
+		{"health-again", "health", "/health"},
+		{"health-again", "path", "/health"},
+		{"admin-png", "admin", "/admin/.png"},
+	}
+	if !reflect.DeepEqual(report.Redundant, wantRedundant) {
+		t.Errorf("Redundant = %v, want %v", report.Redundant, wantRedundant)
+	}
+
+	wantShadowed := []Shadow{
+		{1, []int{0}, "UNION SELECT"},
+		{3, []int{2}, "/admin/login"},
+		{5, []int{4}, "/health"},
+		{6, []int{0, 1, 2}, "/admin/.png"},
+	}
+	if !reflect.DeepEqual(report.Shadowed, wantShadowed) {
+		t.Errorf("Shadowed = %v, want %v", report.Shadowed, wantShadowed)
+	}
+
+	wantConflicts := []Conflict{
+		{"admin-login", "admin", "/admin/login", "admin"},
+	}
+	if !reflect.DeepEqual(report.Conflicts, wantConflicts) {
+		t.Errorf("Conflicts = %v, want %v", report.Conflicts, wantConflicts)
+	}
+}
+
+func TestRuleSetReportOptions(t *testing.T) {
+	rules := []NamedPattern{{"foo", "foo", Deny}, {"bar", "bar", Allow}}
+	report, err := RuleSetReport(rules, reinter.MatchSemantics(reinter.SubstringMatch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Conflicts) != 1 || report.Conflicts[0].Witness != "barfoo" {
+		t.Errorf("Conflicts = %v, want one on barfoo", report.Conflicts)
+	}
+	if _, err := RuleSetReport([]NamedPattern{{"bad", "a(", Deny}}); err == nil {
+		t.Errorf("RuleSetReport accepted an invalid pattern")
+	}
+}
+
+func TestLattice(t *testing.T) {
+	exprs := []string{
+		"/api/.*",       // 0
+		"/api/v1/.*",    // 1
+		"/api/v1/users", // 2
+		"/.*",           // 3
+		"/api/v[12]/.*", // 4
+		"/health",       // 5
+		"/api/v1/(.*)",  // 6, same as 1
+	}
+	edges, err := Lattice(exprs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Edge{
+		{Sub: 0, Super: 3},
+		{Sub: 1, Super: 4},
+		{Sub: 2, Super: 1},
+		{Sub: 4, Super: 0},
+		{Sub: 5, Super: 3},
+		{Sub: 6, Super: 1, Equal: true},
+	}
+	if !reflect.DeepEqual(edges, want) {
+		t.Errorf("Lattice() = %v, want %v", edges, want)
+	}
+
+	edges, err = Lattice([]string{"ADMIN", "admin"}, reinter.CaseInsensitive(true))
+	if err != nil || !reflect.DeepEqual(edges, []Edge{{Sub: 1, Super: 0, Equal: true}}) {
+		t.Errorf("Lattice() with CaseInsensitive = %v, %v", edges, err)
+	}
+	if _, err := Lattice([]string{"a", "("}); err == nil {
+		t.Errorf("Lattice accepted an invalid pattern")
+	}
+}
+
+func TestSortBySpecificity(t *testing.T) {
+	exprs := []string{
+		"/.*",           // 0
+		"/api/.*",       // 1
+		"/api/v1/users", // 2
+		"/health",       // 3
+		"/api/v1/.*",    // 4
+		"/api/v1/(.*)",  // 5, same as 4
+		"/api/.*/users", // 6
+	}
+	order, err := SortBySpecificity(exprs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Order{
+		Order:        []int{2, 3, 4, 5, 6, 1, 0},
+		Ties:         []Pair{{4, 5}},
+		Incomparable: []Overlap{{4, 6, "/api/v1/users"}, {5, 6, "/api/v1/users"}},
+	}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("SortBySpecificity() = %+v, want %+v", order, want)
+	}
+	if _, err := SortBySpecificity([]string{"("}); err == nil {
+		t.Errorf("SortBySpecificity accepted an invalid pattern")
+	}
+}
+
+func TestFindShadowed(t *testing.T) {
+	rules := []string{
+		"/api/v1/.*",    // 0
+		"/api/v2/.*",    // 1
+		"/api/v[12]/x",  // 2, shadowed by 0 and 1 together
+		"/health",       // 3
+		"/api/v3/.*",    // 4
+		"/health",       // 5, shadowed by 3
+		dfa.NoMatch,     // 6, matches nothing
+		"/api/v[1-3]/y", // 7, shadowed by 0, 1 and 4
+	}
+	shadows, err := FindShadowed(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Shadow{
+		{Rule: 2, By: []int{0, 1}, Witness: "/api/v1/x"},
+		{Rule: 5, By: []int{3}, Witness: "/health"},
+		{Rule: 7, By: []int{0, 1, 4}, Witness: "/api/v1/y"},
+	}
+	if !reflect.DeepEqual(shadows, want) {
+		t.Errorf("FindShadowed() = %+v, want %+v", shadows, want)
+	}
+
+	shadows, err = FindShadowed([]string{"ADMIN", "admin"}, reinter.CaseInsensitive(true))
+	if err != nil || len(shadows) != 1 || shadows[0].Rule != 1 {
+		t.Errorf("FindShadowed() with CaseInsensitive = %+v, %v", shadows, err)
+	}
+	if _, err := FindShadowed([]string{"("}); err == nil {
+		t.Errorf("FindShadowed accepted an invalid pattern")
+	}
+}
+
+func TestGroupEquivalent(t *testing.T) {
+	exprs := []string{
+		"/api/(v1|v2)/.*",
+		"/health",
+		"/api/v[12]/.*",
+		"(/health)",
+		"/api/v[1-3]/.*",
+		"/api/v[21]/(.*)",
+	}
+	groups, err := GroupEquivalent(exprs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]int{{0, 2, 5}, {1, 3}, {4}}; !reflect.DeepEqual(groups, want) {
+		t.Errorf("GroupEquivalent() = %v, want %v", groups, want)
+	}
+
+	groups, err = GroupEquivalent([]string{"ADMIN", "admin", "Admin"}, reinter.CaseInsensitive(true))
+	if err != nil || !reflect.DeepEqual(groups, [][]int{{0, 1, 2}}) {
+		t.Errorf("GroupEquivalent() with CaseInsensitive = %v, %v", groups, err)
+	}
+	if groups, err := GroupEquivalent(nil); err != nil || groups != nil {
+		t.Errorf("GroupEquivalent(nil) = %v, %v", groups, err)
+	}
+	if _, err := GroupEquivalent([]string{"("}); err == nil {
+		t.Errorf("GroupEquivalent accepted an invalid pattern")
+	}
+}
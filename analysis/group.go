@@ -0,0 +1,45 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package analysis
+
+import (
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/reinter"
+)
+
+// GroupEquivalent compiles the patterns with the given options and groups
+// the indexes of the patterns matching the same strings, for duplicates to
+// be collapsed. Every pattern is in one group, in increasing order, and the
+// groups are sorted by their first pattern. Patterns are compared by the
+// keys of their languages, see dfa.Key, so each is only compiled and
+// minimized once.
+func GroupEquivalent(exprs []string, opts ...reinter.Option) ([][]int, error) {
+	nodes, err := compileAll(exprs, opts)
+	if err != nil {
+		return nil, err
+	}
+	var groups [][]int
+	index := make(map[dfa.Key]int)
+	for i, n := range nodes {
+		key := dfa.KeyOf(n)
+		g, ok := index[key]
+		if !ok {
+			g = len(groups)
+			index[key] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups, nil
+}
@@ -0,0 +1,104 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/reinter"
+)
+
+// Edge is an edge of the Hasse diagram Lattice returns, between the
+// patterns of the given indexes: every string Sub matches, Super matches as
+// well. Unless Equal, Super matches more strings and no pattern lies in
+// between.
+type Edge struct {
+	Sub, Super int
+	Equal      bool
+}
+
+// Lattice compiles the patterns with the given options and returns the
+// Hasse diagram of their containment order, for hierarchies of patterns to
+// be drawn. Patterns with the same strings are linked to the first of them
+// by Equal edges, that one alone standing for them in the other edges.
+// Edges are sorted by Sub, then Super.
+func Lattice(exprs []string, opts ...reinter.Option) ([]Edge, error) {
+	nodes, err := compileAll(exprs, opts)
+	if err != nil {
+		return nil, err
+	}
+	contains := containment(nodes)
+
+	first := make([]int, len(exprs)) // first pattern with the same strings
+	var edges []Edge
+	for i := range exprs {
+		first[i] = i
+		for j := range i {
+			if contains[i][j] && contains[j][i] {
+				first[i] = first[j]
+				break
+			}
+		}
+	}
+
+	within := func(i, j int) bool { return contains[i][j] && !contains[j][i] }
+	for i := range exprs {
+		if first[i] != i {
+			edges = append(edges, Edge{Sub: i, Super: first[i], Equal: true})
+			continue
+		}
+		for j := range exprs {
+			if first[j] != j || !within(i, j) {
+				continue
+			}
+			direct := true
+			for k := range exprs {
+				if first[k] == k && within(i, k) && within(k, j) {
+					direct = false
+					break
+				}
+			}
+			if direct {
+				edges = append(edges, Edge{Sub: i, Super: j})
+			}
+		}
+	}
+	return edges, nil
+}
+
+// compileAll compiles the patterns, naming the failing one in errors.
+func compileAll(exprs []string, opts []reinter.Option) ([]*dfa.Node, error) {
+	nodes := make([]*dfa.Node, len(exprs))
+	for i, expr := range exprs {
+		var err error
+		if nodes[i], err = reinter.Compile(expr, opts...); err != nil {
+			return nil, fmt.Errorf("analysis: pattern %d: %w", i, err)
+		}
+	}
+	return nodes, nil
+}
+
+// containment returns the containment matrix of the automata: contains[i][j]
+// tells whether automaton j accepts every string automaton i accepts.
+func containment(nodes []*dfa.Node) [][]bool {
+	contains := make([][]bool, len(nodes))
+	for i := range nodes {
+		contains[i] = make([]bool, len(nodes))
+		for j := range nodes {
+			contains[i][j] = i == j || dfa.IsEmpty(dfa.Subtract(nodes[i], nodes[j]))
+		}
+	}
+	return contains
+}
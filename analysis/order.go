@@ -0,0 +1,96 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package analysis
+
+import (
+	"github.com/oulinbao/regexinter/intersection"
+	"github.com/oulinbao/regexinter/reinter"
+)
+
+// Pair is a pair of patterns, by their indexes, the first one the earlier.
+type Pair [2]int
+
+// Overlap is a pair of patterns, by their indexes, matching a common
+// string.
+type Overlap struct {
+	A, B    int
+	Witness string // the shortest string both match
+}
+
+// Order is the outcome of SortBySpecificity.
+type Order struct {
+	// Order holds the indexes of the patterns, every pattern before the
+	// patterns matching more strings than it.
+	Order []int
+
+	// Ties are the patterns with the same strings, which come in their
+	// original order.
+	Ties []Pair
+
+	// Incomparable are the overlapping patterns neither of which matches
+	// every string of the other, whose order still decides which one
+	// applies to the strings they share.
+	Incomparable []Overlap
+}
+
+// SortBySpecificity compiles the patterns with the given options and sorts
+// them topologically by containment, more specific patterns first, for
+// route tables trying routes in order to be ordered safely. Patterns keep
+// their order where containment does not decide it.
+func SortBySpecificity(exprs []string, opts ...reinter.Option) (Order, error) {
+	nodes, err := compileAll(exprs, opts)
+	if err != nil {
+		return Order{}, err
+	}
+	contains := containment(nodes)
+	within := func(i, j int) bool { return contains[i][j] && !contains[j][i] }
+
+	var order Order
+	for i := range exprs {
+		for j := i + 1; j < len(exprs); j++ {
+			switch {
+			case contains[i][j] && contains[j][i]:
+				order.Ties = append(order.Ties, Pair{i, j})
+			case !contains[i][j] && !contains[j][i]:
+				if r := intersection.CheckDFA(nodes[i], nodes[j]); r.Intersects {
+					order.Incomparable = append(order.Incomparable, Overlap{A: i, B: j, Witness: r.Witness})
+				}
+			}
+		}
+	}
+
+	// Kahn's algorithm, taking the first pattern no pattern left is
+	// within each time.
+	placed := make([]bool, len(exprs))
+	for len(order.Order) < len(exprs) {
+		for i := range exprs {
+			if placed[i] {
+				continue
+			}
+			ready := true
+			for j := range exprs {
+				if !placed[j] && within(j, i) {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				placed[i] = true
+				order.Order = append(order.Order, i)
+				break
+			}
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,59 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package analysis
+
+import (
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/intersection"
+	"github.com/oulinbao/regexinter/reinter"
+)
+
+// Shadow is a rule of an ordered list, by its index, every string of which
+// earlier rules match, so that it is unreachable.
+type Shadow struct {
+	Rule    int
+	By      []int  // the earlier rules matching strings of the rule
+	Witness string // the shortest string the rule matches
+}
+
+// FindShadowed compiles the patterns of an ordered rule list with the given
+// options and returns the rules the union of the earlier ones covers. The
+// union is built up rule by rule and kept minimal, so each rule is checked
+// against a single automaton. Rules matching nothing are not reported.
+func FindShadowed(ordered []string, opts ...reinter.Option) ([]Shadow, error) {
+	nodes, err := compileAll(ordered, opts)
+	if err != nil {
+		return nil, err
+	}
+	return shadowed(nodes), nil
+}
+
+// shadowed returns the automata the union of the earlier ones covers.
+func shadowed(nodes []*dfa.Node) []Shadow {
+	var shadows []Shadow
+	union := dfa.Union()
+	for i, n := range nodes {
+		if !dfa.IsEmpty(n) && dfa.IsEmpty(dfa.Subtract(n, union)) {
+			s := Shadow{Rule: i, Witness: shortest(n)}
+			for j := range i {
+				if intersection.CheckDFA(nodes[j], n).Intersects {
+					s.By = append(s.By, j)
+				}
+			}
+			shadows = append(shadows, s)
+		}
+		union = dfa.Minimize(dfa.Union(union, n))
+	}
+	return shadows
+}
@@ -0,0 +1,163 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oulinbao/regexinter/reinter"
+)
+
+// batchReport is the outcome of a batch run, as written in JSON.
+type batchReport struct {
+	Patterns  []string    `json:"patterns"`
+	Pairs     []batchPair `json:"pairs"`
+	ElapsedNS int64       `json:"elapsed_ns"`
+}
+
+// batchPair is the check of two patterns, identified by their indexes.
+type batchPair struct {
+	A           int    `json:"a"`
+	B           int    `json:"b"`
+	Intersects  bool   `json:"intersects"`
+	Witness     string `json:"witness"`
+	Approximate bool   `json:"approximate,omitempty"`
+	Error       string `json:"error,omitempty"`
+	DurationNS  int64  `json:"duration_ns"`
+}
+
+// batch checks every pair of the patterns of a file, one per line, and
+// writes the results for other programs to read.
+func batch(args []string) {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	options := optionFlags(fs)
+	input := fs.String("input", "", "read the patterns from the `file`, one per line, - for the standard input")
+	format := fs.String("format", "json", "output `format`, json or csv")
+	fs.Usage = func() {
+		fmt.Println(`Usage: regexinter batch [flags] -input file
+
+Checks every pair of patterns of the file for intersection. Empty lines are
+skipped.
+
+Flags:`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *input == "" || fs.NArg() > 0 || *format != "json" && *format != "csv" {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	patterns, err := readPatterns(*input)
+	if err != nil {
+		log.Fatal(err)
+	}
+	report := runBatch(patterns, options())
+	if *format == "csv" {
+		err = writeCSV(os.Stdout, report)
+	} else {
+		err = writeJSON(os.Stdout, report)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func readPatterns(name string) ([]string, error) {
+	r := io.Reader(os.Stdin)
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var patterns []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns, scanner.Err()
+}
+
+// runBatch checks every pair of patterns. Errors, such as invalid patterns
+// or exceeded limits, are reported with the pairs rather than stopping the
+// run.
+func runBatch(patterns []string, opts []reinter.Option) batchReport {
+	start := time.Now()
+	report := batchReport{Patterns: patterns, Pairs: []batchPair{}}
+	for i := range patterns {
+		for j := i + 1; j < len(patterns); j++ {
+			begin := time.Now()
+			result, err := reinter.Check(patterns[i], patterns[j], opts...)
+			pair := batchPair{
+				A:           i,
+				B:           j,
+				Intersects:  result.Intersects,
+				Witness:     result.Witness,
+				Approximate: result.Approximate,
+				DurationNS:  time.Since(begin).Nanoseconds(),
+			}
+			if err != nil {
+				pair.Error = err.Error()
+			}
+			report.Pairs = append(report.Pairs, pair)
+		}
+	}
+	report.ElapsedNS = time.Since(start).Nanoseconds()
+	return report
+}
+
+func writeJSON(w io.Writer, report batchReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// writeCSV writes a row per pair, with the patterns spelled out.
+func writeCSV(w io.Writer, report batchReport) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"a", "b", "pattern_a", "pattern_b", "intersects", "witness", "approximate", "error", "duration_ns"})
+	for _, p := range report.Pairs {
+		cw.Write([]string{
+			strconv.Itoa(p.A),
+			strconv.Itoa(p.B),
+			report.Patterns[p.A],
+			report.Patterns[p.B],
+			strconv.FormatBool(p.Intersects),
+			p.Witness,
+			strconv.FormatBool(p.Approximate),
+			p.Error,
+			strconv.FormatInt(p.DurationNS, 10),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
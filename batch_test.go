@@ -0,0 +1,42 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	report := runBatch([]string{"a+", "a*", "("}, nil)
+	if len(report.Pairs) != 3 {
+		t.Fatalf("got %d pairs, want 3", len(report.Pairs))
+	}
+	if p := report.Pairs[0]; !p.Intersects || p.Witness != "a" || p.Error != "" {
+		t.Errorf("a+ vs a* = %+v", p)
+	}
+	if p := report.Pairs[2]; p.Error == "" {
+		t.Errorf("a* vs ( = %+v, want an error", p)
+	}
+
+	var b bytes.Buffer
+	if err := writeCSV(&b, report); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[1], "0,1,a+,a*,true,a,false,,") {
+		t.Errorf("writeCSV wrote\n%s", b.String())
+	}
+}
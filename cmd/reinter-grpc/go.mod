@@ -0,0 +1,7 @@
+module github.com/oulinbao/regexinter/cmd/reinter-grpc
+
+go 1.24
+
+require github.com/oulinbao/regexinter v0.0.0
+
+replace github.com/oulinbao/regexinter => ../..
@@ -0,0 +1,299 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/intersection"
+	"github.com/oulinbao/regexinter/reinter"
+)
+
+// servicePath is the path prefix of the methods of the service.
+const servicePath = "/reinter.v1.Reinter/"
+
+// maxMessageSize is the largest request accepted, the default of gRPC.
+const maxMessageSize = 4 << 20
+
+// Status codes of gRPC.
+const (
+	codeOK                = 0
+	codeInvalidArgument   = 3
+	codeResourceExhausted = 8
+	codeUnimplemented     = 12
+	codeInternal          = 13
+)
+
+// statusError is an error returned to the client with a status code.
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+// server serves the Reinter service of proto/reinter/v1/reinter.proto over
+// HTTP/2, following the gRPC protocol: every call carries one message
+// prefixed by a compression flag and its length, and the status is sent in
+// the grpc-status and grpc-message trailers.
+type server struct {
+	// Bounds of every call, which requests can only lower; 0 for none.
+	maxStates int
+	timeout   time.Duration
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	if r.Method != http.MethodPost || r.ProtoMajor != 2 || contentType != "application/grpc" && contentType != "application/grpc+proto" {
+		http.Error(w, "gRPC requests only", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+
+	resp, err := s.call(r)
+	if err == nil {
+		_, err = w.Write(frame(resp))
+	}
+	writeStatus(w, err)
+}
+
+// call runs the method of the request and returns the response message.
+func (s *server) call(r *http.Request) ([]byte, error) {
+	method, ok := strings.CutPrefix(r.URL.Path, servicePath)
+	if !ok || method != "Compile" && method != "Check" {
+		return nil, &statusError{codeUnimplemented, "unknown method " + r.URL.Path}
+	}
+	msg, err := readMessage(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if method == "Compile" {
+		var req compileRequest
+		if err := req.unmarshal(msg); err != nil {
+			return nil, &statusError{codeInvalidArgument, err.Error()}
+		}
+		resp, err := s.compile(req)
+		if err != nil {
+			return nil, err
+		}
+		return resp.marshal(), nil
+	}
+	var req checkRequest
+	if err := req.unmarshal(msg); err != nil {
+		return nil, &statusError{codeInvalidArgument, err.Error()}
+	}
+	resp, err := s.check(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.marshal(), nil
+}
+
+func (s *server) compile(req compileRequest) (compileResponse, error) {
+	n, err := s.compilePattern(req.pattern)
+	if err != nil {
+		return compileResponse{}, err
+	}
+	fingerprint := dfa.Fingerprint(n)
+	return compileResponse{automaton{data: dfa.Marshal(n), fingerprint: fingerprint[:]}}, nil
+}
+
+// check runs reinter.Check if both operands are patterns with the same
+// options and checks their automata otherwise, within the smallest bounds of
+// the patterns and the server.
+func (s *server) check(req checkRequest) (checkResult, error) {
+	var result intersection.Result
+	first, second := req.first.pattern, req.second.pattern
+	if first != nil && second != nil && first.options == second.options {
+		opts, err := s.options(first.options)
+		if err != nil {
+			return checkResult{}, err
+		}
+		result, err = reinter.Check(first.expr, second.expr, opts...)
+		if err != nil {
+			return checkResult{}, patternError(err)
+		}
+	} else {
+		node1, err := s.operandNode(req.first)
+		if err != nil {
+			return checkResult{}, err
+		}
+		node2, err := s.operandNode(req.second)
+		if err != nil {
+			return checkResult{}, err
+		}
+		maxStates, timeout := s.maxStates, s.timeout
+		for _, p := range []*pattern{first, second} {
+			if p != nil {
+				maxStates = bound(maxStates, int(p.options.maxStates))
+				timeout = bound(timeout, time.Duration(p.options.timeoutNanos))
+			}
+		}
+		limits := dfa.Limits{MaxStates: maxStates}
+		if timeout > 0 {
+			limits.Deadline = time.Now().Add(timeout)
+		}
+		result, err = intersection.CheckDFAWithLimits(node1, node2, limits)
+		if err != nil {
+			return checkResult{}, patternError(err)
+		}
+		for _, p := range []*pattern{first, second} {
+			if p != nil && p.options.overApproximate {
+				result.Approximate = true
+			}
+		}
+	}
+	return checkResult{
+		intersects:     result.Intersects,
+		witness:        result.Witness,
+		statesExplored: int64(result.StatesExplored),
+		explanation:    result.Explanation,
+		approximate:    result.Approximate,
+	}, nil
+}
+
+// operandNode returns the automaton of an operand.
+func (s *server) operandNode(o operand) (*dfa.Node, error) {
+	switch {
+	case o.pattern != nil:
+		return s.compilePattern(*o.pattern)
+	case o.automaton != nil:
+		n, err := dfa.Unmarshal(o.automaton.data)
+		if err != nil {
+			return nil, &statusError{codeInvalidArgument, "automaton: " + err.Error()}
+		}
+		return n, nil
+	}
+	return nil, &statusError{codeInvalidArgument, "operand has neither a pattern nor an automaton"}
+}
+
+func (s *server) compilePattern(p pattern) (*dfa.Node, error) {
+	opts, err := s.options(p.options)
+	if err != nil {
+		return nil, err
+	}
+	n, err := reinter.Compile(p.expr, opts...)
+	if err != nil {
+		return nil, patternError(err)
+	}
+	return n, nil
+}
+
+// options converts PatternOptions into the options of package reinter,
+// within the bounds of the server.
+func (s *server) options(o patternOptions) ([]reinter.Option, error) {
+	if o.semantics < 0 || o.semantics > int32(reinter.SubstringMatch) {
+		return nil, &statusError{codeInvalidArgument, "unknown semantics " + strconv.Itoa(int(o.semantics))}
+	}
+	opts := []reinter.Option{
+		reinter.MatchSemantics(reinter.Semantics(o.semantics)),
+		reinter.CaseInsensitive(o.caseInsensitive),
+		reinter.UnicodeMode(o.unicodeMode),
+		reinter.OverApproximate(o.overApproximate),
+		reinter.ASCIIOnly(o.asciiOnly),
+	}
+	if maxStates := bound(s.maxStates, int(o.maxStates)); maxStates > 0 {
+		opts = append(opts, reinter.MaxStates(maxStates))
+	}
+	if timeout := bound(s.timeout, time.Duration(o.timeoutNanos)); timeout > 0 {
+		opts = append(opts, reinter.Timeout(timeout))
+	}
+	return opts, nil
+}
+
+// bound returns the smaller of two bounds, 0 standing for none.
+func bound[T int | time.Duration](a, b T) T {
+	if a <= 0 || b > 0 && b < a {
+		return max(b, 0)
+	}
+	return a
+}
+
+// patternError returns the status of an error of package reinter: patterns
+// exceeding the limits exhaust resources, the others are invalid.
+func patternError(err error) error {
+	var limit *reinter.ErrLimitExceeded
+	if errors.Is(err, reinter.ErrBudgetExceeded) || errors.As(err, &limit) {
+		return &statusError{codeResourceExhausted, err.Error()}
+	}
+	return &statusError{codeInvalidArgument, err.Error()}
+}
+
+// readMessage reads the single message of a request.
+func readMessage(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, &statusError{codeInvalidArgument, "reading the request: " + err.Error()}
+	}
+	if prefix[0] != 0 {
+		return nil, &statusError{codeUnimplemented, "compressed messages are not supported"}
+	}
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > maxMessageSize {
+		return nil, &statusError{codeResourceExhausted, "request larger than " + strconv.Itoa(maxMessageSize) + " bytes"}
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, &statusError{codeInvalidArgument, "reading the request: " + err.Error()}
+	}
+	return msg, nil
+}
+
+// frame prefixes an uncompressed message with its length.
+func frame(msg []byte) []byte {
+	b := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(b[1:], uint32(len(msg)))
+	return append(b, msg...)
+}
+
+// writeStatus sends the status of the call in the trailers.
+func writeStatus(w http.ResponseWriter, err error) {
+	code, msg := codeOK, ""
+	if err != nil {
+		code, msg = codeInternal, err.Error()
+		var serr *statusError
+		if errors.As(err, &serr) {
+			code = serr.code
+		}
+	}
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(code))
+	if msg != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", percentEncode(msg))
+	}
+}
+
+// percentEncode encodes the bytes of a grpc-message that are not printable
+// ASCII, and %.
+func percentEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < ' ' || c > '~' || c == '%' {
+			b.WriteString("%" + strings.ToUpper(strconv.FormatUint(uint64(c)|0x100, 16)[1:]))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
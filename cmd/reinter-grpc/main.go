@@ -0,0 +1,59 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Command reinter-grpc is the reference server of the Reinter service of
+// proto/reinter/v1/reinter.proto, for calling the engine from other
+// languages:
+//
+//	reinter-grpc -addr :50051
+//
+// It serves gRPC over HTTP/2 without TLS unless -tls-cert and -tls-key are
+// given. Every call is bounded by -max-states and -timeout, which the
+// max_states and timeout_nanos options of patterns can only lower.
+//
+// It lives in a module of its own, as it needs Go 1.24 for HTTP/2 without
+// TLS in package net/http, and encodes the messages itself rather than
+// carrying a protobuf dependency into the main module.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+)
+
+func main() {
+	log.SetFlags(0)
+
+	addr := flag.String("addr", ":50051", "listen on `address`")
+	cert := flag.String("tls-cert", "", "serve TLS with the certificate of the `file`")
+	key := flag.String("tls-key", "", "serve TLS with the private key of the `file`")
+	maxStates := flag.Int("max-states", 100000, "bound the states of every automaton and product to `n`, 0 for no bound")
+	timeout := flag.Duration("timeout", 10*time.Second, "bound every call to `duration`, 0 for no bound")
+	flag.Parse()
+
+	srv := newHTTPServer(*addr, &server{maxStates: *maxStates, timeout: *timeout})
+	if *cert != "" || *key != "" {
+		log.Fatal(srv.ListenAndServeTLS(*cert, *key))
+	}
+	log.Fatal(srv.ListenAndServe())
+}
+
+// newHTTPServer returns an HTTP/2 server of the service.
+func newHTTPServer(addr string, s *server) *http.Server {
+	var protocols http.Protocols
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Server{Addr: addr, Handler: s, Protocols: &protocols}
+}
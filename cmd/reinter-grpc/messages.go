@@ -0,0 +1,350 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// The messages of proto/reinter/v1/reinter.proto, encoded in the protobuf
+// wire format by hand since the module depends on no protobuf runtime.
+
+type patternOptions struct {
+	semantics       int32
+	caseInsensitive bool
+	unicodeMode     bool
+	overApproximate bool
+	asciiOnly       bool
+	maxStates       int32
+	timeoutNanos    int64
+}
+
+type pattern struct {
+	expr    string
+	options patternOptions
+}
+
+type automaton struct {
+	data        []byte
+	fingerprint []byte
+}
+
+// operand has one of its fields set.
+type operand struct {
+	pattern   *pattern
+	automaton *automaton
+}
+
+type compileRequest struct {
+	pattern pattern
+}
+
+type compileResponse struct {
+	automaton automaton
+}
+
+type checkRequest struct {
+	first, second operand
+}
+
+type checkResult struct {
+	intersects     bool
+	witness        string
+	statesExplored int64
+	explanation    string
+	approximate    bool
+}
+
+// Wire types of the protobuf encoding.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errMalformed = errors.New("malformed protobuf message")
+
+// encoder appends fields to a message. Fields holding the zero value are
+// omitted, as proto3 does.
+type encoder []byte
+
+func (e *encoder) tag(field, wire int) {
+	*e = binary.AppendUvarint(*e, uint64(field)<<3|uint64(wire))
+}
+
+func (e *encoder) varint(field int, v uint64) {
+	if v != 0 {
+		e.tag(field, wireVarint)
+		*e = binary.AppendUvarint(*e, v)
+	}
+}
+
+func (e *encoder) bool(field int, v bool) {
+	if v {
+		e.varint(field, 1)
+	}
+}
+
+func (e *encoder) bytes(field int, b []byte) {
+	if len(b) > 0 {
+		e.message(field, b)
+	}
+}
+
+// message appends a length-delimited field even if it is empty.
+func (e *encoder) message(field int, b []byte) {
+	e.tag(field, wireBytes)
+	*e = binary.AppendUvarint(*e, uint64(len(b)))
+	*e = append(*e, b...)
+}
+
+// decode calls fn for every field of the message, with the value of varint
+// fields in v and the contents of length-delimited fields in b. Fields of
+// other wire types are skipped.
+func decode(msg []byte, fn func(field, wire int, v uint64, b []byte) error) error {
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 || key>>3 == 0 || key>>3 > math.MaxInt32 {
+			return errMalformed
+		}
+		msg = msg[n:]
+		field, wire := int(key>>3), int(key&7)
+
+		var v uint64
+		var b []byte
+		switch wire {
+		case wireVarint:
+			v, n = binary.Uvarint(msg)
+			if n <= 0 {
+				return errMalformed
+			}
+		case wireFixed64:
+			n = 8
+		case wireBytes:
+			v, n = binary.Uvarint(msg)
+			if n <= 0 || v > uint64(len(msg)-n) {
+				return errMalformed
+			}
+			b = msg[n : n+int(v)]
+			n += int(v)
+		case wireFixed32:
+			n = 4
+		default:
+			return errMalformed
+		}
+		if n > len(msg) {
+			return errMalformed
+		}
+		msg = msg[n:]
+		if err := fn(field, wire, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *patternOptions) marshal() []byte {
+	var e encoder
+	e.varint(1, uint64(int64(o.semantics)))
+	e.bool(2, o.caseInsensitive)
+	e.bool(3, o.unicodeMode)
+	e.bool(4, o.overApproximate)
+	e.bool(5, o.asciiOnly)
+	e.varint(6, uint64(int64(o.maxStates)))
+	e.varint(7, uint64(o.timeoutNanos))
+	return e
+}
+
+func (o *patternOptions) unmarshal(msg []byte) error {
+	return decode(msg, func(field, wire int, v uint64, b []byte) error {
+		if wire != wireVarint {
+			return nil
+		}
+		switch field {
+		case 1:
+			o.semantics = int32(v)
+		case 2:
+			o.caseInsensitive = v != 0
+		case 3:
+			o.unicodeMode = v != 0
+		case 4:
+			o.overApproximate = v != 0
+		case 5:
+			o.asciiOnly = v != 0
+		case 6:
+			o.maxStates = int32(v)
+		case 7:
+			o.timeoutNanos = int64(v)
+		}
+		return nil
+	})
+}
+
+func (p *pattern) marshal() []byte {
+	var e encoder
+	e.bytes(1, []byte(p.expr))
+	if opts := p.options.marshal(); len(opts) > 0 {
+		e.message(2, opts)
+	}
+	return e
+}
+
+func (p *pattern) unmarshal(msg []byte) error {
+	return decode(msg, func(field, wire int, v uint64, b []byte) error {
+		if wire != wireBytes {
+			return nil
+		}
+		switch field {
+		case 1:
+			p.expr = string(b)
+		case 2:
+			return p.options.unmarshal(b)
+		}
+		return nil
+	})
+}
+
+func (a *automaton) marshal() []byte {
+	var e encoder
+	e.bytes(1, a.data)
+	e.bytes(2, a.fingerprint)
+	return e
+}
+
+func (a *automaton) unmarshal(msg []byte) error {
+	return decode(msg, func(field, wire int, v uint64, b []byte) error {
+		if wire != wireBytes {
+			return nil
+		}
+		switch field {
+		case 1:
+			a.data = append([]byte(nil), b...)
+		case 2:
+			a.fingerprint = append([]byte(nil), b...)
+		}
+		return nil
+	})
+}
+
+func (o *operand) marshal() []byte {
+	var e encoder
+	switch {
+	case o.pattern != nil:
+		e.message(1, o.pattern.marshal())
+	case o.automaton != nil:
+		e.message(2, o.automaton.marshal())
+	}
+	return e
+}
+
+// unmarshal keeps the last field of the oneof, as protobuf parsers do.
+func (o *operand) unmarshal(msg []byte) error {
+	return decode(msg, func(field, wire int, v uint64, b []byte) error {
+		if wire != wireBytes {
+			return nil
+		}
+		switch field {
+		case 1:
+			*o = operand{pattern: &pattern{}}
+			return o.pattern.unmarshal(b)
+		case 2:
+			*o = operand{automaton: &automaton{}}
+			return o.automaton.unmarshal(b)
+		}
+		return nil
+	})
+}
+
+func (r *compileRequest) marshal() []byte {
+	var e encoder
+	e.message(1, r.pattern.marshal())
+	return e
+}
+
+func (r *compileRequest) unmarshal(msg []byte) error {
+	return decode(msg, func(field, wire int, v uint64, b []byte) error {
+		if field == 1 && wire == wireBytes {
+			return r.pattern.unmarshal(b)
+		}
+		return nil
+	})
+}
+
+func (r *compileResponse) marshal() []byte {
+	var e encoder
+	e.message(1, r.automaton.marshal())
+	return e
+}
+
+func (r *compileResponse) unmarshal(msg []byte) error {
+	return decode(msg, func(field, wire int, v uint64, b []byte) error {
+		if field == 1 && wire == wireBytes {
+			return r.automaton.unmarshal(b)
+		}
+		return nil
+	})
+}
+
+func (r *checkRequest) marshal() []byte {
+	var e encoder
+	e.message(1, r.first.marshal())
+	e.message(2, r.second.marshal())
+	return e
+}
+
+func (r *checkRequest) unmarshal(msg []byte) error {
+	return decode(msg, func(field, wire int, v uint64, b []byte) error {
+		if wire != wireBytes {
+			return nil
+		}
+		switch field {
+		case 1:
+			return r.first.unmarshal(b)
+		case 2:
+			return r.second.unmarshal(b)
+		}
+		return nil
+	})
+}
+
+func (r *checkResult) marshal() []byte {
+	var e encoder
+	e.bool(1, r.intersects)
+	e.bytes(2, []byte(r.witness))
+	e.varint(3, uint64(r.statesExplored))
+	e.bytes(4, []byte(r.explanation))
+	e.bool(5, r.approximate)
+	return e
+}
+
+func (r *checkResult) unmarshal(msg []byte) error {
+	return decode(msg, func(field, wire int, v uint64, b []byte) error {
+		switch {
+		case field == 1 && wire == wireVarint:
+			r.intersects = v != 0
+		case field == 2 && wire == wireBytes:
+			r.witness = string(b)
+		case field == 3 && wire == wireVarint:
+			r.statesExplored = int64(v)
+		case field == 4 && wire == wireBytes:
+			r.explanation = string(b)
+		case field == 5 && wire == wireVarint:
+			r.approximate = v != 0
+		}
+		return nil
+	})
+}
@@ -0,0 +1,199 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/reinter"
+)
+
+func TestMessages(t *testing.T) {
+	req := compileRequest{pattern{expr: "a"}}
+	if got, want := req.marshal(), []byte{0x0a, 0x03, 0x0a, 0x01, 'a'}; !bytes.Equal(got, want) {
+		t.Errorf("marshal() = %x, want %x", got, want)
+	}
+
+	for _, r := range []checkRequest{
+		{},
+		{
+			first:  operand{pattern: &pattern{"[a-z]+", patternOptions{semantics: 2, caseInsensitive: true, maxStates: -1, timeoutNanos: 1e9}}},
+			second: operand{automaton: &automaton{data: []byte{1, 2, 3}, fingerprint: []byte{4}}},
+		},
+	} {
+		var got checkRequest
+		if err := got.unmarshal(r.marshal()); err != nil {
+			t.Errorf("unmarshal(%+v) error: %v", r, err)
+			continue
+		}
+		if r.first.pattern == nil && r.first.automaton == nil {
+			r.first, r.second = operand{}, operand{}
+		}
+		if !reflect.DeepEqual(got, r) {
+			t.Errorf("unmarshal(marshal(%+v)) = %+v", r, got)
+		}
+	}
+
+	// Unknown fields are skipped, truncated messages rejected.
+	var o patternOptions
+	if err := o.unmarshal([]byte{0x10, 0x01, 0x45, 0, 0, 0, 0, 0x7a, 0x01, 'x'}); err != nil || !o.caseInsensitive {
+		t.Errorf("unmarshal with unknown fields = %+v, %v", o, err)
+	}
+	if err := o.unmarshal([]byte{0x0a, 0x05, 'x'}); err == nil {
+		t.Error("unmarshal of a truncated message succeeded")
+	}
+}
+
+// call calls a method of the server over HTTP/2 without TLS and returns the
+// response message and the grpc-status trailer.
+func call(t *testing.T, addr, method string, msg []byte) ([]byte, string) {
+	t.Helper()
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+
+	req, err := http.NewRequest(http.MethodPost, "http://"+addr+servicePath+method, bytes.NewReader(frame(msg)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(body) > 0 {
+		body, err = readMessage(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return body, resp.Trailer.Get("Grpc-Status")
+}
+
+// serve starts an HTTP/2 server of s and returns its address.
+func serve(t *testing.T, s *server) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newHTTPServer("", s)
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+	return l.Addr().String()
+}
+
+func TestServer(t *testing.T) {
+	addr := serve(t, &server{})
+
+	body, status := call(t, addr, "Compile", (&compileRequest{pattern{expr: "[a-z]+"}}).marshal())
+	if status != "0" {
+		t.Fatalf("Compile status = %s", status)
+	}
+	var compiled compileResponse
+	if err := compiled.unmarshal(body); err != nil {
+		t.Fatal(err)
+	}
+	n, err := dfa.Unmarshal(compiled.automaton.data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fingerprint := dfa.Fingerprint(n); !bytes.Equal(compiled.automaton.fingerprint, fingerprint[:]) {
+		t.Errorf("Compile fingerprint = %x, want %x", compiled.automaton.fingerprint, fingerprint)
+	}
+
+	type testCase struct {
+		first, second operand
+		want          checkResult
+	}
+	testCases := []testCase{
+		{operand{pattern: &pattern{expr: "a+"}}, operand{pattern: &pattern{expr: "aa"}}, checkResult{intersects: true, witness: "aa"}},
+		{operand{automaton: &compiled.automaton}, operand{pattern: &pattern{expr: "ab"}}, checkResult{intersects: true, witness: "ab"}},
+		{operand{automaton: &compiled.automaton}, operand{pattern: &pattern{expr: "[0-9]+"}}, checkResult{}},
+		{operand{pattern: &pattern{expr: "API"}}, operand{pattern: &pattern{expr: "api", options: patternOptions{caseInsensitive: true}}}, checkResult{intersects: true, witness: "API"}},
+	}
+	for _, tc := range testCases {
+		body, status := call(t, addr, "Check", (&checkRequest{tc.first, tc.second}).marshal())
+		if status != "0" {
+			t.Errorf("Check(%+v, %+v) status = %s", tc.first, tc.second, status)
+			continue
+		}
+		var got checkResult
+		if err := got.unmarshal(body); err != nil {
+			t.Fatal(err)
+		}
+		if got.intersects != tc.want.intersects || got.witness != tc.want.witness {
+			t.Errorf("Check(%+v, %+v) = %+v, want %+v", tc.first, tc.second, got, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		method string
+		msg    []byte
+		status string
+	}{
+		{"Compile", (&compileRequest{pattern{expr: "(a"}}).marshal(), "3"},
+		{"Compile", (&compileRequest{pattern{expr: "[ab]*a[ab]{12}", options: patternOptions{maxStates: 100}}}).marshal(), "8"},
+		{"Compile", (&compileRequest{pattern{expr: "a", options: patternOptions{semantics: 7}}}).marshal(), "3"},
+		{"Check", (&checkRequest{second: operand{automaton: &automaton{data: []byte{0xff}}}}).marshal(), "3"},
+		{"Check", []byte{0x0a}, "3"},
+		{"Watch", nil, "12"},
+	} {
+		if _, status := call(t, addr, tc.method, tc.msg); status != tc.status {
+			t.Errorf("%s(%x) status = %s, want %s", tc.method, tc.msg, status, tc.status)
+		}
+	}
+}
+
+func TestBounds(t *testing.T) {
+	n, err := reinter.Compile("[ab]*a[ab]{5}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	large := operand{automaton: &automaton{data: dfa.Marshal(n)}}
+
+	type testCase struct {
+		server *server
+		method string
+		msg    []byte
+		status string
+	}
+	testCases := []testCase{
+		{&server{}, "Check", (&checkRequest{large, large}).marshal(), "0"},
+		{&server{maxStates: 10}, "Check", (&checkRequest{large, large}).marshal(), "8"},
+		{&server{}, "Check", (&checkRequest{operand{pattern: &pattern{expr: "[ab]*", options: patternOptions{maxStates: 10}}}, large}).marshal(), "8"},
+		{&server{timeout: time.Nanosecond}, "Check", (&checkRequest{large, large}).marshal(), "8"},
+		{&server{maxStates: 10}, "Compile", (&compileRequest{pattern{expr: "[ab]*a[ab]{5}"}}).marshal(), "8"},
+		{&server{maxStates: 10}, "Compile", (&compileRequest{pattern{expr: "[ab]*a[ab]{5}", options: patternOptions{maxStates: 1000}}}).marshal(), "8"},
+		{&server{maxStates: 1000}, "Compile", (&compileRequest{pattern{expr: "[ab]*a[ab]{5}", options: patternOptions{maxStates: 10}}}).marshal(), "8"},
+		{&server{maxStates: 1000}, "Compile", (&compileRequest{pattern{expr: "[ab]*a[ab]{5}"}}).marshal(), "0"},
+	}
+	for _, tc := range testCases {
+		if _, status := call(t, serve(t, tc.server), tc.method, tc.msg); status != tc.status {
+			t.Errorf("%s(%x) on %+v status = %s, want %s", tc.method, tc.msg, *tc.server, status, tc.status)
+		}
+	}
+}
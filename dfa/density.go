@@ -0,0 +1,68 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dfa
+
+import (
+	"math"
+	"unicode"
+
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// Density returns the fraction of the strings of at most length runes the
+// automaton accepts, every Unicode scalar value being a rune. As there are
+// many more long strings than short ones, it is mostly the fraction of the
+// strings of the given length: about 1 for .* and tiny for [a-z]+. It ranks
+// patterns by how permissive they are.
+func Density(n *Node, length int) float64 {
+	if length < 0 {
+		return 0
+	}
+	universe := float64(runerange.Count(runerange.Valid([]rune{0, unicode.MaxRune})))
+
+	// p holds the probabilities of reaching the states on random strings
+	// of the current length, and accepted[i] the one of strings of i runes
+	// being accepted.
+	nodes := reachable(n)
+	index := make(map[*Node]int, len(nodes))
+	for i, node := range nodes {
+		index[node] = i
+	}
+	p := make([]float64, len(nodes))
+	p[0] = 1
+	accepted := make([]float64, length+1)
+	for i := 0; i <= length; i++ {
+		next := make([]float64, len(nodes))
+		for j, node := range nodes {
+			if node.Final {
+				accepted[i] += p[j]
+			}
+			for _, t := range node.Transitions {
+				if c := runerange.Count(runerange.Valid(t.RuneRanges)); c > 0 {
+					next[index[t.Node]] += p[j] * float64(c) / universe
+				}
+			}
+		}
+		p = next
+	}
+
+	// Strings of i runes weigh universe^(i-length) relative to the longest.
+	var sum, weights float64
+	for i := length; i >= 0; i-- {
+		w := math.Pow(universe, float64(i-length))
+		sum += w * accepted[i]
+		weights += w
+	}
+	return sum / weights
+}
@@ -15,22 +15,33 @@
 package dfa
 
 import (
-	"fmt"
+	"errors"
+	"slices"
 	"sort"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/oulinbao/regexinter/nfa"
 	"github.com/oulinbao/regexinter/runerange"
 )
 
+// Node is a state of an automaton. Automata are never modified once built:
+// the functions of the package return new ones, so they are safe for
+// concurrent use as long as callers do not modify them either.
 type Node struct {
 	State       int  // state
 	Final       bool // final?
-	Transitions []T  // transitions
+	Transitions []T  // transitions ordered by their first rune
 
-	label    string
-	closures []*nfa.Node
+	label   string  // see Label
+	closure []int32 // numbers of its NFA nodes, during the construction
+}
+
+// Label returns the NFA states the node stands for, such as "1,4,7", for
+// debugging. It is empty unless the node was built by a Builder with Labels
+// set.
+func (n *Node) Label() string {
+	return n.label
 }
 
 type T struct {
@@ -39,214 +50,594 @@ type T struct {
 }
 
 type context struct {
-	state        int
-	nodesByLabel map[string]*Node
-	closureCache map[*nfa.Node][]*nfa.Node
+	state      int
+	nodesByKey map[uint64][]*Node // by hash of their NFA states
+	limits     Limits
+	start      time.Time
+	labels     bool
+	err        error
+
+	// The NFA nodes are numbered in breadth-first order. Their transitions
+	// are edges[edgeStart[i]:edgeStart[i+1]] and their epsilon closures,
+	// once computed, closureCache[i].
+	nfaNodes     []*nfa.Node
+	index        map[*nfa.Node]int32
+	edges        []edge
+	edgeStart    []int32
+	closureCache [][]int32
+
+	// Scratch buffers reused by every state of the construction.
+	ranges   [][]rune
+	closures [][]int32
+	frames   []frame
+	set      sparseSet
+
+	nodes slab[Node]
 }
 
-var visited = make(map[*Node]bool)
-
-func (n Node) Print() {
-	fmt.Println(fmt.Sprintf("State: %d, Final: %v, Trans: %v", n.State, n.Final, n.Transitions))
+// edge is a transition of an NFA node to a numbered node.
+type edge struct {
+	r  []rune // nil for epsilon transitions
+	to int32
+}
 
-	for _, t := range n.Transitions {
-		if _, exist := visited[t.Node]; exist {
-			continue
+// number numbers the NFA nodes reachable from n, n being 0.
+func (ctx *context) number(n *nfa.Node) {
+	ctx.nfaNodes = append(ctx.nfaNodes[:0], n)
+	ctx.index[n] = 0
+	ctx.edges, ctx.edgeStart = ctx.edges[:0], ctx.edgeStart[:0]
+	for i := 0; i < len(ctx.nfaNodes); i++ {
+		ctx.edgeStart = append(ctx.edgeStart, int32(len(ctx.edges)))
+		for _, t := range ctx.nfaNodes[i].T {
+			j, ok := ctx.index[t.N]
+			if !ok {
+				j = int32(len(ctx.nfaNodes))
+				ctx.index[t.N] = j
+				ctx.nfaNodes = append(ctx.nfaNodes, t.N)
+			}
+			ctx.edges = append(ctx.edges, edge{t.R, j})
 		}
-
-		visited[t.Node] = true
-		fmt.Println("rune ranges", t.RuneRanges)
-		t.Node.Print()
 	}
+	ctx.edgeStart = append(ctx.edgeStart, int32(len(ctx.edges)))
+	ctx.closureCache = append(ctx.closureCache[:0], make([][]int32, len(ctx.nfaNodes))...)
+	ctx.set.resize(len(ctx.nfaNodes))
 }
 
-func (n Node) NextState(r []rune) *Node {
-	for _, t := range n.Transitions {
-		if runerange.Contains(t.RuneRanges, r) {
-			return t.Node
-		}
+func (ctx *context) edgesOf(i int32) []edge {
+	return ctx.edges[ctx.edgeStart[i]:ctx.edgeStart[i+1]]
+}
+
+// Builder builds automata from NFAs like NewFromNFAWithLimits, reusing its
+// maps and buffers from one automaton to the next. This saves most of the
+// allocations when compiling many patterns. The zero value is ready to use;
+// a Builder must not be used by several goroutines at once.
+type Builder struct {
+	// Labels makes the nodes built keep readable labels, see Node.Label.
+	Labels bool
+
+	ctx context
+}
+
+// Build is NewFromNFAWithLimits reusing the memory of the builder. The
+// assertions of NFAs built by hand are resolved first, see
+// nfa.ResolveAssertions.
+func (b *Builder) Build(nfanode *nfa.Node, limits Limits) (*Node, error) {
+	nfanode = nfa.ResolveAssertions(nfanode)
+	ctx := &b.ctx
+	if ctx.nodesByKey == nil {
+		ctx.nodesByKey = make(map[uint64][]*Node)
+		ctx.index = make(map[*nfa.Node]int32)
 	}
+	// Nothing of the previous automaton may be kept alive.
+	defer func() {
+		clear(ctx.nodesByKey)
+		clear(ctx.index)
+		clear(ctx.nfaNodes)
+		clear(ctx.edges)
+		clear(ctx.closureCache)
+		clear(ctx.ranges[:cap(ctx.ranges)])
+		clear(ctx.frames[:cap(ctx.frames)])
+		ctx.state, ctx.err = 0, nil
+		ctx.nodes = slab[Node]{} // the automaton keeps its nodes
+	}()
+	ctx.limits, ctx.labels = limits, b.Labels
+	ctx.start = time.Now()
+
+	ctx.number(nfanode)
+	node := firstNode(ctx)
+	constructSubset(node, ctx)
+	if ctx.err != nil {
+		return nil, ctx.err
+	}
+	trim(node)
+	// The construction state would keep the NFA alive.
+	for _, n := range reachable(node) {
+		n.closure = nil
+	}
+	return node, nil
+}
+
+// ErrBudgetExceeded is returned when building an automaton exceeds its Limits.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// ErrTooManyStates and ErrDeadlinePassed tell which of the Limits was
+// exceeded. Both wrap ErrBudgetExceeded.
+var (
+	ErrTooManyStates  error = &wrapped{ErrBudgetExceeded, "too many states"}
+	ErrDeadlinePassed error = &wrapped{ErrBudgetExceeded, "deadline passed"}
+)
+
+// wrapped is an error wrapping err with details, as fmt.Errorf with %w
+// would, without pulling fmt into the core of the package.
+type wrapped struct {
+	err    error
+	detail string
+}
+
+func wrap(err error, detail string) error { return &wrapped{err, detail} }
 
+func (e *wrapped) Error() string { return e.err.Error() + ": " + e.detail }
+func (e *wrapped) Unwrap() error { return e.err }
+
+// Limits bound the resources spent on building an automaton. Zero values
+// mean no limit.
+type Limits struct {
+	MaxStates int       // maximum number of states
+	Deadline  time.Time // time by which the construction has to finish
+
+	// Progress, if set, is called every progressInterval states with the
+	// progress of the construction. An error it returns stops the
+	// construction and is returned as is.
+	Progress func(Progress) error
+
+	// Logger, if set, receives every step of the construction.
+	Logger Logger
+}
+
+// progressInterval is the number of states built between calls to
+// Limits.Progress.
+const progressInterval = 1024
+
+// Progress describes a construction under way, see Limits.Progress.
+type Progress struct {
+	States  int           // states built so far
+	Pending int           // states reached but not explored yet
+	Elapsed time.Duration // time since the construction started
+}
+
+// Check returns an error wrapping ErrTooManyStates or ErrDeadlinePassed if
+// an automaton with the given number of states exceeds the limits.
+func (l Limits) Check(states int) error {
+	if l.MaxStates > 0 && states > l.MaxStates {
+		return wrap(ErrTooManyStates, "more than "+strconv.Itoa(l.MaxStates))
+	}
+	if !l.Deadline.IsZero() && time.Now().After(l.Deadline) {
+		return ErrDeadlinePassed
+	}
 	return nil
 }
 
-func NewFromNFA(nfanode *nfa.Node) *Node {
-	ctx := &context{
-		nodesByLabel: make(map[string]*Node),
-		closureCache: make(map[*nfa.Node][]*nfa.Node),
+// Step is Check for constructions that started at start and have pending
+// states left to explore. It also reports their progress to l.Progress.
+func (l Limits) Step(start time.Time, states, pending int) error {
+	if err := l.Check(states); err != nil {
+		return err
 	}
-	node := firstNode(nfanode, ctx)
-	constructSubset(node, ctx)
-	return node
+	if l.Progress != nil && states%progressInterval == 0 {
+		return l.Progress(Progress{States: states, Pending: pending, Elapsed: time.Since(start)})
+	}
+	return nil
 }
 
-func recursiveClosure(node *nfa.Node, visited map[*nfa.Node]struct{}) []*nfa.Node {
-	if visited != nil {
-		if _, ok := visited[node]; ok {
-			return nil
+// Match reports whether the automaton accepts s.
+func (n *Node) Match(s string) bool {
+	for _, r := range s {
+		if n = n.NextState([]rune{r, r}); n == nil {
+			return false
 		}
 	}
+	return n.Final
+}
 
-	cls := []*nfa.Node{node}
-	for _, t := range node.T {
-		if t.R == nil {
-			if visited == nil {
-				visited = make(map[*nfa.Node]struct{})
-				visited[node] = struct{}{}
-			}
-			if c := recursiveClosure(t.N, visited); c != nil {
-				cls = append(cls, c...)
-			}
+// FindPrefix returns the length in bytes of the shortest prefix of s the
+// automaton accepts. It reports false if it accepts no prefix of s.
+func (n *Node) FindPrefix(s string) (end int, ok bool) {
+	for i, r := range s {
+		if n.Final {
+			return i, true
+		}
+		if n = n.NextState([]rune{r, r}); n == nil {
+			return 0, false
 		}
 	}
-
-	if visited != nil {
-		delete(visited, node)
+	if !n.Final {
+		return 0, false
 	}
-
-	return cls
+	return len(s), true
 }
 
-func closure(node *nfa.Node, cache map[*nfa.Node][]*nfa.Node) []*nfa.Node {
-	if cache != nil {
-		if cls, ok := cache[node]; ok {
-			return cls
+// FindLongestPrefix is like FindPrefix but returns the length of the
+// longest prefix of s the automaton accepts.
+func (n *Node) FindLongestPrefix(s string) (end int, ok bool) {
+	for i, r := range s {
+		if n.Final {
+			end, ok = i, true
+		}
+		if n = n.NextState([]rune{r, r}); n == nil {
+			return end, ok
 		}
 	}
+	if n.Final {
+		return len(s), true
+	}
+	return end, ok
+}
 
-	cls := recursiveClosure(node, nil)
-
-	if cache != nil {
-		cache[node] = cls
+func (n Node) NextState(r []rune) *Node {
+	for _, t := range n.Transitions {
+		if runerange.Contains(t.RuneRanges, r) {
+			return t.Node
+		}
 	}
 
-	return cls
+	return nil
 }
 
-func intsToStrings(a []int) []string {
-	s := make([]string, 0, len(a))
-	for _, i := range a {
-		s = append(s, strconv.Itoa(i))
-	}
-	return s
+func NewFromNFA(nfanode *nfa.Node) *Node {
+	node, _ := NewFromNFAWithLimits(nfanode, Limits{})
+	return node
 }
 
-func makeLabel(a []int) string {
-	return strings.Join(intsToStrings(a), ",")
+// NewFromNFAWithLimits is like NewFromNFA but stops with an error wrapping
+// ErrBudgetExceeded once the construction exceeds the limits.
+func NewFromNFAWithLimits(nfanode *nfa.Node, limits Limits) (*Node, error) {
+	var b Builder
+	return b.Build(nfanode, limits)
 }
 
-func labelFromClosure(cls []*nfa.Node) string {
-	m := make(map[int]struct{})
-	for _, n := range cls {
-		m[n.S] = struct{}{}
+// closure returns the sorted numbers of node i and of the nodes reachable
+// from it by epsilon transitions.
+func closure(i int32, ctx *context) []int32 {
+	if cls := ctx.closureCache[i]; cls != nil {
+		return cls
 	}
+	set := &ctx.set
+	set.clear()
+	set.add(i)
+	for k := 0; k < len(set.dense); k++ {
+		for _, e := range ctx.edgesOf(set.dense[k]) {
+			if e.r == nil && !set.contains(e.to) {
+				set.add(e.to)
+			}
+		}
+	}
+	cls := slices.Clone(set.dense)
+	slices.Sort(cls)
+	ctx.closureCache[i] = cls
+	return cls
+}
 
-	states := make([]int, 0, len(m))
-	for n := range m {
-		states = append(states, n)
+// hashStates returns the FNV-1a hash of the states.
+func hashStates(states []int32) uint64 {
+	h := uint64(14695981039346656037)
+	for _, s := range states {
+		for i := 0; i < 32; i += 8 {
+			h ^= uint64(s>>i) & 0xff
+			h *= 1099511628211
+		}
 	}
+	return h
+}
 
+// makeLabel returns the sorted NFA states of a closure joined by commas.
+func makeLabel(cls []int32, ctx *context) string {
+	states := make([]int, len(cls))
+	for i, k := range cls {
+		states[i] = ctx.nfaNodes[k].S
+	}
 	sort.Ints(states)
+	var b []byte
+	for i, s := range states {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = strconv.AppendInt(b, int64(s), 10)
+	}
+	return string(b)
+}
 
-	return makeLabel(states)
+// newNode adds the node of a closure with the given hash.
+func (ctx *context) newNode(cls []int32, key uint64) *Node {
+	ctx.state++
+	node := ctx.nodes.new()
+	*node = Node{
+		State:   ctx.state,
+		Final:   isFinal(cls, ctx),
+		closure: cls,
+	}
+	if ctx.labels {
+		node.label = makeLabel(cls, ctx)
+	}
+	ctx.nodesByKey[key] = append(ctx.nodesByKey[key], node)
+	ctx.limits.Log(Event{Kind: NodeCreated, State: node.State})
+	return node
 }
 
-func isFinal(cls []*nfa.Node) bool {
-	for _, n := range cls {
-		if n.F {
+func isFinal(cls []int32, ctx *context) bool {
+	for _, k := range cls {
+		if ctx.nfaNodes[k].F {
 			return true
 		}
 	}
 	return false
 }
 
-func union(cls ...[]*nfa.Node) []*nfa.Node {
+// union returns the sorted union of the closures, so that the construction
+// does not depend on the order the closures were found in.
+func union(ctx *context, cls ...[]int32) []int32 {
 	if len(cls) == 1 {
 		return cls[0]
 	}
 
-	size := 0
-	for _, c := range cls {
-		size += len(c)
-	}
-
-	m := make(map[*nfa.Node]struct{}, size)
+	set := &ctx.set
+	set.clear()
 	for _, c := range cls {
-		for _, n := range c {
-			m[n] = struct{}{}
+		for _, k := range c {
+			if !set.contains(k) {
+				set.add(k)
+			}
 		}
 	}
-
-	a := make([]*nfa.Node, 0, len(m))
-	for n := range m {
-		a = append(a, n)
-	}
+	a := slices.Clone(set.dense)
+	slices.Sort(a)
 
 	return a
 }
 
-func closuresForRange(n *Node, rr []rune, ctx *context) (closures [][]*nfa.Node) {
-	for _, n := range n.closures {
-		for _, t := range n.T {
-			if runerange.Contains(t.R, rr) {
-				cls := closure(t.N, ctx.closureCache)
-				closures = append(closures, cls)
+func closuresForRange(n *Node, rr []rune, ctx *context) (closures [][]int32) {
+	closures = ctx.closures[:0]
+	defer func() { ctx.closures = closures }()
+	for _, k := range n.closure {
+		for _, e := range ctx.edgesOf(k) {
+			if runerange.Contains(e.r, rr) {
+				closures = append(closures, closure(e.to, ctx))
 			}
 		}
 	}
 	return
 }
 
-func constructSubset(root *Node, ctx *context) {
-	var ranges [][]rune
-	for _, n := range root.closures {
-		for _, t := range n.T {
-			ranges = append(ranges, t.R)
+// frame is a state whose transitions are being built, with the rune pairs
+// its transitions are made of and the first i already done.
+type frame struct {
+	node    *Node
+	pairs   []rune
+	i       int
+	targets []*Node          // in the order of their first rune
+	ranges  map[*Node][]rune // leading to each target
+}
+
+func newFrame(node *Node, ctx *context) frame {
+	ranges := ctx.ranges[:0]
+	for _, k := range node.closure {
+		for _, e := range ctx.edgesOf(k) {
+			ranges = append(ranges, e.r)
 		}
 	}
-	pairs := runerange.Split(ranges)
-
-	m := make(map[*Node][]rune)
+	ctx.ranges = ranges
+	return frame{node: node, pairs: runerange.Split(ranges), ranges: make(map[*Node][]rune)}
+}
 
-	for i := 0; i < len(pairs); i += 2 {
-		cls := union(closuresForRange(root, pairs[i:i+2], ctx)...)
+// constructSubset builds the states reachable from root depth first. It keeps
+// its own stack as automata can be deeper than a goroutine stack.
+func constructSubset(root *Node, ctx *context) {
+	stack := append(ctx.frames[:0], newFrame(root, ctx))
+	defer func() { ctx.frames = stack[:0] }()
+	for len(stack) > 0 {
+		f := &stack[len(stack)-1]
+		if f.i == len(f.pairs) {
+			for _, n := range f.targets {
+				f.node.Transitions = append(f.node.Transitions, T{f.ranges[n], n})
+				ctx.limits.Log(Event{Kind: TransitionAdded, State: f.node.State, To: n.State, Ranges: f.ranges[n]})
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		pair := f.pairs[f.i : f.i+2]
+		f.i += 2
 
-		label := labelFromClosure(cls)
+		cls := union(ctx, closuresForRange(f.node, pair, ctx)...)
+		key := hashStates(cls)
 		var node *Node
-		if n, ok := ctx.nodesByLabel[label]; ok {
-			node = n
-		} else {
-			ctx.state++
-			node = &Node{
-				State:    ctx.state,
-				Final:    isFinal(cls),
-				label:    label,
-				closures: cls,
+		for _, n := range ctx.nodesByKey[key] {
+			if slices.Equal(n.closure, cls) {
+				node = n
+				break
+			}
+		}
+		created := node == nil
+		if created {
+			if ctx.err = ctx.limits.Step(ctx.start, ctx.state+1, len(stack)); ctx.err != nil {
+				return
 			}
-			ctx.nodesByLabel[label] = node
-			constructSubset(node, ctx)
+			node = ctx.newNode(cls, key)
 		}
 
-		m[node] = runerange.Sum(m[node], pairs[i:i+2])
+		if _, ok := f.ranges[node]; !ok {
+			f.targets = append(f.targets, node)
+		}
+		f.ranges[node] = runerange.Sum(f.ranges[node], pair)
+		if created {
+			stack = append(stack, newFrame(node, ctx))
+		}
 	}
+}
+
+func firstNode(ctx *context) *Node {
+	cls := closure(0, ctx)
+	return ctx.newNode(cls, hashStates(cls))
+}
 
-	for n, rr := range m {
-		root.Transitions = append(root.Transitions, T{rr, n})
+// reachable returns the nodes reachable from root in breadth-first order.
+func reachable(root *Node) []*Node {
+	seen := map[*Node]bool{root: true}
+	nodes := []*Node{root}
+	for i := 0; i < len(nodes); i++ {
+		for _, t := range nodes[i].Transitions {
+			if !seen[t.Node] {
+				seen[t.Node] = true
+				nodes = append(nodes, t.Node)
+			}
+		}
 	}
+	return nodes
 }
 
-func firstNode(nfanode *nfa.Node, ctx *context) *Node {
-	cls := closure(nfanode, ctx.closureCache)
-	label := labelFromClosure(cls)
+// Trim returns a copy of the automaton without the states that are
+// unreachable from the start state or cannot reach a final state. The states
+// keep their numbers. Automata built by NewFromNFA are already trimmed.
+func Trim(n *Node) *Node {
+	root := clone(n)
+	trim(root)
+	return root
+}
 
-	ctx.state++
-	node := &Node{
-		State:    ctx.state,
-		Final:    isFinal(cls),
-		label:    label,
-		closures: cls,
+// Complete returns a copy of the automaton with a transition on every rune
+// of the universe from every state. The runes a state has no transition on
+// lead to a new non-final sink state, numbered after the others. No sink is
+// added if the automaton is complete already.
+func Complete(n *Node, universe []rune) *Node {
+	root := clone(n)
+	nodes := reachable(root)
+	sink := &Node{State: maxState(nodes) + 1}
+	sunk := false
+	for _, node := range nodes {
+		var covered []rune
+		for _, t := range node.Transitions {
+			covered = runerange.Sum(covered, t.RuneRanges)
+		}
+		if missing := runerange.Subtract(universe, covered); len(missing) > 0 {
+			node.Transitions = append(node.Transitions, T{missing, sink})
+			ts := node.Transitions
+			sort.Slice(ts, func(i, j int) bool { return ts[i].RuneRanges[0] < ts[j].RuneRanges[0] })
+			sunk = true
+		}
+	}
+	if sunk && len(universe) > 0 {
+		sink.Transitions = []T{{append([]rune(nil), universe...), sink}}
+	}
+	return root
+}
+
+// Alphabet returns the union of the ranges of all the transitions of the
+// automaton, sorted and merged.
+func Alphabet(n *Node) []rune {
+	var alphabet []rune
+	for _, node := range reachable(n) {
+		for _, t := range node.Transitions {
+			alphabet = runerange.Sum(alphabet, t.RuneRanges)
+		}
 	}
-	ctx.nodesByLabel[label] = node
+	return alphabet
+}
 
-	return node
+// clone returns a copy of the automaton.
+func clone(n *Node) *Node {
+	nodes := reachable(n)
+	copies := make(map[*Node]*Node, len(nodes))
+	slab := make([]Node, len(nodes))
+	for i, node := range nodes {
+		slab[i] = Node{State: node.State, Final: node.Final}
+		copies[node] = &slab[i]
+	}
+	for _, node := range nodes {
+		c := copies[node]
+		for _, t := range node.Transitions {
+			c.Transitions = append(c.Transitions, T{t.RuneRanges, copies[t.Node]})
+		}
+	}
+	return copies[n]
+}
+
+func maxState(nodes []*Node) int {
+	highest := 0
+	for _, n := range nodes {
+		if n.State > highest {
+			highest = n.State
+		}
+	}
+	return highest
+}
+
+// trim removes the transitions to states that cannot reach a final state in
+// place. The start state is kept even if it cannot reach a final state.
+func trim(root *Node) {
+	nodes := reachable(root)
+	live := canReachFinal(nodes)
+	for _, node := range nodes {
+		var ts []T
+		for _, t := range node.Transitions {
+			if live[t.Node] {
+				ts = append(ts, t)
+			}
+		}
+		node.Transitions = ts
+	}
+}
+
+// canReachFinal returns the set of nodes from which a final node is reachable.
+func canReachFinal(nodes []*Node) map[*Node]bool {
+	index := make(map[*Node]int32, len(nodes))
+	for i, n := range nodes {
+		index[n] = int32(i)
+	}
+	// The predecessors of node i are preds[predStart[i]:predStart[i+1]].
+	predStart := make([]int32, len(nodes)+1)
+	for _, n := range nodes {
+		for _, t := range n.Transitions {
+			if j, ok := index[t.Node]; ok {
+				predStart[j+1]++
+			}
+		}
+	}
+	for i := range nodes {
+		predStart[i+1] += predStart[i]
+	}
+	preds := make([]int32, predStart[len(nodes)])
+	fill := slices.Clone(predStart)
+	for i, n := range nodes {
+		for _, t := range n.Transitions {
+			if j, ok := index[t.Node]; ok {
+				preds[fill[j]] = int32(i)
+				fill[j]++
+			}
+		}
+	}
+
+	var queue []int32
+	live := make([]bool, len(nodes))
+	for i, n := range nodes {
+		if n.Final {
+			live[i] = true
+			queue = append(queue, int32(i))
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		for _, p := range preds[predStart[i]:predStart[i+1]] {
+			if !live[p] {
+				live[p] = true
+				queue = append(queue, p)
+			}
+		}
+	}
+
+	result := make(map[*Node]bool)
+	for i, n := range nodes {
+		if live[i] {
+			result[n] = true
+		}
+	}
+	return result
 }
@@ -0,0 +1,1012 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dfa
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/oulinbao/regexinter/nfa"
+)
+
+func compile(t *testing.T, expr string) *Node {
+	t.Helper()
+	n, err := nfa.New(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewFromNFA(n)
+}
+
+func accepts(n *Node, s string) bool {
+	for _, r := range s {
+		if n = n.NextState([]rune{r, r}); n == nil {
+			return false
+		}
+	}
+	return n.Final
+}
+
+type matchCase struct {
+	in   string
+	want bool
+}
+
+func checkMatches(t *testing.T, name string, n *Node, cases []matchCase) {
+	t.Helper()
+	for _, c := range cases {
+		if got := accepts(n, c.in); got != c.want {
+			t.Errorf("%s accepts %q = %v, want %v", name, c.in, got, c.want)
+		}
+	}
+}
+
+func TestOps(t *testing.T) {
+	ab, cd := compile(t, "ab"), compile(t, "c|d")
+
+	checkMatches(t, "Concat(ab, c|d)", Concat(ab, cd), []matchCase{
+		{"abc", true}, {"abd", true}, {"ab", false}, {"c", false},
+	})
+	checkMatches(t, "Concat()", Concat(), []matchCase{
+		{"", true}, {"a", false},
+	})
+	checkMatches(t, "Union(ab, c|d)", Union(ab, cd), []matchCase{
+		{"ab", true}, {"d", true}, {"", false}, {"abc", false},
+	})
+	checkMatches(t, "Union()", Union(), []matchCase{
+		{"", false}, {"a", false},
+	})
+	checkMatches(t, "Star(ab)", Star(ab), []matchCase{
+		{"", true}, {"ab", true}, {"ababab", true}, {"aba", false},
+	})
+	checkMatches(t, "Plus(ab)", Plus(ab), []matchCase{
+		{"", false}, {"ab", true}, {"abab", true}, {"abb", false},
+	})
+	checkMatches(t, "Plus(a*b)", Plus(compile(t, "a*b")), []matchCase{
+		{"b", true}, {"aabab", true}, {"bb", true}, {"aa", false},
+	})
+	az, aStar := compile(t, "[a-z]+"), compile(t, "a*")
+	checkMatches(t, "Intersect([a-z]+, a*)", Intersect(az, aStar), []matchCase{
+		{"a", true}, {"aaa", true}, {"", false}, {"ab", false},
+	})
+	checkMatches(t, "Subtract([a-z]+, a*)", Subtract(az, aStar), []matchCase{
+		{"ab", true}, {"b", true}, {"a", false}, {"", false}, {"A", false},
+	})
+	checkMatches(t, "Subtract(a*, [a-z]+)", Subtract(aStar, az), []matchCase{
+		{"", true}, {"a", false},
+	})
+	if n := Intersect(ab, cd); n.Final || len(n.Transitions) > 0 {
+		t.Errorf("Intersect(ab, c|d) accepts strings")
+	}
+}
+
+func TestToRegex(t *testing.T) {
+	exprs := []string{
+		"", "a", "ab|cd", "a*", "(ab)+c?", "[a-z]+@[a-z]+\\.com",
+		"/api/v1/[0-9]+/get", "(a|b)*abb", "x[^y]z", ".*", "a.b", "\\(\\)\\*",
+	}
+	samples := []string{
+		"", "a", "aa", "ab", "cd", "abab", "ababc", "c", "foo@bar.com", "foo@bar.org",
+		"/api/v1/42/get", "/api/v1/x/get", "abb", "aabb", "babb", "xaz", "xyz", "a\nb", "axb", "()*",
+	}
+	for _, e := range exprs {
+		re, err := ToRegex(compile(t, e))
+		if err != nil {
+			t.Fatalf("ToRegex(%q): %v", e, err)
+		}
+		want := regexp.MustCompile("^(?:" + e + ")$")
+		got, err := regexp.Compile("^(?:" + re + ")$")
+		if err != nil {
+			t.Fatalf("ToRegex(%q) = %q: %v", e, re, err)
+		}
+		for _, s := range samples {
+			if got.MatchString(s) != want.MatchString(s) {
+				t.Errorf("ToRegex(%q) = %q disagrees on %q", e, re, s)
+			}
+		}
+	}
+
+	if re, _ := ToRegex(Union()); re != NoMatch {
+		t.Errorf("ToRegex(Union()) = %q, want %q", re, NoMatch)
+	}
+}
+
+func TestToGoRegexp(t *testing.T) {
+	for _, e := range []string{"a|ab", "(a|b)*abb", "^x+$", "[^a]é+", NoMatch} {
+		re, err := ToGoRegexp(compile(t, e))
+		if err != nil {
+			t.Fatalf("ToGoRegexp(%q): %v", e, err)
+		}
+		want := regexp.MustCompile("^(?:" + e + ")$")
+		for _, s := range []string{"", "a", "ab", "abb", "babb", "x", "xx", "bé", "aé", "éé"} {
+			if re.MatchString(s) != want.MatchString(s) {
+				t.Errorf("ToGoRegexp(%q) = %v disagrees on %q", e, re, s)
+			}
+		}
+	}
+	if _, err := ToGoRegexp(nil); err == nil {
+		t.Errorf("ToGoRegexp(nil) succeeded")
+	}
+}
+
+// dump renders the automaton with its states in breadth-first order.
+func dump(n *Node) string {
+	var b strings.Builder
+	for _, n := range reachable(n) {
+		fmt.Fprintf(&b, "%d %v", n.State, n.Final)
+		for _, t := range n.Transitions {
+			fmt.Fprintf(&b, " %v->%d", t.RuneRanges, t.Node.State)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func TestDeterministic(t *testing.T) {
+	exprs := []string{
+		"(a|b)*abb", "[a-z]+@[a-z]+\\.(com|org|net)", "(foo|bar|baz)+(qux)?",
+		"/api/v[0-9]/(users|groups)/[0-9]+", "(?i)hello|world",
+	}
+	for _, e := range exprs {
+		want := dump(compile(t, e))
+		for i := 0; i < 20; i++ {
+			if got := dump(compile(t, e)); got != want {
+				t.Fatalf("construction of %q is not deterministic:\n%s\nvs\n%s", e, got, want)
+			}
+		}
+	}
+}
+
+func TestNestedEpsilonCycles(t *testing.T) {
+	type testCase struct {
+		expr   string
+		accept []string
+		reject []string
+	}
+	testCases := []testCase{
+		{"(a*)*", []string{"", "a", "aaa"}, []string{"b"}},
+		{"(a*|b*)*c", []string{"c", "abac", "bbc"}, []string{"", "ab"}},
+		{"((a?)*)*b", []string{"b", "aab"}, []string{"a"}},
+	}
+	for _, tc := range testCases {
+		n := compile(t, tc.expr)
+		for _, s := range tc.accept {
+			if !accepts(n, s) {
+				t.Errorf("%q does not accept %q", tc.expr, s)
+			}
+		}
+		for _, s := range tc.reject {
+			if accepts(n, s) {
+				t.Errorf("%q accepts %q", tc.expr, s)
+			}
+		}
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	type testCase struct {
+		expr   string
+		states int
+	}
+	testCases := []testCase{
+		{"(a|b)*abb", 4},
+		{"a*", 1},
+		{"(a*)*b*", 2},
+		{"[^\\x00-\\x{10FFFF}]", 1},
+		{"abc|abd|abe", 4},
+	}
+	for _, tc := range testCases {
+		if got := len(reachable(Minimize(compile(t, tc.expr)))); got != tc.states {
+			t.Errorf("Minimize(%q) has %d states, want %d", tc.expr, got, tc.states)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	type testCase struct {
+		a, b string
+		want bool
+	}
+	testCases := []testCase{
+		{"a|b", "[ab]", true},
+		{"(a*)*", "a*", true},
+		{"a+", "aa*", true},
+		{"(a|b)*abb", "[ab]*abb", true},
+		{"/api/v1/[0-9]+", `/api/v1/\d+`, true},
+		{"a+", "a*", false},
+		{"ab", "ba", false},
+		{"[a-z]", "[a-y]", false},
+	}
+	for _, tc := range testCases {
+		a, b := Minimize(compile(t, tc.a)), Minimize(compile(t, tc.b))
+		if got := Equal(a, b); got != tc.want {
+			t.Errorf("Equal(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+
+	if !Equal(Minimize(Union(compile(t, "b"), compile(t, "c"))), Minimize(compile(t, "[bc]"))) {
+		t.Errorf("Minimize does not merge adjacent ranges")
+	}
+
+	n := compile(t, "(a|b)*abb")
+	if !Equal(n, n) {
+		t.Errorf("an automaton is not equal to itself")
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	same := [][]string{
+		{"a|b", "[ab]", "(?:b|a)"},
+		{"(a|b)*abb", "[ab]*abb", "(b|a)*ab{2}"},
+		{"/api/v1/[0-9]+", `/api/v1/\d+`, "/api/v1/[0-9][0-9]*"},
+	}
+	seen := make(map[[32]byte]string)
+	for _, group := range same {
+		fp := Fingerprint(compile(t, group[0]))
+		for _, e := range group[1:] {
+			if Fingerprint(compile(t, e)) != fp {
+				t.Errorf("Fingerprint(%q) != Fingerprint(%q)", e, group[0])
+			}
+		}
+		if prev, ok := seen[fp]; ok {
+			t.Errorf("Fingerprint(%q) == Fingerprint(%q)", group[0], prev)
+		}
+		seen[fp] = group[0]
+	}
+}
+
+func TestKey(t *testing.T) {
+	same := [][]string{
+		{"a|b", "[ab]", "(?:b|a)"},
+		{"(a|b)*abb", "[ab]*abb", "(b|a)*ab{2}"},
+		{"", "(?:)", "a{0}"},
+		{NoMatch, "a[^\\x00-\\x{10FFFF}]", "[^\\x00-\\x{10FFFF}]+b"},
+	}
+	seen := make(map[Key]string)
+	for _, group := range same {
+		key := KeyOf(compile(t, group[0]))
+		for _, e := range group[1:] {
+			if KeyOf(compile(t, e)) != key {
+				t.Errorf("KeyOf(%q) != KeyOf(%q)", e, group[0])
+			}
+		}
+		if prev, ok := seen[key]; ok {
+			t.Errorf("KeyOf(%q) == KeyOf(%q)", group[0], prev)
+		}
+		seen[key] = group[0]
+		if key.Fingerprint() != Fingerprint(compile(t, group[0])) {
+			t.Errorf("KeyOf(%q).Fingerprint() differs from Fingerprint", group[0])
+		}
+	}
+}
+
+func TestUTF8(t *testing.T) {
+	exprs := []string{
+		"abc", "[α-ω]+", ".", "[^a]", `\p{Greek}+`, "日本(語)?", `[\x{10000}-\x{10FFFF}]`, "(?s:.)*x", `^\bab$`,
+	}
+	samples := []string{
+		"abc", "αβγ", "a", "b", "é", "日本", "日本語", "😀", "\U0010FFFF", "xx", "ωx", "\xff", "\xed\xa0\x80", "\xe6\x97", "ab",
+	}
+	for _, e := range exprs {
+		n := compile(t, e)
+		b, err := UTF8(n)
+		if err != nil {
+			t.Fatalf("UTF8(%q): %v", e, err)
+		}
+		for _, s := range samples {
+			want := utf8.ValidString(s) && accepts(n, s)
+			if got := MatchBytes(b, []byte(s)); got != want {
+				t.Errorf("MatchBytes(UTF8(%q), %q) = %v, want %v", e, s, got, want)
+			}
+		}
+	}
+}
+
+func TestTrim(t *testing.T) {
+	dead := &Node{State: 2}
+	final := &Node{State: 3, Final: true}
+	root := &Node{State: 1, Transitions: []T{{[]rune{'a', 'a'}, dead}, {[]rune{'b', 'b'}, final}}}
+	final.Transitions = []T{{[]rune{'c', 'c'}, dead}}
+	dead.Transitions = []T{{[]rune{'d', 'd'}, dead}}
+
+	trimmed := Trim(root)
+	if got, want := dump(trimmed), "1 false [98 98]->3\n3 true\n"; got != want {
+		t.Errorf("Trim() =\n%s\nwant\n%s", got, want)
+	}
+	if len(root.Transitions) != 2 {
+		t.Errorf("Trim modified its argument")
+	}
+	if got := dump(Trim(&Node{State: 1, Transitions: []T{{[]rune{'a', 'a'}, dead}}})); got != "1 false\n" {
+		t.Errorf("Trim() of an empty language = %q", got)
+	}
+
+	for _, e := range []string{"ab[^\\x00-\\x{10FFFF}]|c", "a^b|c", "(a|b)*abb"} {
+		nodes := reachable(compile(t, e))
+		live := canReachFinal(nodes)
+		for _, n := range nodes[1:] {
+			if !live[n] {
+				t.Errorf("the DFA of %q has dead state %d", e, n.State)
+			}
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	universe := []rune{'a', 'c'}
+	n := Complete(compile(t, "ab*"), universe)
+	if got, want := dump(n), "1 false [97 97]->2 [98 99]->4\n"+
+		"2 true [97 97 99 99]->4 [98 98]->3\n"+
+		"4 false [97 99]->4\n"+
+		"3 true [97 97 99 99]->4 [98 98]->3\n"; got != want {
+		t.Errorf("Complete() =\n%s\nwant\n%s", got, want)
+	}
+	for _, node := range reachable(n) {
+		for _, r := range "abc" {
+			if node.NextState([]rune{r, r}) == nil {
+				t.Errorf("state %d has no transition on %q", node.State, r)
+			}
+		}
+	}
+	checkMatches(t, "Complete(ab*)", n, []matchCase{
+		{"a", true}, {"abbb", true}, {"", false}, {"ac", false}, {"ba", false},
+	})
+
+	full := compile(t, "[a-c]*")
+	if got := len(reachable(Complete(full, universe))); got != len(reachable(full)) {
+		t.Errorf("Complete added a sink to a complete automaton")
+	}
+}
+
+func TestAlphabet(t *testing.T) {
+	type testCase struct {
+		expr string
+		want []rune
+	}
+	testCases := []testCase{
+		{"", nil},
+		{"abc|b", []rune{'a', 'c'}},
+		{"[0-9]+|[a-f]x", []rune{'0', '9', 'a', 'f', 'x', 'x'}},
+		{"^a", []rune{'a', 'a'}},
+	}
+	for _, tc := range testCases {
+		if got := Alphabet(compile(t, tc.expr)); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Alphabet(%q) = %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+
+	n := compile(t, "a(b|c)*")
+	if got := Complete(n, Alphabet(n)); len(reachable(got)) != len(reachable(n))+1 {
+		t.Errorf("Complete(n, Alphabet(n)) did not add a sink")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	for _, expr := range []string{"", "a", "(a|b)*abb", `^\d+(\.\d+)?$`, "[^a]é+", NoMatch} {
+		n := compile(t, expr)
+		data := Marshal(n)
+		if data[0] != FormatVersion {
+			t.Errorf("Marshal(%q) starts with version %d, want %d", expr, data[0], FormatVersion)
+		}
+		got, err := Unmarshal(data)
+		if err != nil {
+			t.Errorf("Unmarshal(Marshal(%q)) error: %v", expr, err)
+			continue
+		}
+		if !Equal(got, n) || got.State != n.State {
+			t.Errorf("Unmarshal(Marshal(%q)) differs from the automaton", expr)
+		}
+
+		var u Node
+		if err := u.UnmarshalBinary(data); err != nil || !Equal(&u, n) {
+			t.Errorf("UnmarshalBinary(Marshal(%q)) = %v, differs from the automaton", expr, err)
+		}
+	}
+
+	// The start state of a decoded automaton may be the target of transitions.
+	var u Node
+	if err := u.UnmarshalBinary(Marshal(Minimize(compile(t, "a*")))); err != nil || u.Transitions[0].Node != &u {
+		t.Errorf("UnmarshalBinary(Marshal(a*)) = %v, want a loop on the node", err)
+	}
+
+	data := Marshal(compile(t, "ab"))
+	newer := append([]byte{FormatVersion + 1}, data[1:]...)
+	if _, err := Unmarshal(newer); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Unmarshal of version %d = %v, want ErrUnsupportedVersion", FormatVersion+1, err)
+	}
+	negative := Marshal(&Node{Transitions: []T{{RuneRanges: []rune{nfa.RuneBeginText, nfa.RuneBeginText}, Node: &Node{Final: true}}}})
+	for _, bad := range [][]byte{nil, {FormatVersion}, data[:len(data)-1], append(data, 0), {FormatVersion, 2, 0, 0, 1, 2, 0, 0}, negative} {
+		if _, err := Unmarshal(bad); !errors.Is(err, ErrInvalidData) {
+			t.Errorf("Unmarshal(%v) = %v, want ErrInvalidData", bad, err)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	type testCase struct {
+		a, b  string
+		equal bool
+		onlyA []string
+		onlyB []string
+	}
+	testCases := []testCase{
+		{"a|b", "[ab]", true, nil, nil},
+		{"a*", "a", false, []string{"", "aa", "aaa", "aaaa", "aaaaa"}, nil},
+		{"a", "b", false, []string{"a"}, []string{"b"}},
+		{"[0-9]+", "[0-9]{1,3}", false, []string{"0000", "00000", "000000", "0000000", "00000000"}, nil},
+		{"(ab)*", "(ab)+|c", false, []string{""}, []string{"c"}},
+		{NoMatch, "x", false, nil, []string{"x"}},
+	}
+	for _, tc := range testCases {
+		got := Diff(compile(t, tc.a), compile(t, tc.b))
+		want := DiffReport{Equal: tc.equal, OnlyA: tc.onlyA, OnlyB: tc.onlyB}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Diff(%q, %q) = %#v, want %#v", tc.a, tc.b, got, want)
+		}
+	}
+
+	want := "languages differ\n- \"a\"\n+ \"b\"\n"
+	if got := Diff(compile(t, "a"), compile(t, "b")).String(); got != want {
+		t.Errorf("Diff(a, b).String() = %q, want %q", got, want)
+	}
+	if got := Diff(compile(t, "a"), compile(t, "a")).String(); got != "languages are equal\n" {
+		t.Errorf("Diff(a, a).String() = %q", got)
+	}
+}
+
+func TestReverse(t *testing.T) {
+	checkMatches(t, "Reverse(ab+c)", Reverse(compile(t, "ab+c")), []matchCase{
+		{"cba", true},
+		{"cbba", true},
+		{"abc", false},
+		{"ca", false},
+	})
+	checkMatches(t, "Reverse(a*)", Reverse(compile(t, "a*")), []matchCase{
+		{"", true},
+		{"aaa", true},
+	})
+}
+
+func TestLiterals(t *testing.T) {
+	type testCase struct {
+		expr       string
+		prefix     string
+		suffix     string
+		substrings []string
+	}
+	testCases := []testCase{
+		{"abc", "abc", "abc", nil},
+		{"/api/v[0-9]+/users", "/api/v", "/users", nil},
+		{"[a-z]+@example\\.com", "", "@example.com", nil},
+		{"(abc|xbcy)", "", "", []string{"bc"}},
+		{"a(b|c)d", "a", "d", nil},
+		{".*error: .*timeout.*", "", "", []string{"error: ", "timeout"}},
+		{"(foo|bar)+", "", "", nil},
+		{"x*", "", "", nil},
+		{"^GET /", "GET /", "GET /", nil},
+		{"ab(ab)*", "ab", "ab", nil},
+	}
+	for _, tc := range testCases {
+		prefix, suffix, substrings := Literals(compile(t, tc.expr))
+		if prefix != tc.prefix || suffix != tc.suffix || !reflect.DeepEqual(substrings, tc.substrings) {
+			t.Errorf("Literals(%q) = %q, %q, %q, want %q, %q, %q", tc.expr, prefix, suffix, substrings, tc.prefix, tc.suffix, tc.substrings)
+		}
+	}
+}
+
+func TestLengths(t *testing.T) {
+	type testCase struct {
+		expr     string
+		shortest int
+		longest  int
+	}
+	testCases := []testCase{
+		{"", 0, 0},
+		{"abc", 3, 3},
+		{"a{2,5}|xyz", 2, 5},
+		{"ab+", 2, -1},
+		{"(ab)?c?", 0, 3},
+		{"^é$", 1, 1},
+		{"(^|x)y", 1, 2},
+		{"a*b|c", 1, -1},
+		{NoMatch, -1, -1},
+	}
+	for _, tc := range testCases {
+		shortest, longest := Lengths(compile(t, tc.expr))
+		if shortest != tc.shortest || longest != tc.longest {
+			t.Errorf("Lengths(%q) = %d, %d, want %d, %d", tc.expr, shortest, longest, tc.shortest, tc.longest)
+		}
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	type testCase struct {
+		expr string
+		want string
+		ok   bool
+	}
+	testCases := []testCase{
+		{"abc", "abc", true},
+		{"", "", true},
+		{"(?i:é)", "", false},
+		{"a{3}(b|b)", "aaab", true},
+		{"^/health$", "/health", true},
+		{"a|b", "", false},
+		{"ab?", "", false},
+		{"a+", "", false},
+		{NoMatch, "", false},
+	}
+	for _, tc := range testCases {
+		if got, ok := Literal(compile(t, tc.expr)); got != tc.want || ok != tc.ok {
+			t.Errorf("Literal(%q) = %q, %v, want %q, %v", tc.expr, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestFindPrefix(t *testing.T) {
+	type testCase struct {
+		expr     string
+		in       string
+		shortest int
+		longest  int
+		ok       bool
+	}
+	testCases := []testCase{
+		{"/api(/v[0-9]+)*", "/api/v1/v22/x", 4, 11, true},
+		{"/api(/v[0-9]+)*", "/ap", 0, 0, false},
+		{"a*", "bbb", 0, 0, true},
+		{"é+", "ééa", 2, 4, true},
+		{"ab|abcd", "abcd", 2, 4, true},
+		{"ab|abcd", "abc", 2, 2, true},
+		{"abc", "ab", 0, 0, false},
+		{NoMatch, "", 0, 0, false},
+	}
+	for _, tc := range testCases {
+		n := compile(t, tc.expr)
+		if end, ok := n.FindPrefix(tc.in); end != tc.shortest || ok != tc.ok {
+			t.Errorf("FindPrefix(%q) on %q = %d, %v, want %d, %v", tc.in, tc.expr, end, ok, tc.shortest, tc.ok)
+		}
+		if end, ok := n.FindLongestPrefix(tc.in); end != tc.longest || ok != tc.ok {
+			t.Errorf("FindLongestPrefix(%q) on %q = %d, %v, want %d, %v", tc.in, tc.expr, end, ok, tc.longest, tc.ok)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	for _, expr := range []string{"[0-9]+", "a|ab|abc", "x*", "é+b", "(a|b)*abb", NoMatch} {
+		n := compile(t, expr)
+		re := regexp.MustCompile(expr)
+		re.Longest()
+		for _, s := range []string{"", "abc 12 345", "ababb", "xxéébé", "zzz", "\xffab"} {
+			start, end, ok := Find(n, s)
+			want := re.FindStringIndex(s)
+			if ok != (want != nil) || ok && (start != want[0] || end != want[1]) {
+				t.Errorf("Find(%q) on %q = %d, %d, %v, want %v", s, expr, start, end, ok, want)
+			}
+		}
+	}
+
+	if start, end, ok := Find(compile(t, "^abc$"), "abc"); !ok || start != 0 || end != 3 {
+		t.Errorf("Find(abc) on ^abc$ = %d, %d, %v", start, end, ok)
+	}
+	// Unlike regexp, which finds nothing here, ^ holds at the start of the
+	// substring.
+	if start, end, ok := Find(compile(t, "^a"), "ba"); !ok || start != 1 || end != 2 {
+		t.Errorf("Find(ba) on ^a = %d, %d, %v, want 1, 2, true", start, end, ok)
+	}
+}
+
+func TestFindAllIndex(t *testing.T) {
+	for _, expr := range []string{"[0-9]+", "a|ab|abc", "x*", "é*b", "(a|b)*abb", NoMatch} {
+		table := Freeze(compile(t, expr))
+		re := regexp.MustCompile(expr)
+		re.Longest()
+		for _, s := range []string{"", "abc 12 345", "ababb abb", "xxéébéx", "zzz", "\xffab\xff"} {
+			for _, n := range []int{-1, 0, 2} {
+				got := table.FindAllIndex([]byte(s), n)
+				if want := re.FindAllIndex([]byte(s), n); fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("FindAllIndex(%q, %d) on %q = %v, want %v", s, n, expr, got, want)
+				}
+			}
+			if got, want := table.CountMatches([]byte(s)), len(re.FindAllIndex([]byte(s), -1)); got != want {
+				t.Errorf("CountMatches(%q) on %q = %d, want %d", s, expr, got, want)
+			}
+		}
+	}
+}
+
+func TestFromStrings(t *testing.T) {
+	type testCase struct {
+		words  []string
+		states int
+	}
+	testCases := []testCase{
+		{nil, 1},
+		{[]string{""}, 1},
+		{[]string{"tap", "taps", "top", "tops"}, 5},
+		{[]string{"abc", "abd", "abe", "b", "é", "abc"}, 4},
+		{[]string{"cat", "dog", "", "cats", "dogs", "a", "ab", "abc"}, 9},
+	}
+	for _, tc := range testCases {
+		n := FromStrings(tc.words)
+		var literals []*Node
+		for _, w := range tc.words {
+			literals = append(literals, compile(t, regexp.QuoteMeta(w)))
+		}
+		if want := Minimize(Union(literals...)); !Equal(n, want) {
+			t.Errorf("FromStrings(%q) differs from the union of the words", tc.words)
+		}
+		if got := len(reachable(n)); got != tc.states {
+			t.Errorf("FromStrings(%q) has %d states, want %d", tc.words, got, tc.states)
+		}
+		for _, w := range append(tc.words, "ta", "tapss", "x") {
+			if n.Match(w) != slices.Contains(tc.words, w) {
+				t.Errorf("FromStrings(%q).Match(%q) = %v", tc.words, w, n.Match(w))
+			}
+		}
+	}
+}
+
+func TestDensity(t *testing.T) {
+	type testCase struct {
+		expr   string
+		length int
+		min    float64
+		max    float64
+	}
+	testCases := []testCase{
+		{"(?s:.*)", 10, 1, 1},
+		{"(?s:.+)", 0, 0, 0},
+		{"(?s:.+)", 5, 0.999999, 1},
+		{"(?s:.)", 1, 0.999999, 1},
+		{"(?s:.)", 2, 0, 1e-6},
+		{".*", 10, 0.99999, 0.999999},
+		{"[a-z]+", 3, 0, 1e-12},
+		{"", 3, 0, 1e-18},
+		{NoMatch, 3, 0, 0},
+		{"(?s:.*)", -1, 0, 0},
+	}
+	for _, tc := range testCases {
+		if got := Density(compile(t, tc.expr), tc.length); got < tc.min || got > tc.max {
+			t.Errorf("Density(%q, %d) = %g, want in [%g, %g]", tc.expr, tc.length, got, tc.min, tc.max)
+		}
+	}
+	// A pattern accepting more strings is denser.
+	if a, b := Density(compile(t, "[a-z0-9]+"), 8), Density(compile(t, "[a-z]+"), 8); a <= b {
+		t.Errorf("Density([a-z0-9]+) = %g, not above Density([a-z]+) = %g", a, b)
+	}
+}
+
+func TestExportTable(t *testing.T) {
+	// match runs the exported arrays as a runtime of another language
+	// would.
+	match := func(table []int32, alphabet []rune, s string) bool {
+		width := 1 + len(alphabet)/2
+		state := 0
+		for _, r := range s {
+			c := sort.Search(len(alphabet)/2, func(c int) bool { return alphabet[2*c+1] >= r })
+			if c == len(alphabet)/2 || alphabet[2*c] > r {
+				return false
+			}
+			if state = int(table[state*width+1+c]); state < 0 {
+				return false
+			}
+		}
+		return table[state*width] == 1
+	}
+	for _, e := range []string{"", "(a|b)*abb", "[a-z]+@[a-z]+\\.com", "[^a]é+|x{2,}", NoMatch} {
+		n := compile(t, e)
+		table, alphabet := ExportTable(n)
+		if width := 1 + len(alphabet)/2; len(table) != width*len(reachable(n)) {
+			t.Errorf("ExportTable(%q) has %d entries for %d states of width %d", e, len(table), len(reachable(n)), width)
+		}
+		for _, s := range []string{"", "abb", "aabb", "ab", "x@y.com", "bé", "ééé", "xxx", "a"} {
+			if got, want := match(table, alphabet, s), n.Match(s); got != want {
+				t.Errorf("exported %q matches %q: %v, want %v", e, s, got, want)
+			}
+		}
+	}
+	if table, alphabet := ExportTable(Minimize(compile(t, "[a-c]x|[b-d]y"))); len(alphabet) != 10 || len(table) != 5*6 {
+		t.Errorf("ExportTable([a-c]x|[b-d]y) = %v, %q", table, alphabet)
+	}
+}
+
+func TestIsEmpty(t *testing.T) {
+	for _, e := range []string{NoMatch, "a[^\\x00-\\x{10FFFF}]"} {
+		if !IsEmpty(compile(t, e)) {
+			t.Errorf("IsEmpty(%q) = false", e)
+		}
+	}
+	for _, e := range []string{"", "a", "(a|b)*abb"} {
+		if IsEmpty(compile(t, e)) {
+			t.Errorf("IsEmpty(%q) = true", e)
+		}
+	}
+	if !IsEmpty(Subtract(compile(t, "ab"), compile(t, "a."))) {
+		t.Errorf("IsEmpty(ab - a.) = false")
+	}
+}
+
+func TestLimitsCheck(t *testing.T) {
+	type testCase struct {
+		limits Limits
+		states int
+		want   error
+	}
+	for _, tc := range []testCase{
+		{Limits{MaxStates: 3}, 3, nil},
+		{Limits{MaxStates: 3}, 4, ErrTooManyStates},
+		{Limits{Deadline: time.Now().Add(time.Hour)}, 4, nil},
+		{Limits{Deadline: time.Now().Add(-time.Second)}, 4, ErrDeadlinePassed},
+	} {
+		err := tc.limits.Check(tc.states)
+		if tc.want == nil && err != nil || tc.want != nil && !(errors.Is(err, tc.want) && errors.Is(err, ErrBudgetExceeded)) {
+			t.Errorf("Check(%d) with %+v = %v, want %v", tc.states, tc.limits, err, tc.want)
+		}
+	}
+	if errors.Is(ErrTooManyStates, ErrDeadlinePassed) {
+		t.Errorf("ErrTooManyStates is ErrDeadlinePassed")
+	}
+}
+
+func TestBuildResolvesAssertions(t *testing.T) {
+	// ^a, built by hand.
+	end := &nfa.Node{F: true}
+	mid := &nfa.Node{T: []nfa.T{{R: []rune{'a', 'a'}, N: end}}}
+	begin := &nfa.Node{T: []nfa.T{{R: []rune{nfa.RuneBeginText, nfa.RuneBeginText}, N: mid}}}
+	n := NewFromNFA(begin)
+	if !accepts(n, "a") || accepts(n, "") {
+		t.Error("NewFromNFA(^a) does not accept exactly a")
+	}
+	if got := Alphabet(n); !slices.Equal(got, []rune{'a', 'a'}) {
+		t.Errorf("Alphabet(NewFromNFA(^a)) = %v, want [a a]", got)
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	n := compile(t, "(a|b)*abb|[a-z]+@[a-z]+\\.com")
+	want := Marshal(n)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if !accepts(n, "babb") || accepts(n, "ab") || !accepts(n, "me@x.com") {
+					t.Errorf("concurrent matches disagree")
+				}
+				Minimize(n)
+				Reverse(n)
+				Literals(n)
+				if _, err := ToRegex(n); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if !bytes.Equal(Marshal(n), want) {
+		t.Errorf("the automaton changed while in use")
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	for _, e := range []string{"", "(a|b)*abb", "[a-z]+@[a-z]+\\.com", "[^a]é+|x{2,}", NoMatch} {
+		n := compile(t, e)
+		table := Freeze(n)
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(table); err != nil {
+			t.Fatal(err)
+		}
+		decoded := &Table{}
+		if err := gob.NewDecoder(&buf).Decode(decoded); err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range []string{"", "abb", "babb", "ab", "me@x.com", "bé", "aé", "xx", "x"} {
+			want := accepts(n, s)
+			if table.Match(s) != want || decoded.Match(s) != want || n.Match(s) != want {
+				t.Errorf("%q: table form disagrees on %q", e, s)
+			}
+		}
+		if !Equal(Minimize(table.Thaw()), Minimize(n)) {
+			t.Errorf("Freeze(%q).Thaw() differs", e)
+		}
+	}
+	if table := Freeze(compile(t, "ab")); table.States() != 3 || table.Next(0, 'b') != -1 {
+		t.Errorf("Freeze(ab) has %d states", table.States())
+	}
+}
+
+func TestMapped(t *testing.T) {
+	for _, e := range []string{"", "(a|b)*abb", "[a-z]+@[a-z]+\\.com", "[^a]é+|x{2,}", `^\d+$`, NoMatch} {
+		n := compile(t, e)
+		table := Freeze(n)
+		data := MarshalMapped(table)
+		m, err := OpenMapped(data)
+		if err != nil {
+			t.Errorf("OpenMapped(MarshalMapped(%q)) error: %v", e, err)
+			continue
+		}
+		if m.States() != table.States() {
+			t.Errorf("%q: mapped table has %d states, want %d", e, m.States(), table.States())
+		}
+		for i := 0; i < table.States(); i++ {
+			if m.Final(i) != table.Final[i] {
+				t.Errorf("%q: mapped state %d has Final %v", e, i, m.Final(i))
+			}
+			for _, r := range []rune{-3, 0, 'a', 'b', 'x', 'é', utf8.MaxRune} {
+				if m.Next(i, r) != table.Next(i, r) {
+					t.Errorf("%q: mapped Next(%d, %q) = %d, want %d", e, i, r, m.Next(i, r), table.Next(i, r))
+				}
+			}
+		}
+		for _, s := range []string{"", "abb", "babb", "ab", "me@x.com", "bé", "aé", "xx", "x"} {
+			if m.Match(s) != table.Match(s) {
+				t.Errorf("%q: mapped table disagrees on %q", e, s)
+			}
+		}
+	}
+
+	data := MarshalMapped(Freeze(compile(t, "ab")))
+	newer := bytes.Clone(data)
+	newer[4] = MappedVersion + 1
+	if _, err := OpenMapped(newer); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("OpenMapped of version %d = %v, want ErrUnsupportedVersion", MappedVersion+1, err)
+	}
+	badTarget := bytes.Clone(data)
+	badTarget[mappedHeader+4*4+8] = 9
+	for _, bad := range [][]byte{nil, data[:8], data[:len(data)-1], append(bytes.Clone(data), 0), badTarget} {
+		if _, err := OpenMapped(bad); !errors.Is(err, ErrInvalidData) {
+			t.Errorf("OpenMapped(%v) = %v, want ErrInvalidData", bad, err)
+		}
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	var b Builder
+	for _, e := range []string{"(a|b)*abb", "[a-z]+@[a-z]+\\.com", "x{3,5}", "(a|b)*abb"} {
+		n, err := nfa.New(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := b.Build(n, Limits{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(Marshal(got), Marshal(NewFromNFA(n))) {
+			t.Errorf("Build(%q) differs from NewFromNFA", e)
+		}
+	}
+	n, _ := nfa.New("[a-z]{10}")
+	if _, err := b.Build(n, Limits{MaxStates: 3}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Build exceeding MaxStates returned %v", err)
+	}
+	if got, err := b.Build(n, Limits{}); err != nil || !accepts(got, "abcdefghij") {
+		t.Errorf("Build after an error failed: %v", err)
+	}
+}
+
+func TestLabels(t *testing.T) {
+	n, _ := nfa.New("(a|b)*abb")
+	b := Builder{Labels: true}
+	root, err := b.Build(n, Limits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := make(map[string]bool)
+	for _, node := range reachable(root) {
+		if node.Label() == "" || labels[node.Label()] {
+			t.Errorf("state %d has label %q", node.State, node.Label())
+		}
+		labels[node.Label()] = true
+	}
+	if label := NewFromNFA(n).Label(); label != "" {
+		t.Errorf("NewFromNFA labeled its root %q", label)
+	}
+}
+
+func BenchmarkNewFromNFA(b *testing.B) {
+	n, _ := nfa.New("[a-z]+@[a-z0-9-]+(\\.[a-z]{2,6}){1,3}")
+	for i := 0; i < b.N; i++ {
+		NewFromNFA(n)
+	}
+}
+
+func BenchmarkBuilder(b *testing.B) {
+	n, _ := nfa.New("[a-z]+@[a-z0-9-]+(\\.[a-z]{2,6}){1,3}")
+	var builder Builder
+	for i := 0; i < b.N; i++ {
+		builder.Build(n, Limits{})
+	}
+}
+
+func TestLargeAutomaton(t *testing.T) {
+	// More states than fit in a chunk of nodes, for the NFA and the DFA.
+	n := compile(t, "[ab]*a[ab]{10}|x{900}y{900}")
+	want := regexp.MustCompile("^(?:[ab]*a[ab]{10})$")
+	m := Minimize(n)
+	for _, s := range []string{"abbbbbbbbbbb", "aabababababa", "bbbbbbbbbbba", "babababababab", "ab"} {
+		if accepts(n, s) != want.MatchString(s) || accepts(m, s) != want.MatchString(s) {
+			t.Errorf("accepts(%q) = %v", s, accepts(n, s))
+		}
+	}
+	if xy := strings.Repeat("x", 900) + strings.Repeat("y", 900); !accepts(n, xy) || accepts(n, xy[1:]) {
+		t.Errorf("x{900}y{900} is not matched exactly")
+	}
+}
+
+func TestProgress(t *testing.T) {
+	n, _ := nfa.New("[ab]*a[ab]{11}")
+	var reports []Progress
+	limits := Limits{Progress: func(p Progress) error {
+		reports = append(reports, p)
+		return nil
+	}}
+	if _, err := NewFromNFAWithLimits(n, limits); err != nil {
+		t.Fatal(err)
+	}
+	// The automaton has 2^12 states and a few more.
+	if len(reports) != 4 {
+		t.Fatalf("got %d reports, want 4", len(reports))
+	}
+	for i, p := range reports {
+		if p.States != (i+1)*progressInterval || p.Pending <= 0 || p.Elapsed < 0 {
+			t.Errorf("report %d = %+v", i, p)
+		}
+	}
+
+	abort := errors.New("abort")
+	limits.Progress = func(Progress) error { return abort }
+	if _, err := NewFromNFAWithLimits(n, limits); err != abort {
+		t.Errorf("NewFromNFAWithLimits = %v, want abort", err)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	n, _ := nfa.New("a(b|c)d")
+	var events []Event
+	got, err := NewFromNFAWithLimits(n, Limits{Logger: LoggerFunc(func(e Event) {
+		events = append(events, e)
+	})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[EventKind]int)
+	for _, e := range events {
+		counts[e.Kind]++
+	}
+	nodes := reachable(got)
+	transitions := 0
+	for _, node := range nodes {
+		transitions += len(node.Transitions)
+	}
+	if counts[NodeCreated] != len(nodes) || counts[TransitionAdded] != transitions {
+		t.Errorf("got %v, want %d states and %d transitions", counts, len(nodes), transitions)
+	}
+
+	e := Event{Kind: TransitionAdded, State: 1, To: 2, Ranges: []rune{'a', 'z'}}
+	if s := e.String(); s != "transition 1 -[a-z]-> 2" {
+		t.Errorf("String() = %q", s)
+	}
+}
@@ -0,0 +1,178 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dfa
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// DiffSamples is the number of sample strings Diff reports for each side.
+const DiffSamples = 5
+
+// diffBudget bounds the number of strings Diff tries, as there may be very
+// many strings both automata agree on between two samples.
+const diffBudget = 10000
+
+// DiffReport describes how the languages of two automata differ.
+type DiffReport struct {
+	Equal bool     // the automata accept the same language
+	OnlyA []string // shortest strings accepted by the first automaton only
+	OnlyB []string // shortest strings accepted by the second automaton only
+}
+
+// String formats the report as a diff from the first language to the
+// second: strings only the first accepts are marked with -, strings only
+// the second accepts with +.
+func (r DiffReport) String() string {
+	if r.Equal {
+		return "languages are equal\n"
+	}
+	var b strings.Builder
+	b.WriteString("languages differ\n")
+	for _, s := range r.OnlyA {
+		b.WriteString("- " + strconv.Quote(s) + "\n")
+	}
+	for _, s := range r.OnlyB {
+		b.WriteString("+ " + strconv.Quote(s) + "\n")
+	}
+	return b.String()
+}
+
+// pair is a state of the product of two automata, where nil stands for the
+// dead state.
+type pair [2]*Node
+
+func (p pair) final(i int) bool {
+	return p[i] != nil && p[i].Final
+}
+
+// next returns the states the pair moves to on the runes of r.
+func (p pair) next(r []rune) pair {
+	var q pair
+	for i, n := range p {
+		if n != nil {
+			q[i] = n.NextState(r)
+		}
+	}
+	return q
+}
+
+// moves returns the pairs of runes splitting the transitions of both states.
+func (p pair) moves() [][]rune {
+	var ranges [][]rune
+	for _, n := range p {
+		if n != nil {
+			for _, t := range n.Transitions {
+				ranges = append(ranges, t.RuneRanges)
+			}
+		}
+	}
+	pairs := runerange.Split(ranges)
+	moves := make([][]rune, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		moves = append(moves, pairs[i:i+2])
+	}
+	return moves
+}
+
+// Diff compares the languages of two automata and returns up to
+// DiffSamples of the shortest strings accepted by one automaton but not the
+// other, for each of them.
+func Diff(a, b *Node) DiffReport {
+	// Explore the product, then find the pairs from which a string
+	// accepted by one automaton only can be reached.
+	start := pair{a, b}
+	edges := map[pair][]pair{}
+	order := []pair{start}
+	seen := map[pair]bool{start: true}
+	for i := 0; i < len(order); i++ {
+		for _, r := range order[i].moves() {
+			q := order[i].next(r)
+			edges[q] = append(edges[q], order[i])
+			if !seen[q] {
+				seen[q] = true
+				order = append(order, q)
+			}
+		}
+	}
+
+	// live tells from which pairs strings accepted by the first automaton
+	// only, bit 1, and by the second one only, bit 2, can be reached.
+	live := map[pair]int{}
+	for side := 0; side < 2; side++ {
+		bit := 1 << side
+		var queue []pair
+		for _, p := range order {
+			if p.final(side) && !p.final(1-side) {
+				live[p] |= bit
+				queue = append(queue, p)
+			}
+		}
+		for len(queue) > 0 {
+			p := queue[0]
+			queue = queue[1:]
+			for _, q := range edges[p] {
+				if live[q]&bit == 0 {
+					live[q] |= bit
+					queue = append(queue, q)
+				}
+			}
+		}
+	}
+
+	report := DiffReport{Equal: live[start] == 0}
+	if report.Equal {
+		return report
+	}
+
+	// Enumerate the strings breadth first, only following the pairs that
+	// lead to a sample still wanted, so each step gets closer to one.
+	type path struct {
+		p     pair
+		runes []rune
+	}
+	paths := []path{{p: start}}
+	for tries := 0; len(paths) > 0 && tries < diffBudget; tries++ {
+		cur := paths[0]
+		paths = paths[1:]
+		switch {
+		case cur.p.final(0) && !cur.p.final(1) && len(report.OnlyA) < DiffSamples:
+			report.OnlyA = append(report.OnlyA, sample(cur.runes))
+		case cur.p.final(1) && !cur.p.final(0) && len(report.OnlyB) < DiffSamples:
+			report.OnlyB = append(report.OnlyB, sample(cur.runes))
+		}
+		want := 0
+		if len(report.OnlyA) < DiffSamples {
+			want |= 1
+		}
+		if len(report.OnlyB) < DiffSamples {
+			want |= 2
+		}
+		for _, r := range cur.p.moves() {
+			if q := cur.p.next(r); live[q]&want != 0 {
+				runes := append(cur.runes[:len(cur.runes):len(cur.runes)], r[0])
+				paths = append(paths, path{q, runes})
+			}
+		}
+	}
+	return report
+}
+
+// sample returns the string of a path through the product.
+func sample(runes []rune) string {
+	return string(runes)
+}
@@ -0,0 +1,72 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !tinygo
+
+package dfa
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// Print prints the states reachable from n as DumpTable does.
+func (n Node) Print() {
+	root := &n
+	// The copy leads back to n itself if n is on a cycle.
+	for _, node := range reachable(root)[1:] {
+		if node.State == n.State {
+			root = node
+			break
+		}
+	}
+	DumpTable(os.Stdout, root)
+}
+
+// DumpTable writes the states reachable from n as a table with a row per
+// state and next state, the runes leading there written as a regular
+// expression such as [a-z0-9]. States are listed in breadth-first order.
+func DumpTable(w io.Writer, n *Node) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "state\tfinal\trunes\tnext")
+	for _, node := range reachable(n) {
+		final := "no"
+		if node.Final {
+			final = "yes"
+		}
+		var targets []*Node
+		ranges := make(map[*Node][]rune)
+		for _, t := range node.Transitions {
+			if _, ok := ranges[t.Node]; !ok {
+				targets = append(targets, t.Node)
+			}
+			ranges[t.Node] = runerange.Sum(ranges[t.Node], t.RuneRanges)
+		}
+		if len(targets) == 0 {
+			fmt.Fprintf(tw, "%d\t%s\t\t\n", node.State, final)
+		}
+		for i, t := range targets {
+			if i == 0 {
+				fmt.Fprintf(tw, "%d\t%s\t", node.State, final)
+			} else {
+				fmt.Fprint(tw, "\t\t")
+			}
+			fmt.Fprintf(tw, "%s\t%d\n", classExpr(ranges[t]).s, t.State)
+		}
+	}
+	tw.Flush()
+}
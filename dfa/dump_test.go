@@ -0,0 +1,58 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !tinygo
+
+package dfa
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestDumpTable(t *testing.T) {
+	var b bytes.Buffer
+	DumpTable(&b, compile(t, "a[bc]|b"))
+	want := "" +
+		"state  final  runes  next\n" +
+		"1      no     a      2\n" +
+		"              b      4\n" +
+		"2      no     [bc]   3\n" +
+		"4      yes           \n" +
+		"3      yes           \n"
+	if b.String() != want {
+		t.Errorf("DumpTable wrote\n%s\nwant\n%s", b.String(), want)
+	}
+
+	// Print takes a copy of the node, which must not show up as a state of
+	// its own.
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	n := Minimize(compile(t, "a*"))
+	(*n).Print()
+	os.Stdout = stdout
+	w.Close()
+	b.Reset()
+	b.ReadFrom(r)
+	want = "" +
+		"state  final  runes  next\n" +
+		"1      yes    a      1\n"
+	if b.String() != want {
+		t.Errorf("Print wrote\n%s\nwant\n%s", b.String(), want)
+	}
+}
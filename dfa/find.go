@@ -0,0 +1,34 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dfa
+
+import "unicode/utf8"
+
+// Find returns the byte offsets of the leftmost-longest substring of s the
+// automaton accepts, and whether there is one. The assertions the automaton
+// was built with are judged as if the substring were the whole input, not
+// within s as regexp does: ^a finds "a" in "ba" at offset 1.
+func Find(n *Node, s string) (start, end int, ok bool) {
+	for start = 0; start <= len(s); {
+		if end, ok = n.FindLongestPrefix(s[start:]); ok {
+			return start, start + end, true
+		}
+		if start == len(s) {
+			break
+		}
+		_, size := utf8.DecodeRuneInString(s[start:])
+		start += size
+	}
+	return 0, 0, false
+}
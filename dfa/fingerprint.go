@@ -0,0 +1,78 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dfa
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// Fingerprint returns a SHA-256 hash of the language accepted by n. Automata
+// accepting the same language have the same fingerprint no matter which
+// patterns they were built from.
+func Fingerprint(n *Node) [32]byte {
+	return sha256.Sum256(canonical(Minimize(n)))
+}
+
+// Key identifies the language of an automaton exactly. Keys are comparable,
+// so they can be map keys for caches and deduplication: the keys of two
+// automata are equal if and only if they accept the same language.
+type Key struct {
+	canonical string
+}
+
+// KeyOf returns the key of the language accepted by n, the encoding of its
+// minimal automaton. Unlike fingerprints, keys grow with the automata.
+func KeyOf(n *Node) Key {
+	return Key{string(canonical(Minimize(n)))}
+}
+
+// Fingerprint returns the fingerprint of the language of the key, as
+// Fingerprint does for its automata.
+func (k Key) Fingerprint() [32]byte {
+	return sha256.Sum256([]byte(k.canonical))
+}
+
+// canonical encodes the graph of n. For a minimized automaton, the encoding
+// depends on its language only.
+func canonical(n *Node) []byte {
+	nodes := reachable(n)
+	index := make(map[*Node]int64, len(nodes))
+	for i, node := range nodes {
+		index[node] = int64(i)
+	}
+
+	var buf []byte
+	tmp := make([]byte, binary.MaxVarintLen64)
+	put := func(v int64) {
+		buf = append(buf, tmp[:binary.PutVarint(tmp, v)]...)
+	}
+	put(int64(len(nodes)))
+	for _, node := range nodes {
+		if node.Final {
+			put(1)
+		} else {
+			put(0)
+		}
+		put(int64(len(node.Transitions)))
+		for _, t := range node.Transitions {
+			put(int64(len(t.RuneRanges)))
+			for _, r := range t.RuneRanges {
+				put(int64(r))
+			}
+			put(index[t.Node])
+		}
+	}
+	return buf
+}
@@ -0,0 +1,116 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dfa
+
+// IsEmpty reports whether the automaton accepts no string at all.
+func IsEmpty(n *Node) bool {
+	for _, node := range reachable(n) {
+		if node.Final {
+			return false
+		}
+	}
+	return true
+}
+
+// Lengths returns the lengths in runes of the shortest and the longest
+// strings the automaton accepts, longest being -1 if they are unbounded.
+// Both are -1 if the automaton accepts nothing.
+func Lengths(n *Node) (shortest, longest int) {
+	nodes := reachable(n)
+	live := canReachFinal(nodes)
+	if !live[n] {
+		return -1, -1
+	}
+	dist := map[*Node]int{n: 0}
+	queue := []*Node{n}
+	shortest = -1
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if node.Final {
+			shortest = dist[node]
+			break
+		}
+		for _, t := range node.Transitions {
+			if _, ok := dist[t.Node]; !ok {
+				dist[t.Node] = dist[node] + 1
+				queue = append(queue, t.Node)
+			}
+		}
+	}
+
+	// The strings are unbounded if a component of live states is entered
+	// again. Tarjan's algorithm finds the components
+	// successors first, so the longest strings from each can be computed
+	// as they come.
+	index := make(map[*Node]int)
+	low := make(map[*Node]int)
+	component := make(map[*Node]int)
+	var stack []*Node
+	var longestFrom []int
+	unbounded := false
+	var visit func(node *Node)
+	visit = func(node *Node) {
+		index[node] = len(index)
+		low[node] = index[node]
+		stack = append(stack, node)
+		for _, t := range node.Transitions {
+			if !live[t.Node] {
+				continue
+			}
+			if _, ok := index[t.Node]; !ok {
+				visit(t.Node)
+				low[node] = min(low[node], low[t.Node])
+			} else if _, assigned := component[t.Node]; !assigned {
+				low[node] = min(low[node], index[t.Node])
+			}
+		}
+		if low[node] != index[node] {
+			return
+		}
+		c := len(longestFrom)
+		var members []*Node
+		for {
+			m := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			component[m] = c
+			members = append(members, m)
+			if m == node {
+				break
+			}
+		}
+		l := -1
+		for _, m := range members {
+			if m.Final {
+				l = max(l, 0)
+			}
+			for _, t := range m.Transitions {
+				if !live[t.Node] {
+					continue
+				}
+				if component[t.Node] == c {
+					unbounded = true
+				} else if from := longestFrom[component[t.Node]]; from >= 0 {
+					l = max(l, from+1)
+				}
+			}
+		}
+		longestFrom = append(longestFrom, l)
+	}
+	visit(n)
+	if unbounded {
+		return shortest, -1
+	}
+	return shortest, longestFrom[component[n]]
+}
@@ -0,0 +1,245 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dfa
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// maxLiteral bounds the length of the substrings Literals considers, and
+// maxChains the number of paths it takes them from.
+const (
+	maxLiteral = 64
+	maxChains  = 256
+)
+
+// Literals returns strings every string the automaton accepts has to
+// contain, for prefilters to rule out inputs before running the automaton:
+// the longest common prefix and suffix of the accepted strings, and the
+// longest other substrings they all contain, sorted.
+func Literals(n *Node) (prefix, suffix string, substrings []string) {
+	n = Minimize(n)
+	prefix, _ = forcedPrefix(n)
+	suffix, _ = forcedPrefix(Minimize(Reverse(n)))
+	suffix = reverseString(suffix)
+
+	// A substring of a required string is required too, so the longest
+	// required substring starting at some rune of a chain can be found by
+	// bisection.
+	var required []string
+	tested := make(map[string]bool)
+	for _, chain := range chains(n) {
+		for i := range chain {
+			lo, hi := 0, len(chain)-i // chain[i:i+lo] is required
+			for lo < hi {
+				mid := (lo + hi + 1) / 2
+				w := chain[i : i+mid]
+				ok, done := tested[string(w)]
+				if !done {
+					ok = mustContain(n, w)
+					tested[string(w)] = ok
+				}
+				if ok {
+					lo = mid
+				} else {
+					hi = mid - 1
+				}
+			}
+			if lo > 0 {
+				required = append(required, string(chain[i:i+lo]))
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, s := range required {
+		if !seen[s] && !strings.Contains(prefix, s) && !strings.Contains(suffix, s) && !containedInOther(s, required) {
+			substrings = append(substrings, s)
+		}
+		seen[s] = true
+	}
+	sort.Strings(substrings)
+	return prefix, suffix, substrings
+}
+
+// Literal returns the only string the automaton accepts, if it accepts
+// exactly one.
+func Literal(n *Node) (string, bool) {
+	s, end := forcedPrefix(Minimize(n))
+	if !end.Final || len(end.Transitions) > 0 {
+		return "", false
+	}
+	return s, true
+}
+
+// forcedPrefix returns the runes every accepted string starts with, read
+// while the automaton has a single way to go, and the state reached.
+func forcedPrefix(n *Node) (string, *Node) {
+	var b strings.Builder
+	seen := make(map[*Node]bool)
+	for !n.Final && len(n.Transitions) == 1 && !seen[n] {
+		seen[n] = true
+		t := n.Transitions[0]
+		rr := t.RuneRanges
+		if len(rr) != 2 || rr[0] != rr[1] {
+			break
+		}
+		b.WriteRune(rr[0])
+		n = t.Node
+	}
+	return b.String(), n
+}
+
+// chains returns the runes read along simple paths of transitions on single
+// runes, up to maxLiteral runes long and maxChains paths in all. These are
+// where Literals looks for required substrings.
+func chains(n *Node) [][]rune {
+	var result [][]rune
+	onPath := make(map[*Node]bool)
+	var path []rune
+	var walk func(node *Node)
+	walk = func(node *Node) {
+		onPath[node] = true
+		extended := false
+		for _, t := range node.Transitions {
+			r, ok := singleRune(t.RuneRanges)
+			if !ok || onPath[t.Node] || len(path) == maxLiteral || len(result) == maxChains {
+				continue
+			}
+			extended = true
+			path = append(path, r)
+			walk(t.Node)
+			path = path[:len(path)-1]
+		}
+		if !extended && len(path) > 0 && len(result) < maxChains {
+			result = append(result, append([]rune(nil), path...))
+		}
+		onPath[node] = false
+	}
+	for _, node := range reachable(n) {
+		walk(node)
+	}
+	return result
+}
+
+func singleRune(rr []rune) (rune, bool) {
+	if len(rr) == 2 && rr[0] == rr[1] {
+		return rr[0], true
+	}
+	return 0, false
+}
+
+// mustContain reports whether every string the automaton accepts contains
+// w. It searches the product of the automaton and of the Knuth-Morris-Pratt
+// automaton of w for an accepted string avoiding w.
+func mustContain(n *Node, w []rune) bool {
+	// fail[k] is the length of the longest proper border of w[:k].
+	fail := make([]int, len(w)+1)
+	for k := 2; k <= len(w); k++ {
+		b := fail[k-1]
+		for b > 0 && w[b] != w[k-1] {
+			b = fail[b]
+		}
+		if w[b] == w[k-1] {
+			b++
+		}
+		fail[k] = b
+	}
+	step := func(k int, r rune) int {
+		for k > 0 && w[k] != r {
+			k = fail[k]
+		}
+		if w[k] == r {
+			k++
+		}
+		return k
+	}
+
+	type state struct {
+		node    *Node
+		matched int
+	}
+	start := state{n, 0}
+	seen := map[state]bool{start: true}
+	queue := []state{start}
+	visit := func(s state) {
+		if s.matched < len(w) && !seen[s] {
+			seen[s] = true
+			queue = append(queue, s)
+		}
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		if s.node.Final {
+			return false
+		}
+		for _, t := range s.node.Transitions {
+			rr := t.RuneRanges
+			others := false // the range has runes that are not in w
+			for i := 0; i < len(rr); i += 2 {
+				size := int(rr[i+1]-rr[i]) + 1
+				for _, r := range w {
+					if r >= rr[i] && r <= rr[i+1] {
+						size--
+					}
+				}
+				if size > 0 {
+					others = true
+				}
+			}
+			for _, r := range uniqueRunes(w) {
+				if runerange.In(rr, r) {
+					visit(state{t.Node, step(s.matched, r)})
+				}
+			}
+			if others {
+				visit(state{t.Node, 0})
+			}
+		}
+	}
+	return true
+}
+
+func uniqueRunes(w []rune) []rune {
+	var result []rune
+	seen := make(map[rune]bool)
+	for _, r := range w {
+		if !seen[r] {
+			seen[r] = true
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+func containedInOther(s string, list []string) bool {
+	for _, l := range list {
+		if l != s && strings.Contains(l, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func reverseString(s string) string {
+	rs := []rune(s)
+	for i, j := 0, len(rs)-1; i < j; i, j = i+1, j-1 {
+		rs[i], rs[j] = rs[j], rs[i]
+	}
+	return string(rs)
+}
@@ -0,0 +1,72 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dfa
+
+import (
+	"strconv"
+
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// Logger receives the events of constructions, see Limits.Logger.
+type Logger interface {
+	Log(Event)
+}
+
+// LoggerFunc adapts a function to the Logger interface.
+type LoggerFunc func(Event)
+
+func (f LoggerFunc) Log(e Event) {
+	f(e)
+}
+
+// EventKind tells what happened during a construction.
+type EventKind int
+
+const (
+	NodeCreated         EventKind = iota // a DFA state was created
+	TransitionAdded                      // a transition was added to a DFA state
+	ProductStateVisited                  // a state of a product was explored
+)
+
+// Event describes a step of a construction.
+type Event struct {
+	Kind   EventKind
+	State  int    // state created, origin of the transition or product state
+	To     int    // target of the transition
+	Ranges []rune // runes of the transition
+
+	// Pair holds the states of the two DFAs a product state stands for, 0
+	// for their dead states. It is zero for products of NFAs.
+	Pair [2]int
+}
+
+func (e Event) String() string {
+	switch e.Kind {
+	case NodeCreated:
+		return "state " + strconv.Itoa(e.State) + " created"
+	case TransitionAdded:
+		return "transition " + strconv.Itoa(e.State) + " -" + runerange.String(e.Ranges) + "-> " + strconv.Itoa(e.To)
+	case ProductStateVisited:
+		return "product state " + strconv.Itoa(e.State) + " (" + strconv.Itoa(e.Pair[0]) + ", " + strconv.Itoa(e.Pair[1]) + ") visited"
+	}
+	return "event " + strconv.Itoa(int(e.Kind))
+}
+
+// Log passes e to l.Logger if set.
+func (l Limits) Log(e Event) {
+	if l.Logger != nil {
+		l.Logger.Log(e)
+	}
+}
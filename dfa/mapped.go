@@ -0,0 +1,168 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dfa
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+// MappedVersion is the version of the format written by MarshalMapped.
+const MappedVersion = 1
+
+// mappedMagic starts the data written by MarshalMapped.
+const mappedMagic = "RIDT"
+
+// mappedHeader is the size of the header: the magic, the version and the
+// numbers of states and of entries, as 32-bit words.
+const mappedHeader = 16
+
+// MarshalMapped encodes the table in a format that OpenMapped uses in place,
+// so that an automaton stored in a file can be mapped into memory and run
+// without being decoded. All numbers are little-endian 32-bit words: after
+// the header come the states+1 offsets, then the entries as fixed-width
+// records of Lo, Hi and To, and last the finality of the states as a bitmap.
+func MarshalMapped(t *Table) []byte {
+	states, entries := t.States(), len(t.Lo)
+	buf := make([]byte, 0, mappedSize(states, entries))
+	buf = append(buf, mappedMagic...)
+	buf = binary.LittleEndian.AppendUint32(buf, MappedVersion)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(states))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(entries))
+	for _, o := range t.Offsets {
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(o))
+	}
+	for j := range t.Lo {
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(t.Lo[j]))
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(t.Hi[j]))
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(t.To[j]))
+	}
+	final := make([]byte, (states+7)/8)
+	for i, f := range t.Final {
+		if f {
+			final[i/8] |= 1 << (i % 8)
+		}
+	}
+	return append(buf, final...)
+}
+
+// mappedSize returns the size of the encoding of a table.
+func mappedSize(states, entries int) int {
+	return mappedHeader + 4*(states+1) + 12*entries + (states+7)/8
+}
+
+// Mapped is a table encoded by MarshalMapped and read in place: it holds on
+// to the data, typically a memory-mapped file, and decodes only the words a
+// lookup needs. It must not outlive the data, which must not change.
+type Mapped struct {
+	data            []byte
+	states, entries int
+}
+
+// OpenMapped returns the table encoded in data by MarshalMapped, without
+// copying it. The data is checked once so that lookups need no bounds
+// checks of their own, which reads it entirely but allocates nothing. It
+// fails with an error wrapping ErrUnsupportedVersion or ErrInvalidData.
+func OpenMapped(data []byte) (*Mapped, error) {
+	if len(data) < mappedHeader || string(data[:4]) != mappedMagic {
+		return nil, wrap(ErrInvalidData, "no mapped table header")
+	}
+	if v := binary.LittleEndian.Uint32(data[4:]); v == 0 || v > MappedVersion {
+		return nil, wrap(ErrUnsupportedVersion, strconv.FormatUint(uint64(v), 10)+", want at most "+strconv.Itoa(MappedVersion))
+	}
+	states, entries := binary.LittleEndian.Uint32(data[8:]), binary.LittleEndian.Uint32(data[12:])
+	if states == 0 || states > math.MaxInt32 || entries > math.MaxInt32 {
+		return nil, wrap(ErrInvalidData, "bad table size")
+	}
+	m := &Mapped{data: data, states: int(states), entries: int(entries)}
+	if len(data) != mappedSize(m.states, m.entries) {
+		return nil, wrap(ErrInvalidData, "size "+strconv.Itoa(len(data))+", want "+strconv.Itoa(mappedSize(m.states, m.entries)))
+	}
+	if m.offset(0) != 0 || m.offset(m.states) != m.entries {
+		return nil, wrap(ErrInvalidData, "bad offsets")
+	}
+	for i := 0; i < m.states; i++ {
+		begin, end := m.offset(i), m.offset(i+1)
+		if end < begin || end > m.entries {
+			return nil, wrap(ErrInvalidData, "bad offsets of state "+strconv.Itoa(i))
+		}
+		for j := begin; j < end; j++ {
+			lo, hi, to := m.entry(j)
+			if lo > hi || j > begin && m.hi(j-1) >= lo || to < 0 || int(to) >= m.states {
+				return nil, wrap(ErrInvalidData, "bad entry "+strconv.Itoa(j))
+			}
+		}
+	}
+	return m, nil
+}
+
+// offset returns the index of the first entry of state i.
+func (m *Mapped) offset(i int) int {
+	return int(int32(binary.LittleEndian.Uint32(m.data[mappedHeader+4*i:])))
+}
+
+// entry returns the entry j.
+func (m *Mapped) entry(j int) (lo, hi rune, to int32) {
+	b := m.data[mappedHeader+4*(m.states+1)+12*j:]
+	return rune(binary.LittleEndian.Uint32(b)), rune(binary.LittleEndian.Uint32(b[4:])), int32(binary.LittleEndian.Uint32(b[8:]))
+}
+
+// hi returns the last rune of entry j.
+func (m *Mapped) hi(j int) rune {
+	return rune(binary.LittleEndian.Uint32(m.data[mappedHeader+4*(m.states+1)+12*j+4:]))
+}
+
+// States returns the number of states.
+func (m *Mapped) States() int {
+	return m.states
+}
+
+// Final reports whether state is accepting.
+func (m *Mapped) Final(state int) bool {
+	return m.data[mappedSize(m.states, m.entries)-(m.states+7)/8+state/8]&(1<<(state%8)) != 0
+}
+
+// Next returns the state reached from state on r, or -1 if there is none.
+func (m *Mapped) Next(state int, r rune) int {
+	begin, end := m.offset(state), m.offset(state+1)
+	for begin < end {
+		j := int(uint(begin+end) >> 1)
+		if m.hi(j) < r {
+			begin = j + 1
+		} else {
+			end = j
+		}
+	}
+	if begin < m.offset(state+1) {
+		if lo, _, to := m.entry(begin); lo <= r {
+			return int(to)
+		}
+	}
+	return -1
+}
+
+// Match reports whether the automaton accepts s.
+func (m *Mapped) Match(s string) bool {
+	state := 0
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if state = m.Next(state, r); state < 0 {
+			return false
+		}
+		i += size
+	}
+	return m.Final(state)
+}
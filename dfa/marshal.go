@@ -0,0 +1,178 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dfa
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"unicode"
+)
+
+// FormatVersion is the version of the format written by Marshal. Unmarshal
+// reads data written in this version and in every earlier one, so automata
+// stored by older releases stay readable after an upgrade. Data written by a
+// newer release is rejected with ErrUnsupportedVersion rather than misread.
+const FormatVersion = 1
+
+// ErrUnsupportedVersion is returned by Unmarshal and OpenMapped for data
+// written in a format version newer than FormatVersion or MappedVersion.
+var ErrUnsupportedVersion = errors.New("unsupported format version")
+
+// ErrInvalidData is returned by Unmarshal and OpenMapped for data that is not
+// a marshaled automaton.
+var ErrInvalidData = errors.New("invalid automaton data")
+
+// Marshal encodes the automaton. The first byte of the encoding is the format
+// version, followed by the states in breadth-first order with their numbers,
+// finality and transitions, all as varints.
+func Marshal(n *Node) []byte {
+	nodes := reachable(n)
+	index := make(map[*Node]int64, len(nodes))
+	for i, node := range nodes {
+		index[node] = int64(i)
+	}
+
+	buf := []byte{FormatVersion}
+	tmp := make([]byte, binary.MaxVarintLen64)
+	put := func(v int64) {
+		buf = append(buf, tmp[:binary.PutVarint(tmp, v)]...)
+	}
+	put(int64(len(nodes)))
+	for _, node := range nodes {
+		put(int64(node.State))
+		if node.Final {
+			put(1)
+		} else {
+			put(0)
+		}
+		put(int64(len(node.Transitions)))
+		for _, t := range node.Transitions {
+			put(int64(len(t.RuneRanges)))
+			for _, r := range t.RuneRanges {
+				put(int64(r))
+			}
+			put(index[t.Node])
+		}
+	}
+	return buf
+}
+
+// Unmarshal decodes an automaton encoded by Marshal. It fails with an error
+// wrapping ErrUnsupportedVersion or ErrInvalidData.
+func Unmarshal(data []byte) (*Node, error) {
+	if len(data) == 0 {
+		return nil, wrap(ErrInvalidData, "no data")
+	}
+	if v := data[0]; v == 0 || v > FormatVersion {
+		return nil, wrap(ErrUnsupportedVersion, strconv.Itoa(int(v))+", want at most "+strconv.Itoa(FormatVersion))
+	}
+
+	d := decoder{data: data[1:]}
+	count := d.count()
+	slab := make([]Node, count)
+	nodes := make([]*Node, count)
+	for i := range nodes {
+		nodes[i] = &slab[i]
+	}
+	for _, node := range nodes {
+		node.State = int(d.int(-1<<31, 1<<31-1))
+		node.Final = d.int(0, 1) == 1
+		node.Transitions = make([]T, d.count())
+		for i := range node.Transitions {
+			rr := make([]rune, d.count())
+			// The negative runes older releases encoded anchors with are
+			// rejected.
+			for j := range rr {
+				rr[j] = rune(d.int(0, unicode.MaxRune))
+			}
+			if len(rr)%2 != 0 {
+				d.fail("odd number of runes in a range")
+			}
+			node.Transitions[i] = T{RuneRanges: rr, Node: nodes[d.int(0, int64(count)-1)]}
+		}
+		if d.err != nil {
+			return nil, d.err
+		}
+	}
+	if count == 0 {
+		d.fail("no state")
+	}
+	if d.err == nil && len(d.data) > 0 {
+		d.fail("trailing data")
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return nodes[0], nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler using Marshal.
+func (n *Node) MarshalBinary() ([]byte, error) {
+	return Marshal(n), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler using Unmarshal. The
+// node becomes the start state of the decoded automaton.
+func (n *Node) UnmarshalBinary(data []byte) error {
+	root, err := Unmarshal(data)
+	if err != nil {
+		return err
+	}
+	*n = *root
+	for _, node := range reachable(n) {
+		for i, t := range node.Transitions {
+			if t.Node == root {
+				node.Transitions[i].Node = n
+			}
+		}
+	}
+	return nil
+}
+
+// decoder reads the varints of a marshaled automaton. After the first error
+// all reads return 0.
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) fail(reason string) {
+	if d.err == nil {
+		d.err = wrap(ErrInvalidData, reason)
+	}
+}
+
+// int reads a varint that must be between lo and hi.
+func (d *decoder) int(lo, hi int64) int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.fail("truncated")
+		return 0
+	}
+	d.data = d.data[n:]
+	if v < lo || v > hi {
+		d.fail("value " + strconv.FormatInt(v, 10) + " out of range")
+		return 0
+	}
+	return v
+}
+
+// count reads a number of items, each of which takes at least a byte.
+func (d *decoder) count() int {
+	return int(d.int(0, int64(len(d.data))))
+}
@@ -0,0 +1,56 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !tinygo
+
+package dfa
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// mermaidEscaper replaces the characters Mermaid treats specially in labels
+// with entity codes.
+var mermaidEscaper = strings.NewReplacer("#", "#35;", ";", "#59;", "<", "#60;", ">", "#62;")
+
+// WriteMermaid writes the automaton as a Mermaid state diagram. States are
+// named after their numbers and transitions are labelled with the regular
+// expression matching their runes.
+func WriteMermaid(w io.Writer, n *Node) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "stateDiagram-v2")
+	fmt.Fprintf(bw, "    [*] --> s%d\n", n.State)
+	for _, node := range reachable(n) {
+		var targets []*Node
+		ranges := make(map[*Node][]rune)
+		for _, t := range node.Transitions {
+			if _, ok := ranges[t.Node]; !ok {
+				targets = append(targets, t.Node)
+			}
+			ranges[t.Node] = runerange.Sum(ranges[t.Node], t.RuneRanges)
+		}
+		for _, t := range targets {
+			label := mermaidEscaper.Replace(classExpr(ranges[t]).s)
+			fmt.Fprintf(bw, "    s%d --> s%d: %s\n", node.State, t.State, label)
+		}
+		if node.Final {
+			fmt.Fprintf(bw, "    s%d --> [*]\n", node.State)
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,41 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !tinygo
+
+package dfa
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteMermaid(t *testing.T) {
+	var b strings.Builder
+	if err := WriteMermaid(&b, compile(t, "a|[b;#]c*")); err != nil {
+		t.Fatal(err)
+	}
+	want := `stateDiagram-v2
+    [*] --> s1
+    s1 --> s2: [#35;#59;b]
+    s1 --> s4: a
+    s2 --> s3: c
+    s2 --> [*]
+    s4 --> [*]
+    s3 --> s3: c
+    s3 --> [*]
+`
+	if got := b.String(); got != want {
+		t.Errorf("WriteMermaid() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,169 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dfa
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// Minimize returns the minimal automaton accepting the same language as n.
+// States that cannot reach a final state are removed, so missing
+// transitions lead to the implicit dead state. The states are numbered in
+// breadth-first order from 1, which makes the result canonical: automata
+// accepting the same language minimize to identical graphs.
+func Minimize(n *Node) *Node {
+	nodes := reachable(n)
+	live := canReachFinal(nodes)
+	if !live[n] {
+		return &Node{State: 1}
+	}
+
+	var states []*Node
+	for _, node := range nodes {
+		if live[node] {
+			states = append(states, node)
+		}
+	}
+
+	// Refine the partition into final and non-final states until the
+	// transitions of the states in each block lead to the same blocks.
+	block := make(map[*Node]int, len(states))
+	for _, node := range states {
+		if node.Final {
+			block[node] = 1
+		}
+	}
+	blocks := 0
+	for {
+		next := make(map[*Node]int, len(states))
+		ids := make(map[string]int)
+		for _, node := range states {
+			sig := signature(node, block, live)
+			id, ok := ids[sig]
+			if !ok {
+				id = len(ids)
+				ids[sig] = id
+			}
+			next[node] = id
+		}
+		block = next
+		if len(ids) == blocks {
+			break
+		}
+		blocks = len(ids)
+	}
+
+	// Build a node per block, numbered in breadth-first order.
+	nodeOf := make(map[int]*Node, blocks)
+	var minimal slab[Node]
+	newNode := func(final bool) *Node {
+		node := minimal.new()
+		node.State, node.Final = len(nodeOf)+1, final
+		return node
+	}
+	root := newNode(n.Final)
+	nodeOf[block[n]] = root
+	queue := []*Node{n}
+	for len(queue) > 0 {
+		rep := queue[0]
+		queue = queue[1:]
+		mn := nodeOf[block[rep]]
+		for _, t := range blockTransitions(rep, block, live) {
+			target, ok := nodeOf[block[t.Node]]
+			if !ok {
+				target = newNode(t.Node.Final)
+				nodeOf[block[t.Node]] = target
+				queue = append(queue, t.Node)
+			}
+			mn.Transitions = append(mn.Transitions, T{t.RuneRanges, target})
+		}
+	}
+
+	return root
+}
+
+// blockTransitions returns the transitions of node to live nodes with the
+// ranges leading to the same block merged, ordered by their first rune. The
+// node of each transition is one of the nodes of the block.
+func blockTransitions(node *Node, block map[*Node]int, live map[*Node]bool) []T {
+	var ts []T
+	index := make(map[int]int)
+	for _, t := range node.Transitions {
+		if !live[t.Node] {
+			continue
+		}
+		b := block[t.Node]
+		if i, ok := index[b]; ok {
+			ts[i].RuneRanges = runerange.Sum(ts[i].RuneRanges, t.RuneRanges)
+			continue
+		}
+		index[b] = len(ts)
+		ts = append(ts, T{append([]rune(nil), t.RuneRanges...), t.Node})
+	}
+	sort.Slice(ts, func(i, j int) bool { return ts[i].RuneRanges[0] < ts[j].RuneRanges[0] })
+	return ts
+}
+
+func signature(node *Node, block map[*Node]int, live map[*Node]bool) string {
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(block[node]))
+	for _, t := range blockTransitions(node, block, live) {
+		for _, r := range t.RuneRanges {
+			b.WriteString(" " + strconv.Itoa(int(r)))
+		}
+		b.WriteString(":" + strconv.Itoa(block[t.Node]))
+	}
+	return b.String()
+}
+
+// Equal reports whether the automata are isomorphic, that is whether they
+// are the same graph up to the numbering of their states. Minimized
+// automata are equal if and only if they accept the same language.
+func Equal(a, b *Node) bool {
+	ab := map[*Node]*Node{a: b}
+	ba := map[*Node]*Node{b: a}
+	queue := []*Node{a}
+	for len(queue) > 0 {
+		na := queue[0]
+		queue = queue[1:]
+		nb := ab[na]
+		if na.Final != nb.Final || len(na.Transitions) != len(nb.Transitions) {
+			return false
+		}
+		for i, ta := range na.Transitions {
+			tb := nb.Transitions[i]
+			if !slices.Equal(ta.RuneRanges, tb.RuneRanges) {
+				return false
+			}
+			ma, okA := ab[ta.Node]
+			mb, okB := ba[tb.Node]
+			switch {
+			case okA != okB:
+				return false
+			case okA && (ma != tb.Node || mb != ta.Node):
+				return false
+			case !okA:
+				ab[ta.Node] = tb.Node
+				ba[tb.Node] = ta.Node
+				queue = append(queue, ta.Node)
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,202 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dfa
+
+import (
+	"github.com/oulinbao/regexinter/nfa"
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// fragments converts DFA graphs back into NFA fragments. Regular operations
+// are expressed by wiring fragments together with epsilon transitions and
+// determinizing the result again.
+type fragments struct {
+	state int
+	nodes slab[nfa.Node]
+}
+
+func (f *fragments) node() *nfa.Node {
+	f.state++
+	n := f.nodes.new()
+	n.S = f.state
+	return n
+}
+
+// fragment returns the NFA equivalent of the automaton rooted at root along
+// with the NFA nodes of its final states. The final nodes are not marked as
+// final; that is up to the caller.
+func (f *fragments) fragment(root *Node) (begin *nfa.Node, finals []*nfa.Node) {
+	nodes := map[*Node]*nfa.Node{root: f.node()}
+	queue := []*Node{root}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+
+		nn := nodes[n]
+		if n.Final {
+			finals = append(finals, nn)
+		}
+		for _, t := range n.Transitions {
+			next, ok := nodes[t.Node]
+			if !ok {
+				next = f.node()
+				nodes[t.Node] = next
+				queue = append(queue, t.Node)
+			}
+			nn.T = append(nn.T, nfa.T{R: t.RuneRanges, N: next})
+		}
+	}
+
+	return nodes[root], finals
+}
+
+// Concat returns an automaton accepting the concatenation of the languages
+// of the given automata. Without arguments it accepts only the empty string.
+func Concat(nodes ...*Node) *Node {
+	f := &fragments{}
+	begin := f.node()
+	ends := []*nfa.Node{begin}
+	for _, n := range nodes {
+		b, finals := f.fragment(n)
+		for _, e := range ends {
+			e.T = append(e.T, nfa.T{N: b})
+		}
+		ends = finals
+	}
+	for _, e := range ends {
+		e.F = true
+	}
+
+	return NewFromNFA(begin)
+}
+
+// Union returns an automaton accepting the union of the languages of the
+// given automata. Without arguments it accepts nothing.
+func Union(nodes ...*Node) *Node {
+	f := &fragments{}
+	begin := f.node()
+	for _, n := range nodes {
+		b, finals := f.fragment(n)
+		begin.T = append(begin.T, nfa.T{N: b})
+		for _, e := range finals {
+			e.F = true
+		}
+	}
+
+	return NewFromNFA(begin)
+}
+
+// Star returns an automaton accepting zero or more repetitions of the
+// language of n (the Kleene star).
+func Star(n *Node) *Node {
+	f := &fragments{}
+	begin := f.node()
+	begin.F = true
+	b, finals := f.fragment(n)
+	begin.T = append(begin.T, nfa.T{N: b})
+	for _, e := range finals {
+		e.T = append(e.T, nfa.T{N: begin})
+	}
+
+	return NewFromNFA(begin)
+}
+
+// Plus returns an automaton accepting one or more repetitions of the
+// language of n.
+func Plus(n *Node) *Node {
+	f := &fragments{}
+	begin, finals := f.fragment(n)
+	for _, e := range finals {
+		e.F = true
+		e.T = append(e.T, nfa.T{N: begin})
+	}
+
+	return NewFromNFA(begin)
+}
+
+// Reverse returns an automaton accepting the reversals of the strings n
+// accepts.
+func Reverse(n *Node) *Node {
+	f := &fragments{}
+	begin := f.node()
+	nodes := make(map[*Node]*nfa.Node)
+	for _, node := range reachable(n) {
+		nodes[node] = f.node()
+	}
+	for node, nn := range nodes {
+		if node.Final {
+			begin.T = append(begin.T, nfa.T{N: nn})
+		}
+		for _, t := range node.Transitions {
+			next := nodes[t.Node]
+			next.T = append(next.T, nfa.T{R: t.RuneRanges, N: nn})
+		}
+	}
+	nodes[n].F = true
+
+	return NewFromNFA(begin)
+}
+
+// Intersect returns an automaton accepting the strings both a and b accept.
+func Intersect(a, b *Node) *Node {
+	return product(a, b, false, func(p pair) bool { return p.final(0) && p.final(1) })
+}
+
+// Subtract returns an automaton accepting the strings a accepts and b does
+// not.
+func Subtract(a, b *Node) *Node {
+	return product(a, b, true, func(p pair) bool { return p.final(0) && !p.final(1) })
+}
+
+// product builds the product of two automata, trimmed, with the pairs accept
+// tells as final states. If partial is set, the second automaton may be in
+// its dead state.
+func product(a, b *Node, partial bool, accept func(pair) bool) *Node {
+	var nodes slab[Node]
+	states := make(map[pair]*Node)
+	var order []pair
+	get := func(p pair) *Node {
+		n, ok := states[p]
+		if !ok {
+			n = nodes.new()
+			*n = Node{State: len(order) + 1, Final: accept(p)}
+			states[p] = n
+			order = append(order, p)
+		}
+		return n
+	}
+	root := get(pair{a, b})
+	for i := 0; i < len(order); i++ {
+		p := order[i]
+		n := states[p]
+		var targets []*Node
+		ranges := make(map[*Node][]rune)
+		for _, r := range p.moves() {
+			q := p.next(r)
+			if q[0] == nil || q[1] == nil && !partial {
+				continue
+			}
+			next := get(q)
+			if _, ok := ranges[next]; !ok {
+				targets = append(targets, next)
+			}
+			ranges[next] = runerange.Sum(ranges[next], r)
+		}
+		for _, t := range targets {
+			n.Transitions = append(n.Transitions, T{ranges[t], t})
+		}
+	}
+	trim(root)
+	return root
+}
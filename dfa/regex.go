@@ -0,0 +1,275 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dfa
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// NoMatch is the regular expression produced for automata accepting nothing.
+const NoMatch = `[^\x00-\x{10FFFF}]`
+
+// Precedence of the outermost operator of an expression.
+const (
+	precAlt = iota
+	precConcat
+	precRepeat
+	precAtom
+)
+
+// expr is a regular expression built up during state elimination.
+type expr struct {
+	s     string
+	prec  int
+	class []rune // set if the expression is a plain character class
+	base  *expr  // repeated expression of x*, x+ and x?
+	parts []*expr
+}
+
+var epsilon = &expr{prec: precAtom}
+
+func (e *expr) group(prec int) string {
+	if e.prec < prec {
+		return "(?:" + e.s + ")"
+	}
+	return e.s
+}
+
+func (e *expr) isStar() bool {
+	return e.prec == precRepeat && strings.HasSuffix(e.s, "*")
+}
+
+func (e *expr) isPlus() bool {
+	return e.prec == precRepeat && strings.HasSuffix(e.s, "+")
+}
+
+func alt(a, b *expr) *expr {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.s == b.s:
+		return a
+	case a.class != nil && b.class != nil:
+		return classExpr(runerange.Sum(a.class, b.class))
+	case a == epsilon:
+		return quest(b)
+	case b == epsilon:
+		return quest(a)
+	}
+	return &expr{s: a.s + "|" + b.s, prec: precAlt}
+}
+
+func concat(es ...*expr) *expr {
+	var parts []*expr
+	for _, e := range es {
+		if e == nil {
+			return nil
+		}
+		if e == epsilon {
+			continue
+		}
+		if e.prec == precConcat {
+			parts = append(parts, e.parts...)
+			continue
+		}
+		if n := len(parts); n > 0 && e.isStar() && parts[n-1].s == e.base.s {
+			parts[n-1] = plus(e.base)
+			continue
+		}
+		parts = append(parts, e)
+	}
+	switch len(parts) {
+	case 0:
+		return epsilon
+	case 1:
+		return parts[0]
+	}
+
+	var b strings.Builder
+	for _, e := range parts {
+		b.WriteString(e.group(precConcat))
+	}
+	return &expr{s: b.String(), prec: precConcat, parts: parts}
+}
+
+func star(e *expr) *expr {
+	if e == nil || e == epsilon {
+		return epsilon
+	}
+	if e.isStar() {
+		return e
+	}
+	if e.isPlus() {
+		e = e.base
+	}
+	return &expr{s: e.group(precAtom) + "*", prec: precRepeat, base: e}
+}
+
+func plus(e *expr) *expr {
+	return &expr{s: e.group(precAtom) + "+", prec: precRepeat, base: e}
+}
+
+func quest(e *expr) *expr {
+	if e == epsilon || e.isStar() {
+		return e
+	}
+	if e.isPlus() {
+		return star(e.base)
+	}
+	return &expr{s: e.group(precAtom) + "?", prec: precRepeat, base: e}
+}
+
+func classExpr(rr []rune) *expr {
+	// Surrogate halves never match, so whether rr has them does not matter.
+	missing := runerange.Valid(runerange.Negate(rr))
+	var s string
+	switch {
+	case len(rr) == 2 && rr[0] == rr[1] && strconv.IsPrint(rr[0]):
+		s = regexp.QuoteMeta(string(rr[0]))
+	case len(rr) == 2 && rr[0] == rr[1]:
+		s = `\x{` + strings.ToUpper(strconv.FormatInt(int64(rr[0]), 16)) + `}`
+	case len(missing) == 0:
+		s = `(?s:.)`
+	case len(missing) == 2 && missing[0] == '\n' && missing[1] == '\n':
+		s = "."
+	default:
+		s = runerange.String(rr)
+	}
+	return &expr{s: s, prec: precAtom, class: rr}
+}
+
+// ToRegex converts the automaton into an equivalent regular expression using
+// the state elimination method. The expression describes the strings the
+// automaton accepts as a whole, so it has to be anchored (as in ^(?:re)$) to
+// get the same behavior from a regexp engine that searches for matches.
+func ToRegex(n *Node) (string, error) {
+	if n == nil {
+		return "", errors.New("dfa: nil automaton")
+	}
+
+	var nodes []*Node
+	live := canReachFinal(reachable(n))
+	if !live[n] {
+		return NoMatch, nil
+	}
+	index := make(map[*Node]int)
+	for _, node := range reachable(n) {
+		if live[node] {
+			index[node] = len(nodes)
+			nodes = append(nodes, node)
+		}
+	}
+
+	begin, end := len(nodes), len(nodes)+1
+	out := make([]map[int]*expr, len(nodes)+2)
+	in := make([]map[int]bool, len(nodes)+2)
+	for i := range out {
+		out[i] = make(map[int]*expr)
+		in[i] = make(map[int]bool)
+	}
+	add := func(i, j int, e *expr) {
+		out[i][j] = alt(out[i][j], e)
+		in[j][i] = true
+	}
+
+	add(begin, 0, epsilon)
+	for i, node := range nodes {
+		if node.Final {
+			add(i, end, epsilon)
+		}
+		for _, t := range node.Transitions {
+			if live[t.Node] {
+				add(i, index[t.Node], classExpr(t.RuneRanges))
+			}
+		}
+	}
+
+	eliminated := make([]bool, len(nodes))
+	for range nodes {
+		// Eliminating the state with the fewest paths through it keeps the
+		// intermediate expressions small.
+		q, cost := -1, 0
+		for i := range nodes {
+			if eliminated[i] {
+				continue
+			}
+			c := len(in[i]) * len(out[i])
+			if q < 0 || c < cost {
+				q, cost = i, c
+			}
+		}
+		eliminated[q] = true
+
+		loop := star(out[q][q])
+		for _, i := range sortedPreds(in[q]) {
+			if i == q {
+				continue
+			}
+			for _, j := range sortedSuccs(out[q]) {
+				if j == q {
+					continue
+				}
+				add(i, j, concat(out[i][q], loop, out[q][j]))
+			}
+		}
+		for i := range in[q] {
+			delete(out[i], q)
+		}
+		for j := range out[q] {
+			delete(in[j], q)
+		}
+	}
+
+	return out[begin][end].s, nil
+}
+
+// ToGoRegexp returns a regexp matching the strings the automaton accepts as
+// a whole. The automaton is minimized first, which keeps the expression
+// short.
+func ToGoRegexp(n *Node) (*regexp.Regexp, error) {
+	if n == nil {
+		return nil, errors.New("dfa: nil automaton")
+	}
+	re, err := ToRegex(Minimize(n))
+	if err != nil {
+		return nil, err
+	}
+	return regexp.Compile("^(?:" + re + ")$")
+}
+
+func sortedPreds(m map[int]bool) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func sortedSuccs(m map[int]*expr) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
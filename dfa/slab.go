@@ -0,0 +1,32 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dfa
+
+// maxChunk is the number of values of the largest chunks of slabs.
+const maxChunk = 1024
+
+// slab allocates values in chunks of growing size. An automaton of
+// thousands of nodes is then a few objects for the garbage collector, which
+// frees them together once none of the nodes is referenced anymore.
+type slab[V any] struct {
+	chunk []V
+}
+
+func (s *slab[V]) new() *V {
+	if len(s.chunk) == cap(s.chunk) {
+		s.chunk = make([]V, 0, min(max(2*cap(s.chunk), 16), maxChunk))
+	}
+	s.chunk = s.chunk[:len(s.chunk)+1]
+	return &s.chunk[len(s.chunk)-1]
+}
@@ -0,0 +1,44 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dfa
+
+// sparseSet is a set of numbers below its size that is cleared in constant
+// time, as described by Briggs and Torczon.
+type sparseSet struct {
+	dense  []int32
+	sparse []int32
+}
+
+// resize makes the set empty and able to hold numbers below size.
+func (s *sparseSet) resize(size int) {
+	if cap(s.sparse) < size {
+		s.sparse = make([]int32, size)
+	}
+	s.sparse = s.sparse[:size]
+	s.dense = s.dense[:0]
+}
+
+func (s *sparseSet) contains(i int32) bool {
+	j := s.sparse[i]
+	return int(j) < len(s.dense) && s.dense[j] == i
+}
+
+func (s *sparseSet) add(i int32) {
+	s.sparse[i] = int32(len(s.dense))
+	s.dense = append(s.dense, i)
+}
+
+func (s *sparseSet) clear() {
+	s.dense = s.dense[:0]
+}
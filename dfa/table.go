@@ -0,0 +1,260 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dfa
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// Table is an automaton stored in flat slices instead of a graph of nodes,
+// which is compact, cache friendly and serializable as is, with encoding/gob
+// for instance. States are numbered from 0, the start state. The transitions
+// of state i are the entries Offsets[i] to Offsets[i+1]-1 of Lo, Hi and To,
+// sorted by Lo: the runes in [Lo[j], Hi[j]] lead to state To[j].
+//
+// Tables are read-only; Freeze returns a new one for every automaton.
+type Table struct {
+	Final   []bool
+	Offsets []int32
+	Lo, Hi  []rune
+	To      []int32
+}
+
+// Freeze returns the table form of the automaton. Its states are numbered
+// in breadth-first order.
+func Freeze(n *Node) *Table {
+	nodes := reachable(n)
+	index := make(map[*Node]int32, len(nodes))
+	for i, node := range nodes {
+		index[node] = int32(i)
+	}
+	t := &Table{Final: make([]bool, len(nodes)), Offsets: make([]int32, 0, len(nodes)+1)}
+	for i, node := range nodes {
+		t.Final[i] = node.Final
+		t.Offsets = append(t.Offsets, int32(len(t.Lo)))
+		first := len(t.Lo)
+		for _, tr := range node.Transitions {
+			for j := 0; j < len(tr.RuneRanges); j += 2 {
+				t.Lo = append(t.Lo, tr.RuneRanges[j])
+				t.Hi = append(t.Hi, tr.RuneRanges[j+1])
+				t.To = append(t.To, index[tr.Node])
+			}
+		}
+		sort.Sort(entries{t, first, len(t.Lo)})
+	}
+	t.Offsets = append(t.Offsets, int32(len(t.Lo)))
+	return t
+}
+
+// entries sorts the transitions of a state by their first rune.
+type entries struct {
+	t          *Table
+	begin, end int
+}
+
+func (e entries) Len() int           { return e.end - e.begin }
+func (e entries) Less(i, j int) bool { return e.t.Lo[e.begin+i] < e.t.Lo[e.begin+j] }
+func (e entries) Swap(i, j int) {
+	i, j = e.begin+i, e.begin+j
+	e.t.Lo[i], e.t.Lo[j] = e.t.Lo[j], e.t.Lo[i]
+	e.t.Hi[i], e.t.Hi[j] = e.t.Hi[j], e.t.Hi[i]
+	e.t.To[i], e.t.To[j] = e.t.To[j], e.t.To[i]
+}
+
+// States returns the number of states.
+func (t *Table) States() int {
+	return len(t.Final)
+}
+
+// Next returns the state reached from state on r, or -1 if there is none.
+func (t *Table) Next(state int, r rune) int {
+	begin, end := int(t.Offsets[state]), int(t.Offsets[state+1])
+	j := begin + sort.Search(end-begin, func(j int) bool { return t.Hi[begin+j] >= r })
+	if j < end && t.Lo[j] <= r {
+		return int(t.To[j])
+	}
+	return -1
+}
+
+// Match reports whether the automaton accepts s.
+func (t *Table) Match(s string) bool {
+	state := 0
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if state = t.Next(state, r); state < 0 {
+			return false
+		}
+		i += size
+	}
+	return t.Final[state]
+}
+
+// FindAllIndex returns the byte offsets of the successive leftmost-longest
+// substrings of b the automaton accepts, at most n of them if n >= 0. As in
+// package regexp, empty matches right after a match are skipped, and invalid
+// UTF-8 is read as U+FFFD a byte at a time.
+func (t *Table) FindAllIndex(b []byte, n int) [][]int {
+	var result [][]int
+	for pos, prevEnd := 0, -1; (n < 0 || len(result) < n) && pos <= len(b); {
+		start, end := t.find(b, pos)
+		if start < 0 {
+			break
+		}
+		accept := true
+		if end == pos {
+			accept = start != prevEnd
+			if pos == len(b) {
+				pos++
+			} else {
+				_, size := utf8.DecodeRune(b[pos:])
+				pos += size
+			}
+		} else {
+			pos = end
+		}
+		prevEnd = end
+		if accept {
+			result = append(result, []int{start, end})
+		}
+	}
+	return result
+}
+
+// CountMatches returns the number of substrings FindAllIndex would return
+// for b with no limit, without allocating them.
+func (t *Table) CountMatches(b []byte) int {
+	count := 0
+	for pos, prevEnd := 0, -1; pos <= len(b); {
+		start, end := t.find(b, pos)
+		if start < 0 {
+			break
+		}
+		if end == pos {
+			if start != prevEnd {
+				count++
+			}
+			if pos == len(b) {
+				break
+			}
+			_, size := utf8.DecodeRune(b[pos:])
+			pos += size
+		} else {
+			count++
+			pos = end
+		}
+		prevEnd = end
+	}
+	return count
+}
+
+// find returns the leftmost-longest match in b starting at from or later, or
+// -1, -1 if there is none.
+func (t *Table) find(b []byte, from int) (start, end int) {
+	for start = from; start <= len(b); {
+		if end = t.longestPrefix(b[start:]); end >= 0 {
+			return start, start + end
+		}
+		if start == len(b) {
+			break
+		}
+		_, size := utf8.DecodeRune(b[start:])
+		start += size
+	}
+	return -1, -1
+}
+
+// longestPrefix returns the length of the longest prefix of b the automaton
+// accepts, or -1 if there is none.
+func (t *Table) longestPrefix(b []byte) int {
+	longest, state := -1, 0
+	for i := 0; ; {
+		if t.Final[state] {
+			longest = i
+		}
+		if i == len(b) {
+			return longest
+		}
+		r, size := utf8.DecodeRune(b[i:])
+		if state = t.Next(state, r); state < 0 {
+			return longest
+		}
+		i += size
+	}
+}
+
+// ExportTable returns the automaton as flat arrays for embedding, with
+// go:embed for instance, or for loading into the runtimes of other
+// languages. The alphabet holds the classes of runes the automaton tells
+// apart as pairs of runes like rune ranges, sorted: class c is the runes
+// from alphabet[2c] to alphabet[2c+1]; runes of no class lead nowhere.
+//
+// The table has a row per state in breadth-first order, the start state
+// first, of 1+C entries for C classes: 1 if the state is final and 0 if not,
+// then the next state on each class, -1 for none.
+func ExportTable(n *Node) (table []int32, alphabet []rune) {
+	nodes := reachable(n)
+	index := make(map[*Node]int32, len(nodes))
+	var ranges [][]rune
+	for i, node := range nodes {
+		index[node] = int32(i)
+		for _, t := range node.Transitions {
+			ranges = append(ranges, t.RuneRanges)
+		}
+	}
+	alphabet = runerange.Split(ranges)
+
+	classes := len(alphabet) / 2
+	table = make([]int32, 0, len(nodes)*(1+classes))
+	for _, node := range nodes {
+		final := int32(0)
+		if node.Final {
+			final = 1
+		}
+		table = append(table, final)
+		for c := 0; c < classes; c++ {
+			next := int32(-1)
+			if to := node.NextState(alphabet[2*c : 2*c+2]); to != nil {
+				next = index[to]
+			}
+			table = append(table, next)
+		}
+	}
+	return table, alphabet
+}
+
+// Thaw returns the graph form of the automaton, with states numbered from 1.
+func (t *Table) Thaw() *Node {
+	slab := make([]Node, t.States())
+	nodes := make([]*Node, t.States())
+	for i := range nodes {
+		slab[i] = Node{State: i + 1, Final: t.Final[i]}
+		nodes[i] = &slab[i]
+	}
+	for i, node := range nodes {
+		index := make(map[int32]int)
+		for j := t.Offsets[i]; j < t.Offsets[i+1]; j++ {
+			k, ok := index[t.To[j]]
+			if !ok {
+				k = len(node.Transitions)
+				index[t.To[j]] = k
+				node.Transitions = append(node.Transitions, T{Node: nodes[t.To[j]]})
+			}
+			node.Transitions[k].RuneRanges = runerange.Sum(node.Transitions[k].RuneRanges, []rune{t.Lo[j], t.Hi[j]})
+		}
+	}
+	return nodes[0]
+}
@@ -0,0 +1,140 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dfa
+
+import (
+	"errors"
+	"unicode/utf8"
+
+	"github.com/oulinbao/regexinter/nfa"
+)
+
+// UTF8 converts an automaton over runes into an equivalent automaton over
+// the bytes of their UTF-8 encoding, similar to the byte programs of
+// regexp/syntax. The transitions of the result are labeled with byte values
+// in [0, 0xff] and MatchBytes runs it on []byte input without decoding
+// runes. Surrogate halves have no UTF-8 encoding and are left out; runes
+// outside of Unicode have none either and make the conversion fail.
+func UTF8(n *Node) (*Node, error) {
+	f := &fragments{}
+	nodes := map[*Node]*nfa.Node{n: f.node()}
+	queue := []*Node{n}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		nn := nodes[node]
+		nn.F = node.Final
+		for _, t := range node.Transitions {
+			next, ok := nodes[t.Node]
+			if !ok {
+				next = f.node()
+				nodes[t.Node] = next
+				queue = append(queue, t.Node)
+			}
+			for i := 0; i < len(t.RuneRanges); i += 2 {
+				if t.RuneRanges[i] < 0 || t.RuneRanges[i+1] > nfa.RuneLast {
+					return nil, errors.New("dfa: runes outside of Unicode have no UTF-8 encoding")
+				}
+				utf8Sequences(t.RuneRanges[i], t.RuneRanges[i+1], func(seq [][2]byte) {
+					cur := nn
+					for j, br := range seq {
+						to := next
+						if j < len(seq)-1 {
+							to = f.node()
+						}
+						cur.T = append(cur.T, nfa.T{R: []rune{rune(br[0]), rune(br[1])}, N: to})
+						cur = to
+					}
+				})
+			}
+		}
+	}
+
+	return NewFromNFA(nodes[n]), nil
+}
+
+// MatchBytes reports whether the automaton returned by UTF8 accepts b.
+func MatchBytes(n *Node, b []byte) bool {
+	for _, c := range b {
+		if n = n.NextState([]rune{rune(c), rune(c)}); n == nil {
+			return false
+		}
+	}
+	return n.Final
+}
+
+// utf8Sequences calls emit with sequences of byte ranges such that the UTF-8
+// encodings of the runes in [lo, hi] are exactly the byte strings matched by
+// one of the sequences.
+func utf8Sequences(lo, hi rune, emit func([][2]byte)) {
+	const surrogateMin, surrogateMax = 0xd800, 0xdfff
+
+	if lo < surrogateMin && hi > surrogateMax {
+		utf8Sequences(lo, surrogateMin-1, emit)
+		utf8Sequences(surrogateMax+1, hi, emit)
+		return
+	}
+	if lo >= surrogateMin && lo <= surrogateMax {
+		lo = surrogateMax + 1
+	}
+	if hi >= surrogateMin && hi <= surrogateMax {
+		hi = surrogateMin - 1
+	}
+	if hi > utf8.MaxRune {
+		hi = utf8.MaxRune
+	}
+	if lo > hi {
+		return
+	}
+
+	// Split the range so that all its runes have encodings of the same length.
+	for _, max := range []rune{0x7f, 0x7ff, 0xffff} {
+		if lo <= max && hi > max {
+			utf8Sequences(lo, max, emit)
+			utf8Sequences(max+1, hi, emit)
+			return
+		}
+	}
+	if hi <= 0x7f {
+		emit([][2]byte{{byte(lo), byte(hi)}})
+		return
+	}
+
+	// Split the range so that the encodings of lo and hi only differ in
+	// bytes where every value between them is allowed.
+	for i := uint(1); i < utf8.UTFMax; i++ {
+		m := rune(1)<<(6*i) - 1
+		if lo&^m != hi&^m {
+			if lo&m != 0 {
+				utf8Sequences(lo, lo|m, emit)
+				utf8Sequences((lo|m)+1, hi, emit)
+				return
+			}
+			if hi&m != m {
+				utf8Sequences(lo, (hi&^m)-1, emit)
+				utf8Sequences(hi&^m, hi, emit)
+				return
+			}
+		}
+	}
+
+	a, b := make([]byte, utf8.UTFMax), make([]byte, utf8.UTFMax)
+	n := utf8.EncodeRune(a, lo)
+	utf8.EncodeRune(b, hi)
+	seq := make([][2]byte, n)
+	for i := range seq {
+		seq[i] = [2]byte{a[i], b[i]}
+	}
+	emit(seq)
+}
@@ -0,0 +1,100 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dfa
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// trieNode is a node of the trie FromStrings starts from.
+type trieNode struct {
+	final    bool
+	children map[rune]*trieNode
+}
+
+// FromStrings returns the minimal automaton accepting exactly the given
+// words, a directed acyclic word graph. It is built from the trie of the
+// words by merging, from the leaves up, the nodes with the same suffixes,
+// without going through an NFA. Without words it accepts nothing.
+func FromStrings(words []string) *Node {
+	root := &trieNode{}
+	for _, w := range words {
+		t := root
+		for _, r := range w {
+			c := t.children[r]
+			if c == nil {
+				if t.children == nil {
+					t.children = make(map[rune]*trieNode)
+				}
+				c = &trieNode{}
+				t.children[r] = c
+			}
+			t = c
+		}
+		t.final = true
+	}
+
+	// Nodes are registered by their finality and transitions, of which
+	// there is a single node once the children are merged already.
+	register := make(map[string]*Node)
+	ids := make(map[*Node]int)
+	var build func(t *trieNode) *Node
+	build = func(t *trieNode) *Node {
+		runes := make([]rune, 0, len(t.children))
+		for r := range t.children {
+			runes = append(runes, r)
+		}
+		sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+		targets := make([]*Node, len(runes))
+		var key strings.Builder
+		key.WriteString(strconv.FormatBool(t.final))
+		for i, r := range runes {
+			targets[i] = build(t.children[r])
+			key.WriteString(" " + strconv.Itoa(int(r)) + ":" + strconv.Itoa(ids[targets[i]]))
+		}
+		if n, ok := register[key.String()]; ok {
+			return n
+		}
+
+		n := &Node{Final: t.final}
+		index := make(map[*Node]int)
+		for i, r := range runes {
+			k, ok := index[targets[i]]
+			if !ok {
+				k = len(n.Transitions)
+				index[targets[i]] = k
+				n.Transitions = append(n.Transitions, T{Node: targets[i]})
+			}
+			// The runes come in increasing order, so they extend the
+			// last range or start a new one.
+			rr := n.Transitions[k].RuneRanges
+			if len(rr) > 0 && rr[len(rr)-1] == r-1 {
+				rr[len(rr)-1] = r
+			} else {
+				rr = append(rr, r, r)
+			}
+			n.Transitions[k].RuneRanges = rr
+		}
+		ids[n] = len(ids) + 1
+		register[key.String()] = n
+		return n
+	}
+	n := build(root)
+	for i, node := range reachable(n) {
+		node.State = i + 1
+	}
+	return n
+}
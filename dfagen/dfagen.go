@@ -0,0 +1,180 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package dfagen generates random strings matching, or not matching, a DFA
+// for property based tests.
+package dfagen
+
+import (
+	"math/rand"
+	"reflect"
+	"unicode"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// DefaultSize is the length of the strings Values aims for.
+const DefaultSize = 20
+
+// Generator generates random strings from the language of a DFA or from its
+// complement.
+type Generator struct {
+	root  *dfa.Node
+	moves map[*dfa.Node][]move
+	dist  map[*dfa.Node]int // length of the shortest accepted suffix
+}
+
+type move struct {
+	ranges []rune
+	node   *dfa.Node // nil for the dead state
+}
+
+// Matching returns a generator of strings the DFA accepts.
+func Matching(n *dfa.Node) *Generator {
+	return newGenerator(n, false)
+}
+
+// NotMatching returns a generator of strings the DFA rejects.
+func NotMatching(n *dfa.Node) *Generator {
+	return newGenerator(n, true)
+}
+
+func newGenerator(root *dfa.Node, complement bool) *Generator {
+	g := &Generator{root: root, moves: make(map[*dfa.Node][]move)}
+
+	var nodes []*dfa.Node
+	queue := []*dfa.Node{root}
+	seen := map[*dfa.Node]bool{root: true}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		nodes = append(nodes, n)
+
+		var covered []rune
+		for _, t := range n.Transitions {
+			covered = runerange.Sum(covered, t.RuneRanges)
+			rr := runerange.Valid(t.RuneRanges)
+			if len(rr) == 0 {
+				continue
+			}
+			g.moves[n] = append(g.moves[n], move{rr, t.Node})
+			if !seen[t.Node] {
+				seen[t.Node] = true
+				queue = append(queue, t.Node)
+			}
+		}
+		if complement {
+			if rr := runerange.Valid(runerange.Negate(covered)); len(rr) > 0 {
+				g.moves[n] = append(g.moves[n], move{rr, nil})
+			}
+		}
+	}
+
+	accepting := func(n *dfa.Node) bool {
+		if n == nil {
+			return complement
+		}
+		return n.Final != complement
+	}
+	if complement {
+		nodes = append(nodes, nil)
+		g.moves[nil] = []move{{runerange.Valid([]rune{0, unicode.MaxRune}), nil}}
+	}
+
+	// Compute the distances by relaxing the moves until nothing changes.
+	g.dist = make(map[*dfa.Node]int)
+	for _, n := range nodes {
+		if accepting(n) {
+			g.dist[n] = 0
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, n := range nodes {
+			for _, m := range g.moves[n] {
+				d, ok := g.dist[m.node]
+				if cur, has := g.dist[n]; ok && (!has || d+1 < cur) {
+					g.dist[n] = d + 1
+					changed = true
+				}
+			}
+		}
+	}
+
+	return g
+}
+
+// Empty reports whether the generator has no string to generate.
+func (g *Generator) Empty() bool {
+	_, ok := g.dist[g.root]
+	return !ok
+}
+
+// Generate returns a random string of about size runes at most. It returns
+// false if there is no string to generate. Strings longer than size are only
+// returned if there is no shorter one.
+func (g *Generator) Generate(r *rand.Rand, size int) (string, bool) {
+	if g.Empty() {
+		return "", false
+	}
+
+	target := 0
+	if size > 0 {
+		target = r.Intn(size + 1)
+	}
+	var s []rune
+	n := g.root
+	for {
+		d := g.dist[n]
+		if d == 0 && len(s) >= target {
+			break
+		}
+
+		// Wander while below the target length, then head for the
+		// closest accepting state.
+		var choices []move
+		for _, m := range g.moves[n] {
+			if md, ok := g.dist[m.node]; ok && (len(s) < target || md < d) {
+				choices = append(choices, m)
+			}
+		}
+		if len(choices) == 0 {
+			break
+		}
+		m := choices[r.Intn(len(choices))]
+		s = append(s, randomRune(r, m.ranges))
+		n = m.node
+	}
+
+	return string(s), true
+}
+
+// Values fills args with generated strings of up to DefaultSize runes. It
+// can serve as the Values function of a testing/quick.Config for properties
+// taking string arguments. It panics if there is no string to generate.
+func (g *Generator) Values(args []reflect.Value, r *rand.Rand) {
+	for i := range args {
+		s, ok := g.Generate(r, DefaultSize)
+		if !ok {
+			panic("dfagen: no string to generate")
+		}
+		args[i] = reflect.ValueOf(s)
+	}
+}
+
+// randomRune returns a rune from a random pair of the ranges.
+func randomRune(r *rand.Rand, ranges []rune) rune {
+	i := r.Intn(len(ranges)/2) * 2
+	return ranges[i] + r.Int31n(ranges[i+1]-ranges[i]+1)
+}
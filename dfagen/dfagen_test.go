@@ -0,0 +1,87 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dfagen
+
+import (
+	"math/rand"
+	"regexp"
+	"testing"
+	"testing/quick"
+	"unicode/utf8"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/nfa"
+)
+
+func compile(t *testing.T, expr string) *dfa.Node {
+	t.Helper()
+	n, err := nfa.New(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dfa.NewFromNFA(n)
+}
+
+func TestGenerate(t *testing.T) {
+	exprs := []string{
+		"a", "(a|b)*abb", "[a-z]+@[a-z]+\\.com", "/api/v1/[0-9]+/get", ".*", "x[^y]z", "\\p{Greek}{3,5}", "a{30}",
+	}
+	r := rand.New(rand.NewSource(1))
+	for _, e := range exprs {
+		re := regexp.MustCompile("^(?:" + e + ")$")
+		n := compile(t, e)
+		for _, tc := range []struct {
+			g    *Generator
+			want bool
+		}{{Matching(n), true}, {NotMatching(n), false}} {
+			if tc.g.Empty() {
+				t.Errorf("generator for %q matching %v is empty", e, tc.want)
+				continue
+			}
+			for i := 0; i < 100; i++ {
+				s, ok := tc.g.Generate(r, 10)
+				if !ok || !utf8.ValidString(s) || re.MatchString(s) != tc.want {
+					t.Errorf("generated %q for %q matching %v", s, e, tc.want)
+				}
+			}
+		}
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	if !Matching(compile(t, dfa.NoMatch)).Empty() {
+		t.Errorf("Matching(NoMatch) is not empty")
+	}
+	if !NotMatching(compile(t, "(?s:.*)")).Empty() {
+		t.Errorf("NotMatching((?s:.*)) is not empty")
+	}
+	if !Matching(compile(t, "a^b")).Empty() {
+		t.Errorf("Matching(a^b) is not empty")
+	}
+	if s, ok := Matching(compile(t, "")).Generate(rand.New(rand.NewSource(1)), 10); !ok || s != "" {
+		t.Errorf("Matching(\"\") generated %q, %v", s, ok)
+	}
+}
+
+func TestValues(t *testing.T) {
+	expr := "[0-9]+-[a-z]+"
+	re := regexp.MustCompile("^(?:" + expr + ")$")
+	g := Matching(compile(t, expr))
+	err := quick.Check(func(a, b string) bool {
+		return re.MatchString(a) && re.MatchString(b)
+	}, &quick.Config{Values: g.Values})
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,120 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package iam tells whether the wildcard resource patterns of access
+// policies, such as arn:aws:s3:::bucket/*, can match the same resource.
+package iam
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/intersection"
+)
+
+// GlobToRegexp returns the regular expression matching the resource names
+// the pattern matches. In patterns * matches any run of characters, slashes
+// and colons included, ? matches a single character and ${*}, ${?} and ${$}
+// stand for the characters themselves. Other policy variables such as
+// ${aws:username} are replaced at request time, so they may match anything
+// and are treated as *.
+func GlobToRegexp(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			b.WriteString("(?s:.*)")
+		case '?':
+			b.WriteString("(?s:.)")
+		case '$':
+			end := strings.IndexByte(pattern[i:], '}')
+			if !strings.HasPrefix(pattern[i:], "${") || end < 0 {
+				b.WriteString(`\$`)
+				break
+			}
+			switch v := pattern[i+2 : i+end]; v {
+			case "*", "?", "$":
+				b.WriteString(regexp.QuoteMeta(v))
+			default:
+				b.WriteString("(?s:.*)")
+			}
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// Compile returns the automaton of the resource names the pattern matches,
+// see GlobToRegexp.
+func Compile(pattern string) (*dfa.Node, error) {
+	return intersection.Compile(GlobToRegexp(pattern), intersection.Options{})
+}
+
+// Overlap reports whether some resource name matches both patterns, and the
+// shortest such name.
+func Overlap(a, b string) (bool, string, error) {
+	nodeA, err := Compile(a)
+	if err != nil {
+		return false, "", err
+	}
+	nodeB, err := Compile(b)
+	if err != nil {
+		return false, "", err
+	}
+	r := intersection.CheckDFA(nodeA, nodeB)
+	return r.Intersects, r.Witness, nil
+}
+
+// Match is a pair of resource patterns of two policies matching a common
+// resource.
+type Match struct {
+	A, B     string // the patterns
+	Resource string // the shortest resource name both match
+}
+
+// PoliciesOverlap reports whether two policies, given by the resource
+// patterns of their statements, can apply to the same resource. It returns
+// every pair of patterns matching a common resource.
+func PoliciesOverlap(a, b []string) ([]Match, error) {
+	nodesA, err := compileAll(a)
+	if err != nil {
+		return nil, err
+	}
+	nodesB, err := compileAll(b)
+	if err != nil {
+		return nil, err
+	}
+	var matches []Match
+	for i, nodeA := range nodesA {
+		for j, nodeB := range nodesB {
+			if r := intersection.CheckDFA(nodeA, nodeB); r.Intersects {
+				matches = append(matches, Match{A: a[i], B: b[j], Resource: r.Witness})
+			}
+		}
+	}
+	return matches, nil
+}
+
+func compileAll(patterns []string) ([]*dfa.Node, error) {
+	nodes := make([]*dfa.Node, len(patterns))
+	for i, p := range patterns {
+		var err error
+		if nodes[i], err = Compile(p); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
@@ -0,0 +1,72 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package iam
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	type testCase struct {
+		pattern string
+		want    string
+	}
+	testCases := []testCase{
+		{"arn:aws:s3:::bucket/*", `arn:aws:s3:::bucket/(?s:.*)`},
+		{"file?.txt", `file(?s:.)\.txt`},
+		{"a${*}b${?}${$}", `a\*b\?\$`},
+		{"home/${aws:username}/*", `home/(?s:.*)/(?s:.*)`},
+		{"cost$", `cost\$`},
+	}
+	for _, tc := range testCases {
+		if got := GlobToRegexp(tc.pattern); got != tc.want {
+			t.Errorf("GlobToRegexp(%q) = %q, want %q", tc.pattern, got, tc.want)
+		}
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	type testCase struct {
+		a, b     string
+		overlap  bool
+		resource string
+	}
+	testCases := []testCase{
+		{"arn:aws:s3:::bucket/*", "arn:aws:s3:::bucket/logs/*", true, "arn:aws:s3:::bucket/logs/"},
+		{"arn:aws:s3:::bucket/*", "arn:aws:s3:::other/*", false, ""},
+		{"arn:aws:s3:::bucket-?", "arn:aws:s3:::bucket-prod", false, ""},
+		{"arn:aws:s3:::*-prod/*", "arn:aws:s3:::team-*/x", true, "arn:aws:s3:::team-prod/x"},
+		{"arn:aws:s3:::a${*}", "arn:aws:s3:::ab", false, ""},
+	}
+	for _, tc := range testCases {
+		overlap, resource, err := Overlap(tc.a, tc.b)
+		if err != nil || overlap != tc.overlap || resource != tc.resource {
+			t.Errorf("Overlap(%q, %q) = %v, %q, %v, want %v, %q", tc.a, tc.b, overlap, resource, err, tc.overlap, tc.resource)
+		}
+	}
+}
+
+func TestPoliciesOverlap(t *testing.T) {
+	a := []string{"arn:aws:s3:::public/*", "arn:aws:s3:::logs/2024/*"}
+	b := []string{"arn:aws:s3:::logs/*/secret", "arn:aws:s3:::private/*"}
+	got, err := PoliciesOverlap(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Match{{"arn:aws:s3:::logs/2024/*", "arn:aws:s3:::logs/*/secret", "arn:aws:s3:::logs/2024/secret"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PoliciesOverlap = %v, want %v", got, want)
+	}
+}
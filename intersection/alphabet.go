@@ -0,0 +1,42 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package intersection
+
+import (
+	"errors"
+
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// ErrNoWitnessInAlphabet is returned by checks of patterns that intersect,
+// but only on strings with runes outside of Options.Alphabet.
+var ErrNoWitnessInAlphabet = errors.New("patterns only intersect outside of the alphabet")
+
+// restrict returns the runes of the range r within the alphabet, all of them
+// without an alphabet.
+func restrict(r, alphabet []rune) []rune {
+	if alphabet == nil {
+		return r
+	}
+	return runerange.Intersect(r, alphabet)
+}
+
+// noWitnessInAlphabet turns the result of a check repeated without the
+// alphabet into ErrNoWitnessInAlphabet if the patterns intersect after all.
+func noWitnessInAlphabet(result Result, err error) (Result, error) {
+	if err == nil && result.Intersects {
+		return Result{Intersects: true, StatesExplored: result.StatesExplored}, ErrNoWitnessInAlphabet
+	}
+	return result, err
+}
@@ -0,0 +1,168 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !tinygo
+
+package intersection
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// Certificate is evidence that two patterns have no intersection. It lists
+// the product states reachable from the initial one, the initial state
+// first. For each state, the moves partition the runes both DFAs can read in
+// that state by the product state they lead to. No listed state is final in
+// both DFAs and every move leads to a listed state, so no common string
+// exists.
+//
+// Verify checks a certificate without relying on the search that built it.
+type Certificate struct {
+	States []CertifiedState
+}
+
+// CertifiedState is a product state of a Certificate.
+type CertifiedState struct {
+	State1 int // state of the first DFA
+	State2 int // state of the second DFA
+	Moves  []Move
+}
+
+// Move is a transition of a CertifiedState.
+type Move struct {
+	RuneRanges []rune // rune ranges
+	State1     int    // next state of the first DFA
+	State2     int    // next state of the second DFA
+}
+
+// Certify returns a certificate proving that the patterns have no
+// intersection, or nil if they do intersect.
+func Certify(expr1, expr2 string) (*Certificate, error) {
+	node1, node2, err := compilePair(expr1, expr2, Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	return CertifyDFA(node1, node2), nil
+}
+
+// CertifyDFA is like Certify but works on compiled automata.
+func CertifyDFA(node1, node2 *dfa.Node) *Certificate {
+	s := newSearch(func(final1, final2 bool) bool { return final1 && final2 })
+	if node, _ := s.run(node1, node2); node != nil {
+		return nil
+	}
+
+	c := &Certificate{}
+	for _, node := range s.order {
+		state := CertifiedState{State1: node.Node1.State, State2: node.Node2.State}
+		index := make(map[*CombineNode]int)
+		for _, t := range node.Transitions {
+			i, ok := index[t.Node]
+			if !ok {
+				i = len(state.Moves)
+				index[t.Node] = i
+				state.Moves = append(state.Moves, Move{State1: t.Node.Node1.State, State2: t.Node.Node2.State})
+			}
+			state.Moves[i].RuneRanges = runerange.Sum(state.Moves[i].RuneRanges, t.RuneRanges)
+		}
+		c.States = append(c.States, state)
+	}
+	return c
+}
+
+// Verify checks that the certificate proves the patterns have no
+// intersection. It returns nil if the certificate is valid.
+func Verify(expr1, expr2 string, c *Certificate) error {
+	node1, node2, err := compilePair(expr1, expr2, Options{})
+	if err != nil {
+		return err
+	}
+
+	return VerifyDFA(node1, node2, c)
+}
+
+// VerifyDFA is like Verify but works on compiled automata.
+func VerifyDFA(node1, node2 *dfa.Node, c *Certificate) error {
+	if c == nil || len(c.States) == 0 {
+		return fmt.Errorf("empty certificate")
+	}
+	if c.States[0].State1 != node1.State || c.States[0].State2 != node2.State {
+		return fmt.Errorf("certificate does not start at the initial state %d_%d", node1.State, node2.State)
+	}
+
+	states1, states2 := statesByNumber(node1), statesByNumber(node2)
+	listed := make(map[[2]int]bool, len(c.States))
+	for _, cs := range c.States {
+		key := [2]int{cs.State1, cs.State2}
+		if listed[key] {
+			return fmt.Errorf("state %d_%d is listed twice", cs.State1, cs.State2)
+		}
+		listed[key] = true
+	}
+
+	for _, cs := range c.States {
+		n1, n2 := states1[cs.State1], states2[cs.State2]
+		if n1 == nil || n2 == nil {
+			return fmt.Errorf("state %d_%d does not exist", cs.State1, cs.State2)
+		}
+		if n1.Final && n2.Final {
+			return fmt.Errorf("state %d_%d is final", cs.State1, cs.State2)
+		}
+
+		want := make(map[[2]int][]rune)
+		for _, t1 := range n1.Transitions {
+			for _, t2 := range n2.Transitions {
+				if rr := runerange.Intersect(t1.RuneRanges, t2.RuneRanges); len(rr) > 0 {
+					key := [2]int{t1.Node.State, t2.Node.State}
+					want[key] = runerange.Sum(want[key], rr)
+				}
+			}
+		}
+
+		got := make(map[[2]int][]rune)
+		for _, m := range cs.Moves {
+			key := [2]int{m.State1, m.State2}
+			if !listed[key] {
+				return fmt.Errorf("state %d_%d moves to unlisted state %d_%d", cs.State1, cs.State2, m.State1, m.State2)
+			}
+			got[key] = runerange.Sum(got[key], m.RuneRanges)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			return fmt.Errorf("moves of state %d_%d do not match the automata", cs.State1, cs.State2)
+		}
+	}
+
+	return nil
+}
+
+func statesByNumber(root *dfa.Node) map[int]*dfa.Node {
+	states := map[int]*dfa.Node{root.State: root}
+	queue := []*dfa.Node{root}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, t := range n.Transitions {
+			if _, ok := states[t.Node.State]; !ok {
+				states[t.Node.State] = t.Node
+				queue = append(queue, t.Node)
+			}
+		}
+	}
+	return states
+}
@@ -0,0 +1,57 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !tinygo
+
+package intersection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCertificate(t *testing.T) {
+	type Case struct {
+		Expr1 string
+		Expr2 string
+	}
+	cases := []Case{
+		{"[A-Z]+", "[a-z]+"},
+		{"a*bba+", "b*aaabbb+a"},
+		{"/api/v1/.*/", "/api/v2/.*/"},
+		{"/api/v1/[0-9]+/get", "/api/v1/[a-z]+/get"},
+		{"[a-m]+x", "[h-z]+y"},
+	}
+
+	for _, c := range cases {
+		cert, err := Certify(c.Expr1, c.Expr2)
+		assert.NoError(t, err)
+		if assert.NotNil(t, cert, "%q vs %q", c.Expr1, c.Expr2) {
+			assert.NoError(t, Verify(c.Expr1, c.Expr2, cert), "%q vs %q", c.Expr1, c.Expr2)
+		}
+	}
+
+	cert, err := Certify("a+", "a*")
+	assert.NoError(t, err)
+	assert.Nil(t, cert)
+
+	// A certificate for other patterns does not verify.
+	cert, _ = Certify("a", "b")
+	assert.Error(t, Verify("a", "a", cert))
+
+	// Nor does a certificate with a move removed.
+	cert, _ = Certify("[a-m]+x", "[h-z]+y")
+	cert.States[0].Moves = cert.States[0].Moves[1:]
+	assert.Error(t, Verify("[a-m]+x", "[h-z]+y", cert))
+}
@@ -0,0 +1,85 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package intersection
+
+import "regexp"
+
+// Report is the outcome of VerifyAgainstCorpus.
+type Report struct {
+	Result                // decision of the automata
+	Common     []string   // samples package regexp matches against both patterns
+	Mismatches []Mismatch // samples package regexp and the automata disagree on
+	Err        error      // set if a pattern could not be compiled
+}
+
+// OK reports whether the patterns compiled and no mismatch was found.
+func (r Report) OK() bool {
+	return r.Err == nil && len(r.Mismatches) == 0
+}
+
+// Mismatch is a string on which package regexp and the automata disagree.
+type Mismatch struct {
+	Sample    string
+	Expr      string // the pattern, or empty if Sample contradicts the decision
+	Regexp    bool   // package regexp matches Sample against Expr, or both patterns
+	Automaton bool   // the automaton of Expr, or of both patterns, accepts Sample
+}
+
+// VerifyAgainstCorpus cross-checks the intersection check of two patterns
+// against package regexp on the given samples. It reports the samples that
+// package regexp and the automaton of a pattern disagree on, the samples
+// that both patterns match although the automata do not intersect, and the
+// witness if package regexp does not match it against both patterns.
+func VerifyAgainstCorpus(expr1, expr2 string, samples []string) Report {
+	var report Report
+	re1, err := regexp.Compile("^(?:" + expr1 + ")$")
+	if err != nil {
+		report.Err = patternError(expr1, err)
+		return report
+	}
+	re2, err := regexp.Compile("^(?:" + expr2 + ")$")
+	if err != nil {
+		report.Err = patternError(expr2, err)
+		return report
+	}
+	node1, node2, err := compilePair(expr1, expr2, Options{})
+	if err != nil {
+		report.Err = err
+		return report
+	}
+	report.Result = CheckDFA(node1, node2)
+
+	for _, s := range samples {
+		m1, m2 := re1.MatchString(s), re2.MatchString(s)
+		a1, a2 := node1.Match(s), node2.Match(s)
+		if m1 != a1 {
+			report.Mismatches = append(report.Mismatches, Mismatch{Sample: s, Expr: expr1, Regexp: m1, Automaton: a1})
+		}
+		if m2 != a2 {
+			report.Mismatches = append(report.Mismatches, Mismatch{Sample: s, Expr: expr2, Regexp: m2, Automaton: a2})
+		}
+		if m1 && m2 {
+			report.Common = append(report.Common, s)
+			if !report.Intersects {
+				report.Mismatches = append(report.Mismatches, Mismatch{Sample: s, Regexp: true, Automaton: false})
+			}
+		}
+	}
+
+	if w := report.Witness; report.Intersects && !(re1.MatchString(w) && re2.MatchString(w)) {
+		report.Mismatches = append(report.Mismatches, Mismatch{Sample: w, Regexp: false, Automaton: true})
+	}
+
+	return report
+}
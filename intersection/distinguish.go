@@ -0,0 +1,53 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package intersection
+
+// Side tells which of two patterns accepts a string.
+type Side int
+
+const (
+	Neither Side = iota // both or none of the patterns accept the string
+	First               // only the first pattern accepts the string
+	Second              // only the second pattern accepts the string
+)
+
+func (s Side) String() string {
+	switch s {
+	case First:
+		return "first"
+	case Second:
+		return "second"
+	}
+	return "neither"
+}
+
+// Distinguish returns the shortest string accepted by exactly one of the
+// patterns and the side accepting it. Neither is returned if the patterns
+// accept the same language.
+func Distinguish(expr1, expr2 string) (string, Side, error) {
+	node1, node2, err := compilePair(expr1, expr2, Options{})
+	if err != nil {
+		return "", Neither, err
+	}
+
+	s := newPartialSearch(func(final1, final2 bool) bool { return final1 != final2 })
+	node, _ := s.run(node1, node2)
+	switch {
+	case node == nil:
+		return "", Neither, nil
+	case isFinal(node.Node1):
+		return witness(node), First, nil
+	}
+	return witness(node), Second, nil
+}
@@ -0,0 +1,74 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package intersection
+
+import (
+	"errors"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// Approx reports whether the patterns are within k edits of overlapping:
+// whether a string of the first pattern turns into one of the second with
+// at most k insertions, deletions or substitutions of runes. Approx with k
+// 0 is HasIntersection.
+func Approx(expr1, expr2 string, k int) (bool, error) {
+	if k < 0 {
+		return false, errors.New("negative edit distance")
+	}
+	node1, node2, err := compilePair(expr1, expr2, Options{})
+	if err != nil {
+		return false, err
+	}
+
+	// The product of the automata with the number of edits made so far is
+	// searched with a queue per number of edits, so every pair of states
+	// is first reached with the fewest edits.
+	type pair struct{ n1, n2 *dfa.Node }
+	edits := map[pair]int{{node1, node2}: 0}
+	queues := make([][]pair, k+1)
+	queues[0] = []pair{{node1, node2}}
+	visit := func(p pair, e int) {
+		if old, ok := edits[p]; e <= k && (!ok || e < old) {
+			edits[p] = e
+			queues[e] = append(queues[e], p)
+		}
+	}
+	for e := 0; e <= k; e++ {
+		for i := 0; i < len(queues[e]); i++ {
+			p := queues[e][i]
+			if edits[p] < e {
+				continue // reached with fewer edits since
+			}
+			if p.n1.Final && p.n2.Final {
+				return true, nil
+			}
+			for _, t1 := range p.n1.Transitions {
+				visit(pair{t1.Node, p.n2}, e+1) // deletion
+				for _, t2 := range p.n2.Transitions {
+					if len(runerange.Intersect(t1.RuneRanges, t2.RuneRanges)) > 0 {
+						visit(pair{t1.Node, t2.Node}, e)
+					} else {
+						visit(pair{t1.Node, t2.Node}, e+1) // substitution
+					}
+				}
+			}
+			for _, t2 := range p.n2.Transitions {
+				visit(pair{p.n1, t2.Node}, e+1) // insertion
+			}
+		}
+	}
+	return false, nil
+}
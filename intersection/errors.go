@@ -0,0 +1,102 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package intersection
+
+import (
+	"errors"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/nfa"
+)
+
+// ErrInvalidPattern is returned for patterns that are not valid regular
+// expressions.
+type ErrInvalidPattern struct {
+	Expr string // the pattern
+	Pos  int    // byte offset of the invalid part of the pattern
+	Err  error  // error reported by the parser
+}
+
+func (e *ErrInvalidPattern) Error() string {
+	return "invalid regexp " + strconv.Quote(e.Expr) + " at offset " + strconv.Itoa(e.Pos) + ": " + e.Err.Error()
+}
+
+func (e *ErrInvalidPattern) Unwrap() error {
+	return e.Err
+}
+
+// ErrUnsupportedConstruct is returned for patterns using Perl or PCRE
+// syntax that has no finite automaton equivalent, such as backreferences and
+// lookarounds, unless the patterns are approximated.
+type ErrUnsupportedConstruct struct {
+	Expr      string // the pattern
+	Construct string // name of the construct, e.g. "lookahead"
+	Pos       int    // byte offset of the construct in the pattern
+}
+
+func (e *ErrUnsupportedConstruct) Error() string {
+	return "unsupported regexp syntax " + e.Construct + " in " + strconv.Quote(e.Expr) + " at offset " + strconv.Itoa(e.Pos)
+}
+
+// ErrLimitExceeded is returned for patterns exceeding the limits of
+// nfa.Options.
+type ErrLimitExceeded = nfa.ErrLimitExceeded
+
+// ErrBudgetExceeded is returned when building or searching an automaton
+// exceeds the configured limits.
+var ErrBudgetExceeded = dfa.ErrBudgetExceeded
+
+// parse translates a pattern into an NFA, see Parse.
+func parse(expr string, opts nfa.Options) (*nfa.Node, error) {
+	r, err := Parse(expr, opts)
+	if err != nil {
+		return nil, err
+	}
+	return nfa.NewFromRegexpWithOptions(r, opts)
+}
+
+// Parse parses a pattern as nfa.Parse does, failing with the errors of
+// Compile. Unless opts.Approximate is set, constructs reported by
+// nfa.FindUnsupported are rejected before parsing.
+func Parse(expr string, opts nfa.Options) (*syntax.Regexp, error) {
+	if !opts.Approximate {
+		if found := nfa.FindUnsupported(expr); len(found) > 0 {
+			return nil, &ErrUnsupportedConstruct{Expr: expr, Construct: found[0].Construct, Pos: found[0].Pos}
+		}
+	}
+
+	r, err := nfa.Parse(expr, opts)
+	if err != nil {
+		return nil, patternError(expr, err)
+	}
+	return r, nil
+}
+
+// patternError converts an error returned by the parser into an
+// ErrInvalidPattern.
+func patternError(expr string, err error) error {
+	var serr *syntax.Error
+	if !errors.As(err, &serr) {
+		return err
+	}
+
+	pos := strings.Index(expr, serr.Expr)
+	if pos < 0 {
+		pos = 0
+	}
+	return &ErrInvalidPattern{Expr: expr, Pos: pos, Err: err}
+}
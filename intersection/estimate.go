@@ -0,0 +1,153 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !tinygo
+
+package intersection
+
+import (
+	"math/big"
+
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// Estimate counts the strings of at most maxLen runes both patterns accept,
+// and reports whether there are infinitely many of them.
+func Estimate(expr1, expr2 string, maxLen int) (count *big.Int, infinite bool, err error) {
+	node1, node2, err := compilePair(expr1, expr2, Options{})
+	if err != nil {
+		return nil, false, err
+	}
+
+	s := newSearch(func(final1, final2 bool) bool { return false })
+	if _, err := s.run(node1, node2); err != nil {
+		return nil, false, err
+	}
+	states := countingStates(s.order)
+
+	count = new(big.Int)
+	counts := make([]*big.Int, len(states))
+	for i := range counts {
+		counts[i] = new(big.Int)
+	}
+	counts[0].SetInt64(1)
+	var tmp big.Int
+	for length := 0; length <= maxLen; length++ {
+		for i, st := range states {
+			if st.final {
+				count.Add(count, counts[i])
+			}
+		}
+		next := make([]*big.Int, len(states))
+		for i := range next {
+			next[i] = new(big.Int)
+		}
+		for i, st := range states {
+			if counts[i].Sign() == 0 {
+				continue
+			}
+			for _, m := range st.moves {
+				next[m.to].Add(next[m.to], tmp.Mul(counts[i], big.NewInt(int64(m.runes))))
+			}
+		}
+		counts = next
+	}
+
+	return count, hasLiveCycle(states), nil
+}
+
+// countingState is a product node with the number of runes of each of its
+// moves.
+type countingState struct {
+	final bool
+	moves []countingMove
+}
+
+type countingMove struct {
+	runes int // number of runes leading to the state
+	to    int // index of the state
+}
+
+// countingStates returns the product nodes of a search, given in
+// breadth-first order. The first state is the start.
+func countingStates(order []*CombineNode) []countingState {
+	index := make(map[*CombineNode]int, len(order))
+	for i, n := range order {
+		index[n] = i
+	}
+	states := make([]countingState, len(order))
+	for i, n := range order {
+		states[i].final = n.Final
+		for _, t := range n.Transitions {
+			j, ok := index[t.Node]
+			if runes := runerange.Count(runerange.Valid(t.RuneRanges)); ok && runes > 0 {
+				states[i].moves = append(states[i].moves, countingMove{runes: runes, to: j})
+			}
+		}
+	}
+	return states
+}
+
+// hasLiveCycle reports whether a cycle goes through states from which a
+// final state can be reached. Every move reads a rune, so such a cycle
+// gives infinitely many accepted strings.
+func hasLiveCycle(states []countingState) bool {
+	reverse := make([][]int, len(states))
+	for i, st := range states {
+		for _, m := range st.moves {
+			reverse[m.to] = append(reverse[m.to], i)
+		}
+	}
+	live := make([]bool, len(states))
+	var queue []int
+	for i, st := range states {
+		if st.final {
+			live[i] = true
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		for _, j := range reverse[i] {
+			if !live[j] {
+				live[j] = true
+				queue = append(queue, j)
+			}
+		}
+	}
+
+	// Look for a back edge between live states in a depth-first search.
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	color := make([]int, len(states))
+	var visit func(i int) bool
+	visit = func(i int) bool {
+		color[i] = active
+		for _, m := range states[i].moves {
+			switch {
+			case !live[m.to]:
+			case color[m.to] == active:
+				return true
+			case color[m.to] == unvisited && visit(m.to):
+				return true
+			}
+		}
+		color[i] = done
+		return false
+	}
+	return live[0] && visit(0)
+}
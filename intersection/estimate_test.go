@@ -0,0 +1,55 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !tinygo
+
+package intersection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimate(t *testing.T) {
+	type Case struct {
+		Expr1    string
+		Expr2    string
+		MaxLen   int
+		Count    string
+		Infinite bool
+	}
+	cases := []Case{
+		{"[a-c]", "[b-d]", 5, "2", false},
+		{"a*", "a+", 3, "3", true},
+		{"[0-9]{3}", "[0-9]+", 3, "1000", false},
+		{"[0-9]{3}", "[0-9]+", 2, "0", false},
+		{"a", "b", 10, "0", false},
+		{"", "a*", 5, "1", false},
+		{"(?s).*", "(?s).*", 1, "1112065", true},
+		{"(?s).", "(?s).", 1, "1112064", false},
+		{"a|^a", "a|^a", 1, "1", false},
+		{"(?:^)*a", "a", 1, "1", false},
+		{"^a+$", `a+\b`, 3, "3", true},
+		{"a*", "a*", -1, "0", true},
+	}
+	for _, c := range cases {
+		count, infinite, err := Estimate(c.Expr1, c.Expr2, c.MaxLen)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Count, count.String(), "%q vs %q up to %d", c.Expr1, c.Expr2, c.MaxLen)
+		assert.Equal(t, c.Infinite, infinite, "%q vs %q", c.Expr1, c.Expr2)
+	}
+
+	_, _, err := Estimate("a(", "a", 1)
+	assert.Error(t, err)
+}
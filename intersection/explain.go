@@ -0,0 +1,72 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package intersection
+
+import (
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// explain returns why the patterns of a search that found no common string
+// do not intersect, given the product nodes in breadth-first order. It
+// names the earliest position where the patterns have no rune in common,
+// or else the earliest position where only one of them may end.
+func explain(order []*CombineNode) string {
+	for _, n := range order {
+		allowed1, allowed2 := allowedRunes(n.Node1), allowedRunes(n.Node2)
+		if len(runerange.Intersect(allowed1, allowed2)) == 0 {
+			return "position " + strconv.Itoa(position(n)) + ": first pattern only allows " +
+				allowed(allowed1, isFinal(n.Node1)) + ", second only allows " + allowed(allowed2, isFinal(n.Node2))
+		}
+	}
+	for _, n := range order {
+		if final1, final2 := isFinal(n.Node1), isFinal(n.Node2); final1 != final2 {
+			return "the patterns never end at the same position, e.g. at position " + strconv.Itoa(position(n)) +
+				" the first allows " + allowed(allowedRunes(n.Node1), final1) + " and the second " + allowed(allowedRunes(n.Node2), final2)
+		}
+	}
+	return ""
+}
+
+// allowedRunes returns the runes the node has a transition on.
+func allowedRunes(n *dfa.Node) []rune {
+	var rr []rune
+	if n != nil {
+		for _, t := range n.Transitions {
+			rr = runerange.Sum(rr, t.RuneRanges)
+		}
+	}
+	return rr
+}
+
+// position returns the number of runes read to reach the node.
+func position(n *CombineNode) int {
+	return utf8.RuneCountInString(witness(n))
+}
+
+// allowed describes what may come next in a string.
+func allowed(rr []rune, final bool) string {
+	switch {
+	case len(rr) == 0 && final:
+		return "the end"
+	case len(rr) == 0:
+		return "nothing"
+	case final:
+		return runerange.String(rr) + " or the end"
+	}
+	return runerange.String(rr)
+}
@@ -0,0 +1,284 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !tinygo
+
+package intersection
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// graph is the data the page draws for one automaton.
+type graph struct {
+	Title string      `json:"title"`
+	Nodes []graphNode `json:"nodes"`
+	Edges []graphEdge `json:"edges"`
+}
+
+type graphNode struct {
+	ID     int    `json:"id"`
+	Label  string `json:"label"`
+	Final  bool   `json:"final"`
+	Depth  int    `json:"depth"`
+	OnPath bool   `json:"onPath"`
+}
+
+type graphEdge struct {
+	From   int    `json:"from"`
+	To     int    `json:"to"`
+	Label  string `json:"label"`
+	OnPath bool   `json:"onPath"`
+}
+
+// WriteHTML writes a self-contained HTML page drawing the DFAs of both
+// patterns and their product, with the path of the witness highlighted if
+// the patterns intersect. Hovering a transition shows its runes.
+func WriteHTML(w io.Writer, expr1, expr2 string, opts Options) error {
+	node1, node2, err := compilePair(expr1, expr2, opts)
+	if err != nil {
+		return err
+	}
+
+	// Explore the whole product; breadth-first order makes the first
+	// accepted node the end of the shortest witness.
+	s := newSearch(func(final1, final2 bool) bool { return false })
+	s.configure(opts)
+	if _, err := s.run(node1, node2); err != nil {
+		return err
+	}
+	var end *CombineNode
+	for _, n := range s.order {
+		if n.Final {
+			end = n
+			break
+		}
+	}
+
+	onPath := make(map[*CombineNode]bool)
+	path1, path2 := newDFAPath(), newDFAPath()
+	for n := end; n != nil; n = n.parent {
+		onPath[n] = true
+		var from1, from2 *dfa.Node
+		if n.parent != nil {
+			from1, from2 = n.parent.Node1, n.parent.Node2
+		}
+		path1.add(from1, n.Node1)
+		path2.add(from2, n.Node2)
+	}
+
+	data := struct {
+		Expr1, Expr2 string
+		Witness      string
+		Intersects   bool
+		Graphs       []graph
+	}{
+		Expr1:      expr1,
+		Expr2:      expr2,
+		Intersects: end != nil,
+		Graphs: []graph{
+			dfaGraph(expr1, node1, path1),
+			dfaGraph(expr2, node2, path2),
+			productGraph(s.order, onPath),
+		},
+	}
+	if end != nil {
+		data.Witness = witness(end)
+	}
+
+	return page.Execute(w, data)
+}
+
+// dfaPath holds the nodes and transitions of a DFA the witness goes through.
+type dfaPath struct {
+	nodes map[*dfa.Node]bool
+	edges map[[2]*dfa.Node]bool
+}
+
+func newDFAPath() dfaPath {
+	return dfaPath{nodes: make(map[*dfa.Node]bool), edges: make(map[[2]*dfa.Node]bool)}
+}
+
+// add adds the node to the path along with the transition to it from the
+// previous node, which is nil for the first one.
+func (p dfaPath) add(from, to *dfa.Node) {
+	p.nodes[to] = true
+	if from != nil {
+		p.edges[[2]*dfa.Node{from, to}] = true
+	}
+}
+
+// dfaGraph returns the graph of the DFA rooted at root.
+func dfaGraph(title string, root *dfa.Node, path dfaPath) graph {
+	g := graph{Title: title}
+	depth := map[*dfa.Node]int{root: 0}
+	queue := []*dfa.Node{root}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		g.Nodes = append(g.Nodes, graphNode{
+			ID: n.State, Label: fmt.Sprint(n.State), Final: n.Final, Depth: depth[n], OnPath: path.nodes[n],
+		})
+
+		var targets []*dfa.Node
+		ranges := make(map[*dfa.Node][]rune)
+		for _, t := range n.Transitions {
+			if _, ok := ranges[t.Node]; !ok {
+				targets = append(targets, t.Node)
+			}
+			ranges[t.Node] = runerange.Sum(ranges[t.Node], t.RuneRanges)
+			if _, ok := depth[t.Node]; !ok {
+				depth[t.Node] = depth[n] + 1
+				queue = append(queue, t.Node)
+			}
+		}
+		for _, t := range targets {
+			g.Edges = append(g.Edges, graphEdge{
+				From: n.State, To: t.State, Label: runerange.String(ranges[t]), OnPath: path.edges[[2]*dfa.Node{n, t}],
+			})
+		}
+	}
+	return g
+}
+
+// productGraph returns the graph of the product nodes, given in
+// breadth-first order.
+func productGraph(order []*CombineNode, onPath map[*CombineNode]bool) graph {
+	g := graph{Title: "product"}
+	id := make(map[*CombineNode]int)
+	depth := make(map[*CombineNode]int)
+	for i, n := range order {
+		id[n] = i
+		if n.parent != nil {
+			depth[n] = depth[n.parent] + 1
+		}
+		g.Nodes = append(g.Nodes, graphNode{
+			ID: i, Label: n.Name, Final: n.Final, Depth: depth[n], OnPath: onPath[n],
+		})
+	}
+	for _, n := range order {
+		var targets []*CombineNode
+		ranges := make(map[*CombineNode][]rune)
+		for _, t := range n.Transitions {
+			if _, ok := ranges[t.Node]; !ok {
+				targets = append(targets, t.Node)
+			}
+			ranges[t.Node] = runerange.Sum(ranges[t.Node], t.RuneRanges)
+		}
+		for _, t := range targets {
+			g.Edges = append(g.Edges, graphEdge{
+				From: id[n], To: id[t], Label: runerange.String(ranges[t]), OnPath: onPath[t] && t.parent == n,
+			})
+		}
+	}
+	return g
+}
+
+var page = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Expr1}} vs {{.Expr2}}</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+code { background: #eee; padding: 0 .2em; }
+section { margin-bottom: 2em; }
+svg { border: 1px solid #ccc; }
+circle { fill: #fff; stroke: #333; stroke-width: 1.5; }
+circle.final { stroke-width: 4; }
+.path circle, circle.path { stroke: #d22; }
+line, path { stroke: #999; stroke-width: 1.5; fill: none; }
+marker path { fill: #999; stroke: none; }
+.edge.path line, .edge.path path { stroke: #d22; stroke-width: 3; }
+.edge:hover line, .edge:hover path { stroke: #26c; stroke-width: 4; }
+text { font-size: 11px; text-anchor: middle; dominant-baseline: middle; }
+#tip { position: fixed; background: #333; color: #fff; padding: .2em .5em; font-family: monospace; display: none; }
+</style>
+</head>
+<body>
+<h1><code>{{.Expr1}}</code> vs <code>{{.Expr2}}</code></h1>
+{{if .Intersects}}<p>The patterns intersect. Shortest common string: <code>{{printf "%q" .Witness}}</code>, highlighted in red.</p>
+{{else}}<p>The patterns do not intersect.</p>
+{{end}}<div id="graphs"></div>
+<div id="tip"></div>
+<script>
+const graphs = {{.Graphs}};
+const ns = "http://www.w3.org/2000/svg";
+const tip = document.getElementById("tip");
+
+function el(name, attrs, parent) {
+	const e = document.createElementNS(ns, name);
+	for (const k in attrs) e.setAttribute(k, attrs[k]);
+	parent.appendChild(e);
+	return e;
+}
+
+for (const g of graphs) {
+	const section = document.createElement("section");
+	const h = document.createElement("h2");
+	h.textContent = g.title;
+	section.appendChild(h);
+	document.getElementById("graphs").appendChild(section);
+
+	// Lay the nodes out in columns by their distance from the start.
+	const columns = [], pos = {};
+	for (const n of g.nodes) {
+		(columns[n.depth] = columns[n.depth] || []).push(n);
+	}
+	columns.forEach((col, x) => col.forEach((n, y) => {
+		pos[n.id] = {x: 60 + x * 140, y: 50 + y * 80};
+	}));
+	const width = 120 + Math.max(0, columns.length - 1) * 140;
+	const height = 100 + Math.max(0, ...columns.map(c => c.length - 1)) * 80;
+	const svg = el("svg", {width: width, height: height}, section);
+	el("marker", {id: "arrow", viewBox: "0 0 10 10", refX: 10, refY: 5, markerWidth: 6, markerHeight: 6, orient: "auto"},
+		el("defs", {}, svg)).appendChild(document.createElementNS(ns, "path")).setAttribute("d", "M0,0L10,5L0,10z");
+
+	for (const e of g.edges) {
+		const a = pos[e.from], b = pos[e.to];
+		const group = el("g", {class: e.onPath ? "edge path" : "edge"}, svg);
+		if (e.from === e.to) {
+			el("path", {d: "M" + (a.x - 8) + "," + (a.y - 18) + "a14,14 0 1,1 16,0", "marker-end": "url(#arrow)"}, group);
+		} else {
+			// Bend edges so that opposite transitions do not overlap.
+			const dx = b.x - a.x, dy = b.y - a.y, len = Math.hypot(dx, dy);
+			const ux = dx / len, uy = dy / len;
+			const mx = (a.x + b.x) / 2 - uy * 20, my = (a.y + b.y) / 2 + ux * 20;
+			el("path", {d: "M" + (a.x + ux * 20) + "," + (a.y + uy * 20) + "Q" + mx + "," + my + " " +
+				(b.x - ux * 20) + "," + (b.y - uy * 20), "marker-end": "url(#arrow)"}, group);
+		}
+		group.addEventListener("mousemove", ev => {
+			tip.textContent = e.label;
+			tip.style.display = "block";
+			tip.style.left = ev.clientX + 12 + "px";
+			tip.style.top = ev.clientY + 12 + "px";
+		});
+		group.addEventListener("mouseleave", () => { tip.style.display = "none"; });
+	}
+
+	for (const n of g.nodes) {
+		const p = pos[n.id];
+		const c = el("circle", {cx: p.x, cy: p.y, r: 20}, svg);
+		c.setAttribute("class", (n.final ? "final " : "") + (n.onPath ? "path" : ""));
+		el("text", {x: p.x, y: p.y}, svg).textContent = n.label;
+	}
+}
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,38 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !tinygo
+
+package intersection
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteHTML(t *testing.T) {
+	var b strings.Builder
+	assert.NoError(t, WriteHTML(&b, "a+b", "a*b</script>", Options{}))
+	assert.Contains(t, b.String(), "<!DOCTYPE html>")
+	assert.Contains(t, b.String(), "The patterns do not intersect.")
+	assert.NotContains(t, b.String(), "b</script>")
+
+	b.Reset()
+	assert.NoError(t, WriteHTML(&b, "/api/v1/[0-9]+", `/api/v1/\w+`, Options{}))
+	assert.Contains(t, b.String(), "Shortest common string: <code>&#34;/api/v1/0&#34;</code>")
+	assert.Contains(t, b.String(), `"onPath":true`)
+
+	assert.Error(t, WriteHTML(&b, "a(", "a", Options{}))
+}
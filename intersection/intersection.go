@@ -11,26 +11,26 @@
 // You should have received a copy of the GNU General Public License along
 // with this program.  If not, see <http://www.gnu.org/licenses/>.
 
-// This package checks that two DFA has intersection or not by DFS algorithm.
+// This package checks that two DFA has intersection or not by a breadth first search of their product.
 package intersection
 
 import (
-	"fmt"
+	"strconv"
+
 	"github.com/oulinbao/regexinter/dfa"
 	"github.com/oulinbao/regexinter/nfa"
-	"github.com/oulinbao/regexinter/runerange"
-	"log"
-	"regexp"
 )
 
-var nodeMap map[string]*CombineNode
-
 type CombineNode struct {
 	Name        string // state1_state2
 	Final       bool
 	Node1       *dfa.Node
 	Node2       *dfa.Node
 	Transitions []T
+
+	parent *CombineNode // node the search reached this node from
+	via    rune         // rune leading from parent to this node
+	length int          // runes read from the first node on
 }
 
 type T struct {
@@ -38,87 +38,217 @@ type T struct {
 	Node       *CombineNode // node
 }
 
+// HasIntersection reports whether two patterns accept a common string. It
+// panics if a pattern is invalid.
 func HasIntersection(expr1, expr2 string) bool {
 	node1, node2 := convert2Dfa(expr1), convert2Dfa(expr2)
-	if node1.Final && node2.Final {
-		return true
-	}
+	return CheckDFA(node1, node2).Intersects
+}
 
-	nodeMap = make(map[string]*CombineNode)
-	firstNode := createNode(node1, node2)
-	return dfs(firstNode)
+// Result describes the outcome of an intersection check.
+type Result struct {
+	Intersects     bool   // the patterns accept a common string
+	Witness        string // shortest common string if any
+	StatesExplored int    // number of product states visited
+
+	// Explanation tells why the patterns do not intersect, such as
+	// "position 8: first pattern only allows [0-9], second only allows
+	// [a-z]". It is only set by checks building DFAs.
+	Explanation string
+
+	// Approximate is set if a pattern was over-approximated, see
+	// nfa.Options.Approximate. The patterns certainly do not intersect if
+	// Intersects is false, but may not intersect if it is true.
+	Approximate bool
 }
 
-func convert2Dfa(expr string) *dfa.Node {
-	_, err := regexp.Compile(expr)
-	if err != nil {
-		log.Fatal(fmt.Sprintf("invalid regexp: %q", expr))
+// Options configure how patterns are compiled and searched.
+type Options struct {
+	Pattern nfa.Options // translation of patterns into automata
+	Limits  dfa.Limits  // limits for each DFA and for their product
+
+	// OnTheFly searches the product of the NFAs of the patterns without
+	// building their DFAs first, see CheckNFA.
+	OnTheFly bool
+
+	// ReadableWitness spells witnesses with ASCII letters and digits where
+	// the patterns allow, then with other printable runes, instead of with
+	// the smallest runes allowed, which may be control characters.
+	ReadableWitness bool
+
+	// Alphabet, if set, restricts witnesses to the runes of these ranges,
+	// such as the URL-safe characters. Patterns intersecting only on other
+	// runes fail the checks with ErrNoWitnessInAlphabet.
+	Alphabet []rune
+
+	// Minimize minimizes the DFAs of the patterns before searching their
+	// product, which is then usually much smaller. It has no effect on
+	// OnTheFly searches.
+	Minimize bool
+}
+
+// Check reports whether two patterns accept a common string. If they do,
+// the result carries the shortest such string.
+func Check(expr1, expr2 string) (Result, error) {
+	return CheckWithOptions(expr1, expr2, Options{})
+}
+
+// CheckWithOptions is like Check but compiles and searches the automata as
+// configured by opts. Exceeding the limits results in an error wrapping
+// ErrBudgetExceeded.
+func CheckWithOptions(expr1, expr2 string, opts Options) (Result, error) {
+	result, err := checkWithOptions(expr1, expr2, opts)
+	if err == nil && opts.Pattern.Approximate {
+		result.Approximate = len(nfa.FindUnsupported(expr1)) > 0 || len(nfa.FindUnsupported(expr2)) > 0
+	}
+	return result, err
+}
+
+func checkWithOptions(expr1, expr2 string, opts Options) (Result, error) {
+	if opts.OnTheFly {
+		nfa1, err := parse(expr1, opts.Pattern)
+		if err != nil {
+			return Result{}, err
+		}
+		nfa2, err := parse(expr2, opts.Pattern)
+		if err != nil {
+			return Result{}, err
+		}
+		return checkNFA(nfa1, nfa2, opts)
 	}
 
-	nfaNode, err := nfa.New(expr)
+	node1, node2, err := compilePair(expr1, expr2, opts)
 	if err != nil {
-		log.Fatal(err)
+		return Result{}, err
 	}
 
-	return dfa.NewFromNFA(nfaNode)
+	return checkDFA(node1, node2, opts)
 }
 
-func createNode(node1, node2 *dfa.Node) *CombineNode {
-	return &CombineNode{
-		Name:  nodeName(node1, node2),
-		Node1: node1,
-		Node2: node2,
-		Final: node1.Final && node2.Final,
-	}
+// CheckDFA is like Check but works on compiled automata, such as the ones
+// built by nfa.NewLevenshtein.
+func CheckDFA(node1, node2 *dfa.Node) Result {
+	result, _ := checkDFA(node1, node2, Options{})
+	return result
 }
 
-func dfs(node *CombineNode) bool {
-	ranges := findOverlapRanges(node.Node1.Transitions, node.Node2.Transitions)
+// CheckDFAWithLimits is like CheckDFA but fails with an error wrapping
+// ErrBudgetExceeded once the search of the product exceeds the limits.
+func CheckDFAWithLimits(node1, node2 *dfa.Node, limits dfa.Limits) (Result, error) {
+	return checkDFA(node1, node2, Options{Limits: limits})
+}
 
-	for _, r := range ranges {
-		nextNode1 := node.Node1.NextState(r)
-		nextNode2 := node.Node2.NextState(r)
-		node, ok := nodeMap[nodeName(nextNode1, nextNode2)]
-		if !ok {
-			node = createNode(nextNode1, nextNode2)
-			nodeMap[nodeName(nextNode1, nextNode2)] = node
-		} else {
-			// point to the same node, should ignore
-			continue
-		}
+// CheckTables is like CheckDFA but works on automata in table form, see
+// dfa.Freeze.
+func CheckTables(table1, table2 *dfa.Table) Result {
+	return CheckDFA(table1.Thaw(), table2.Thaw())
+}
 
-		// expend new node
-		node.Transitions = append(node.Transitions, T{r, node})
+func checkDFA(node1, node2 *dfa.Node, opts Options) (Result, error) {
+	s := newSearch(func(final1, final2 bool) bool { return final1 && final2 })
+	s.configure(opts)
+	node, err := s.run(node1, node2)
+	if err != nil {
+		return Result{}, err
+	}
+	if node == nil && opts.Alphabet != nil {
+		opts.Alphabet = nil
+		return noWitnessInAlphabet(checkDFA(node1, node2, opts))
+	}
+	result := Result{StatesExplored: s.visited}
+	if node != nil {
+		result.Intersects = true
+		result.Witness = witness(node)
+	} else {
+		result.Explanation = explain(s.order)
+	}
+	return result, nil
+}
 
-		if node.Final {
-			return true
-		}
+// WitnessMaxLen returns the shortest string of at most maxLen runes both
+// patterns accept. Only the part of their product within the bound is
+// searched, so it answers quickly even for patterns with huge products. It
+// reports false if there is no such string, which does not rule out longer
+// ones.
+func WitnessMaxLen(expr1, expr2 string, maxLen int) (string, bool, error) {
+	node1, node2, err := compilePair(expr1, expr2, Options{})
+	if err != nil {
+		return "", false, err
+	}
+	s := newSearch(func(final1, final2 bool) bool { return final1 && final2 })
+	s.maxLen = max(maxLen, 0)
+	node, err := s.run(node1, node2)
+	if err != nil || node == nil {
+		return "", false, err
+	}
+	return witness(node), true, nil
+}
 
-		// recursive
-		return dfs(node)
+func convert2Dfa(expr string) *dfa.Node {
+	node, err := Compile(expr, Options{})
+	if err != nil {
+		panic(err)
 	}
 
-	return false
+	return node
 }
 
-func findOverlapRanges(trans1, trans2 []dfa.T) [][]rune {
-	result := make([][]rune, 0)
+// Compile compiles a pattern into the DFA the checks work on. Errors are
+// reported as ErrInvalidPattern, ErrUnsupportedConstruct or an error wrapping
+// ErrBudgetExceeded.
+func Compile(expr string, opts Options) (*dfa.Node, error) {
+	nfaNode, err := parse(expr, opts.Pattern)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, t1 := range trans1 {
-		for _, t2 := range trans2 {
-			if runerange.Contains(t1.RuneRanges, t2.RuneRanges) {
-				result = append(result, t2.RuneRanges)
-			}
+	node, err := dfa.NewFromNFAWithLimits(nfaNode, opts.Limits)
+	if err != nil || !opts.Minimize {
+		return node, err
+	}
+	return dfa.Minimize(node), nil
+}
 
-			if runerange.Contains(t2.RuneRanges, t1.RuneRanges) {
-				result = append(result, t1.RuneRanges)
-			}
-		}
+// Normalize returns the pattern the automaton of expr is built from, with
+// capture groups removed and case folding expanded into character classes.
+// Patterns with the same normalized form have the same automaton.
+func Normalize(expr string, opts Options) (string, error) {
+	r, err := Parse(expr, opts.Pattern)
+	if err != nil {
+		return "", err
 	}
+	return nfa.Normalize(r).String(), nil
+}
 
-	return result
+func compilePair(expr1, expr2 string, opts Options) (*dfa.Node, *dfa.Node, error) {
+	node1, err := Compile(expr1, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	node2, err := Compile(expr2, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return node1, node2, nil
+}
+
+func createNode(node1, node2 *dfa.Node) *CombineNode {
+	return &CombineNode{
+		Name:  nodeName(node1, node2),
+		Node1: node1,
+		Node2: node2,
+		Final: isFinal(node1) && isFinal(node2),
+	}
 }
 
 func nodeName(node1, node2 *dfa.Node) string {
-	return fmt.Sprintf("%d_%d", node1.State, node2.State)
+	return strconv.Itoa(stateOf(node1)) + "_" + strconv.Itoa(stateOf(node2))
+}
+
+// stateOf returns the state of a DFA node, or 0 for the dead state.
+func stateOf(n *dfa.Node) int {
+	if n == nil {
+		return 0
+	}
+	return n.State
 }
@@ -14,7 +14,13 @@
 package intersection
 
 import (
+	"errors"
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/nfa"
 	"github.com/stretchr/testify/assert"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -47,3 +53,559 @@ func TestIntersection(t *testing.T) {
 		assert.Equal(t, c.Expect, HasIntersection(c.Expr1, c.Expr2))
 	}
 }
+
+func TestDistinguish(t *testing.T) {
+	type Case struct {
+		Expr1   string
+		Expr2   string
+		Witness string
+		Side    Side
+	}
+	cases := []Case{
+		{"a*", "a+", "", First},
+		{"a+", "a*", "", Second},
+		{"a|b", "[ab]", "", Neither},
+		{"(ab)*", "(ab)*|abc", "abc", Second},
+		{"[0-9]+", "[0-8]+", "9", First},
+		{"/api/v1/[0-9]+", "/api/v1/\\d+", "", Neither},
+		{"/api/v[12]/x", "/api/v1/x", "/api/v2/x", First},
+	}
+
+	for _, c := range cases {
+		witness, side, err := Distinguish(c.Expr1, c.Expr2)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Side, side, "%q vs %q", c.Expr1, c.Expr2)
+		assert.Equal(t, c.Witness, witness, "%q vs %q", c.Expr1, c.Expr2)
+	}
+
+	_, _, err := Distinguish("a(", "a")
+	assert.Error(t, err)
+}
+
+func TestCheck(t *testing.T) {
+	type Case struct {
+		Expr1   string
+		Expr2   string
+		Expect  bool
+		Witness string
+	}
+	cases := []Case{
+		{"", "", true, ""},
+		{"a+", "a*", true, "a"},
+		{"[a-m]+x", "[h-z]+", true, "hx"},
+		{"a*bba+", "b*aaab+a", true, "aaabba"},
+		{"/api/v1/[0-9]+/get", `/api/v1/\w+/get`, true, "/api/v1/0/get"},
+		{"a(b|c)d", "a[cd]d", true, "acd"},
+		{"a", "b", false, ""},
+		{"/api/v1/.*/", "/api/v2/.*/", false, ""},
+		{".", `[\x{D800}-\x{DFFF}]`, false, ""},
+		{"[^a]", `\x{D800}`, false, ""},
+	}
+
+	for _, c := range cases {
+		result, err := Check(c.Expr1, c.Expr2)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Expect, result.Intersects, "%q vs %q", c.Expr1, c.Expr2)
+		assert.Equal(t, c.Witness, result.Witness, "%q vs %q", c.Expr1, c.Expr2)
+		assert.True(t, result.StatesExplored > 0)
+	}
+
+	_, err := Check("a", "[")
+	assert.Error(t, err)
+}
+
+func TestCheckOnTheFly(t *testing.T) {
+	exprs := []string{
+		"", "a+", "a*", "[a-m]+x", "[h-z]+", "a*bba+", "b*aaab+a", "b*aaabbb+a", "/api/v1/.*/", "/api/v2/.*/",
+		"/api/v1/[0-9]+/get", `/api/v1/\w+/get`, "(a|b)*a(a|b){6}", "(a|b)*b(a|b){6}",
+	}
+
+	for _, expr1 := range exprs {
+		for _, expr2 := range exprs {
+			want, err := Check(expr1, expr2)
+			assert.NoError(t, err)
+			got, err := CheckWithOptions(expr1, expr2, Options{OnTheFly: true})
+			assert.NoError(t, err)
+			assert.Equal(t, want.Intersects, got.Intersects, "%q vs %q", expr1, expr2)
+			assert.Equal(t, want.Witness, got.Witness, "%q vs %q", expr1, expr2)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var invalid *ErrInvalidPattern
+	_, err := Check("/api/(v1", "a")
+	if assert.True(t, errors.As(err, &invalid)) {
+		assert.Equal(t, "/api/(v1", invalid.Expr)
+		assert.Equal(t, 0, invalid.Pos)
+	}
+	_, err = Check("a", "[a-z]{2,1}")
+	if assert.True(t, errors.As(err, &invalid)) {
+		assert.Equal(t, 5, invalid.Pos)
+	}
+	_, err = Check("a", `(\x9`)
+	if assert.True(t, errors.As(err, &invalid)) {
+		assert.Equal(t, "error parsing regexp: missing closing ): `(\\x9`", invalid.Err.Error())
+	}
+
+	type Case struct {
+		Expr      string
+		Construct string
+		Pos       int
+	}
+	cases := []Case{
+		{"a(?=b)", "lookahead", 1},
+		{"a(?!b)", "negative lookahead", 1},
+		{"(?<=a)b", "lookbehind", 0},
+		{"(?>a+)b", "atomic group", 0},
+		{"a++", "possessive quantifier", 2},
+		{`(a)\1`, "backreference", 3},
+		{`[a-z]+(?(1)b|c)`, "conditional", 6},
+	}
+	for _, c := range cases {
+		var unsupported *ErrUnsupportedConstruct
+		_, err := Check(c.Expr, "a")
+		if assert.True(t, errors.As(err, &unsupported), c.Expr) {
+			assert.Equal(t, c.Construct, unsupported.Construct)
+			assert.Equal(t, c.Pos, unsupported.Pos)
+			assert.Equal(t, c.Expr, unsupported.Expr)
+		}
+	}
+}
+
+func TestApproximate(t *testing.T) {
+	type Case struct {
+		Expr1  string
+		Expr2  string
+		Expect bool
+	}
+	cases := []Case{
+		{"a(?=b)b", "ab", true},
+		{"a(?!b)", "b", false},
+		{"(?<=a)b", "b", true},
+		{"(?>a+)b", "aab", true},
+		{"[0-9]++x", "12x", true},
+		{`(a)\1`, "aa", true},
+		{`(a)\1`, "ab", false},
+		{`x(?(1)b|c)`, "xc", true},
+		{`x(?(1)b|c)`, "xd", false},
+	}
+	opts := Options{Pattern: nfa.Options{Approximate: true}}
+	for _, c := range cases {
+		for _, onTheFly := range []bool{false, true} {
+			opts.OnTheFly = onTheFly
+			result, err := CheckWithOptions(c.Expr1, c.Expr2, opts)
+			assert.NoError(t, err, c.Expr1)
+			assert.Equal(t, c.Expect, result.Intersects, "%q vs %q", c.Expr1, c.Expr2)
+			assert.True(t, result.Approximate, c.Expr1)
+		}
+	}
+
+	result, err := CheckWithOptions("ab", "a+b", opts)
+	assert.NoError(t, err)
+	assert.False(t, result.Approximate)
+}
+
+func TestVerifyAgainstCorpus(t *testing.T) {
+	samples := []string{"", "a", "ab", "abc", "/api/v1/42", "/api/v1/x", "AB"}
+
+	report := VerifyAgainstCorpus("/api/v1/[0-9]+", `/api/v1/\w+`, samples)
+	assert.True(t, report.OK())
+	assert.True(t, report.Intersects)
+	assert.Equal(t, []string{"/api/v1/42"}, report.Common)
+
+	report = VerifyAgainstCorpus("a[bc]*", "(?i)ab", samples)
+	assert.True(t, report.OK())
+	assert.Equal(t, []string{"ab"}, report.Common)
+
+	report = VerifyAgainstCorpus("^ab$", `\bab`, samples)
+	assert.True(t, report.OK())
+	assert.Equal(t, []string{"ab"}, report.Common)
+
+	report = VerifyAgainstCorpus("a(", "a", samples)
+	var invalid *ErrInvalidPattern
+	assert.True(t, errors.As(report.Err, &invalid))
+	assert.False(t, report.OK())
+}
+
+func TestCheckMinimized(t *testing.T) {
+	type Case struct {
+		Expr1 string
+		Expr2 string
+	}
+	cases := []Case{
+		{"(a|b)*abb", "(a|b)*a(a|b)"},
+		{"(a|ab)(c|bcd)(d*)", "(abc|ab)d+"},
+		{"/api/(v1|v2)/(users|groups)/[0-9]+", "/api/v[0-9]/(user|group)s/.*"},
+		{"a*bba+", "b*aaabbb+a"},
+	}
+	for _, c := range cases {
+		plain, err := CheckWithOptions(c.Expr1, c.Expr2, Options{})
+		assert.NoError(t, err)
+		min, err := CheckWithOptions(c.Expr1, c.Expr2, Options{Minimize: true})
+		assert.NoError(t, err)
+		assert.Equal(t, plain.Intersects, min.Intersects, "%q vs %q", c.Expr1, c.Expr2)
+		assert.Equal(t, len(plain.Witness), len(min.Witness), "%q vs %q", c.Expr1, c.Expr2)
+		assert.LessOrEqual(t, min.StatesExplored, plain.StatesExplored, "%q vs %q", c.Expr1, c.Expr2)
+	}
+
+	plain, _ := CheckWithOptions("(a|b)*abb", "(a|b)*a(a|b)", Options{})
+	min, _ := CheckWithOptions("(a|b)*abb", "(a|b)*a(a|b)", Options{Minimize: true})
+	assert.Less(t, min.StatesExplored, plain.StatesExplored)
+}
+
+func TestCheckASCII(t *testing.T) {
+	type Case struct {
+		Expr1 string
+		Expr2 string
+	}
+	cases := []Case{
+		{"a*bba+", "b*aaab+a"},
+		{"/api/v1/[0-9]+/get", `/api/v1/\w+/get`},
+		{"/api/v1/.*/", "/api/v2/.*/"},
+		{"/users/[^/]+/posts", "/users/me/.*"},
+		{`\bab`, `\bab`},
+		{"[a-m]+x", "[h-z]+"},
+	}
+	for _, c := range cases {
+		plain, err := CheckWithOptions(c.Expr1, c.Expr2, Options{})
+		assert.NoError(t, err)
+		ascii, err := CheckWithOptions(c.Expr1, c.Expr2, Options{Pattern: nfa.Options{ASCII: true}})
+		assert.NoError(t, err)
+		assert.Equal(t, plain.Intersects, ascii.Intersects, "%q vs %q", c.Expr1, c.Expr2)
+		assert.Equal(t, plain.Witness, ascii.Witness, "%q vs %q", c.Expr1, c.Expr2)
+	}
+
+	result, err := CheckWithOptions("[^a]+", "é+", Options{Pattern: nfa.Options{ASCII: true}})
+	assert.NoError(t, err)
+	assert.False(t, result.Intersects)
+	result, err = CheckWithOptions(".", "[\x00-\x1f]", Options{Pattern: nfa.Options{ASCII: true}})
+	assert.NoError(t, err)
+	assert.False(t, result.Intersects)
+}
+
+func TestExplanation(t *testing.T) {
+	type Case struct {
+		Expr1  string
+		Expr2  string
+		Expect string
+	}
+	cases := []Case{
+		{"/api/v1/[0-9]+", "/api/v1/[a-z]+", "position 8: first pattern only allows [0-9], second only allows [a-z]"},
+		{"a", "ab", "position 1: first pattern only allows the end, second only allows [b]"},
+		{"ab|cd", "ad|cb", "position 1: first pattern only allows [b], second only allows [d]"},
+		{"é[0-9]*", "é[a-z]", "position 1: first pattern only allows [0-9] or the end, second only allows [a-z]"},
+		{"(ab)*", "(ab)*a", "the patterns never end at the same position, e.g. at position 0 the first allows [a] or the end and the second [a]"},
+		{"a+", "a*", ""},
+	}
+	for _, c := range cases {
+		result, err := Check(c.Expr1, c.Expr2)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Expect, result.Explanation, "%q vs %q", c.Expr1, c.Expr2)
+	}
+}
+
+func TestToRegex(t *testing.T) {
+	type Case struct {
+		Expr1  string
+		Expr2  string
+		Expect string
+	}
+	cases := []Case{
+		{"[a-m]+", "[h-z]+", "[h-m]+"},
+		{"a*", "aa", "aa"},
+		{"/api/v[12]/.*", "/api/v[23]/users", "/api/v2/users"},
+		{"a", "b", dfa.NoMatch},
+	}
+	for _, c := range cases {
+		got, err := ToRegex(c.Expr1, c.Expr2)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Expect, got, "%q vs %q", c.Expr1, c.Expr2)
+	}
+
+	// The pattern matches the common strings and nothing else.
+	expr1, expr2 := "(a|b)*abb", "[ab]{3,5}"
+	got, err := ToRegex(expr1, expr2)
+	assert.NoError(t, err)
+	_, side, err := Distinguish(got, "(?:a|b){0,2}abb")
+	assert.NoError(t, err)
+	assert.Equal(t, Neither, side, got)
+
+	_, err = ToRegex("a(", "a")
+	assert.Error(t, err)
+}
+
+func TestScanReader(t *testing.T) {
+	log := "GET /api/v1/users 200\r\nGET /api/v2/users 500\nPOST /api/v1/users 500\n\nGET /api/v1/orders 500"
+	got, err := ScanReader(strings.NewReader(log), "GET .*", ".* 500")
+	assert.NoError(t, err)
+	assert.Equal(t, []Match{{2, "GET /api/v2/users 500"}, {5, "GET /api/v1/orders 500"}}, got)
+
+	got, err = ScanReader(strings.NewReader(log), "GET .*", "POST .*")
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	got, err = ScanReader(strings.NewReader("\nx\n"), "x?", ".*")
+	assert.NoError(t, err)
+	assert.Equal(t, []Match{{1, ""}, {2, "x"}}, got)
+
+	_, err = ScanReader(strings.NewReader(log), "a(", "a")
+	assert.Error(t, err)
+}
+
+func TestConcurrentCheck(t *testing.T) {
+	node1, node2 := convert2Dfa("/api/v[0-9]+/.*"), convert2Dfa("/api/v1/users?")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				result := CheckDFA(node1, node2)
+				assert.True(t, result.Intersects)
+				assert.Equal(t, "/api/v1/user", result.Witness)
+				result, err := checkDFA(node1, node2, Options{Pattern: nfa.Options{ASCII: true}})
+				assert.NoError(t, err)
+				assert.True(t, result.Intersects)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCheckTables(t *testing.T) {
+	table1, table2 := dfa.Freeze(convert2Dfa("[a-m]+x")), dfa.Freeze(convert2Dfa("[h-z]+"))
+	result := CheckTables(table1, table2)
+	assert.True(t, result.Intersects)
+	assert.Equal(t, "hx", result.Witness)
+	assert.False(t, CheckTables(table1, dfa.Freeze(convert2Dfa("a"))).Intersects)
+}
+
+func TestCheckDFAWithLimits(t *testing.T) {
+	node1, node2 := convert2Dfa("[ab]*a[ab]{5}"), convert2Dfa("[ab]*")
+	_, err := CheckDFAWithLimits(node1, node2, dfa.Limits{MaxStates: 10})
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+	result, err := CheckDFAWithLimits(node1, node2, dfa.Limits{MaxStates: 1000})
+	assert.NoError(t, err)
+	assert.Equal(t, "aaaaaa", result.Witness)
+}
+
+func TestLogger(t *testing.T) {
+	for _, onTheFly := range []bool{false, true} {
+		for _, c := range []struct {
+			expr1, expr2 string
+			intersects   bool
+		}{
+			{"/api/v1/.*/", "/api/v2/.*/", false},
+			// The search stops with states still queued.
+			{"[ab]*a[ab]{5}", "[ab]*", true},
+		} {
+			visited := 0
+			logger := dfa.LoggerFunc(func(e dfa.Event) {
+				if e.Kind == dfa.ProductStateVisited {
+					visited++
+				}
+			})
+			result, err := CheckWithOptions(c.expr1, c.expr2, Options{OnTheFly: onTheFly, Limits: dfa.Limits{Logger: logger}})
+			assert.NoError(t, err)
+			assert.Equal(t, c.intersects, result.Intersects)
+			assert.Equal(t, visited, result.StatesExplored, "%q vs %q, OnTheFly: %v", c.expr1, c.expr2, onTheFly)
+		}
+	}
+}
+
+func TestHasIntersectionPanics(t *testing.T) {
+	assert.Panics(t, func() { HasIntersection("a(", "a") })
+}
+
+func TestWithStrings(t *testing.T) {
+	type Case struct {
+		Expr   string
+		Words  []string
+		Expect []string
+	}
+	cases := []Case{
+		{"debug|info|warn(ing)?", []string{"info", "warning", "trace", "warn", "", "infos", "info"}, []string{"info", "warning", "warn", "info"}},
+		{"[a-z]*", []string{"", "abc", "ABC", "é"}, []string{"", "abc"}},
+		{"x+", nil, []string{}},
+		{dfa.NoMatch, []string{"", "a"}, []string{}},
+	}
+	for _, c := range cases {
+		words, err := WithStrings(c.Expr, c.Words)
+		assert.NoError(t, err, c.Expr)
+		assert.Equal(t, c.Expect, words, c.Expr)
+	}
+	_, err := WithStrings("(", []string{"a"})
+	assert.Error(t, err)
+}
+
+func TestApprox(t *testing.T) {
+	type Case struct {
+		Expr1  string
+		Expr2  string
+		K      int
+		Expect bool
+	}
+	cases := []Case{
+		{"/api/users", "/api/user", 0, false},
+		{"/api/users", "/api/user", 1, true},
+		{"/api/v1/.*", "/apl/v2/x", 1, false},
+		{"/api/v1/.*", "/apl/v2/x", 2, true},
+		{"kitten", "sitting", 2, false},
+		{"kitten", "sitting", 3, true},
+		{"a+", "b+", 1, true},
+		{"", "abc", 3, true},
+		{"", "abc", 2, false},
+		{"[0-9]{3}", "[a-z]{3}", 2, false},
+		{"[0-9]{3}", "[a-z]{2}", 3, true},
+		{"x", dfa.NoMatch, 5, false},
+	}
+	for _, c := range cases {
+		ok, err := Approx(c.Expr1, c.Expr2, c.K)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Expect, ok, "%s ~%d %s", c.Expr1, c.K, c.Expr2)
+		// Edits can be undone the other way round.
+		ok, err = Approx(c.Expr2, c.Expr1, c.K)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Expect, ok, "%s ~%d %s", c.Expr2, c.K, c.Expr1)
+	}
+	_, err := Approx("a", "b", -1)
+	assert.Error(t, err)
+	_, err = Approx("(", "b", 1)
+	assert.Error(t, err)
+}
+
+func TestReadableWitness(t *testing.T) {
+	type Case struct {
+		Expr1    string
+		Expr2    string
+		Smallest string
+		Readable string
+	}
+	cases := []Case{
+		{`\w+`, `.{3}`, "000", "aaa"},
+		{`[^a-z]`, `.`, "\x00", "A"},
+		{`id-[\-0-9a-f]{2}`, `.*`, "id---", "id-aa"},
+		{`[\x{80}-\x{10FFFF}]`, `.`, "\u0080", "ª"},
+		{`[\x{80}-\x{a9}]`, `.`, "\u0080", "¡"},
+		{`[\x00-\x1f]`, `.|\n`, "\x00", "\x00"},
+	}
+	for _, c := range cases {
+		for _, onTheFly := range []bool{false, true} {
+			result, err := CheckWithOptions(c.Expr1, c.Expr2, Options{OnTheFly: onTheFly})
+			assert.NoError(t, err)
+			assert.Equal(t, c.Smallest, result.Witness, c.Expr1)
+			result, err = CheckWithOptions(c.Expr1, c.Expr2, Options{OnTheFly: onTheFly, ReadableWitness: true})
+			assert.NoError(t, err)
+			assert.Equal(t, c.Readable, result.Witness, c.Expr1)
+		}
+	}
+}
+
+func TestAlphabet(t *testing.T) {
+	type Case struct {
+		Expr1   string
+		Expr2   string
+		Witness string
+		Err     error
+	}
+	urlSafe := []rune{'-', '.', '0', '9', 'A', 'Z', '_', '_', 'a', 'z', '~', '~'}
+	cases := []Case{
+		{`/files/.+`, `/files/[^a-z]+`, "", ErrNoWitnessInAlphabet},
+		{`/files/[^/]+`, `/files/[^a-z]+`, "", ErrNoWitnessInAlphabet},
+		{`/files/.+`, `.*[ %A]`, "", ErrNoWitnessInAlphabet},
+		{`id-.+`, `id-[^a-z]+`, "id--", nil},
+		{`[a-z]+`, `[A-Z]+`, "", nil},
+	}
+	for _, c := range cases {
+		for _, onTheFly := range []bool{false, true} {
+			result, err := CheckWithOptions(c.Expr1, c.Expr2, Options{OnTheFly: onTheFly, Alphabet: urlSafe})
+			assert.ErrorIs(t, err, c.Err, c.Expr2)
+			assert.Equal(t, c.Witness, result.Witness, c.Expr2)
+			assert.Equal(t, c.Err != nil || c.Witness != "", result.Intersects, c.Expr2)
+		}
+	}
+}
+
+func TestWitnessMaxLen(t *testing.T) {
+	type Case struct {
+		Expr1   string
+		Expr2   string
+		MaxLen  int
+		Witness string
+		Found   bool
+	}
+	cases := []Case{
+		{"a{5}", "a+", 4, "", false},
+		{"a{5}", "a+", 5, "aaaaa", true},
+		{"x.*y", ".*z.*", 2, "", false},
+		{"x.*y", ".*z.*", 3, "xzy", true},
+		{"x.*y", ".*z.*", 100, "xzy", true},
+		{"a*", "b*", 0, "", true},
+		{"a*", "b*", -1, "", true},
+		{"a+", "b+", 10, "", false},
+		{"^ab$", "^a.$", 2, "ab", true},
+	}
+	for _, c := range cases {
+		witness, found, err := WitnessMaxLen(c.Expr1, c.Expr2, c.MaxLen)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Found, found, "%s %s %d", c.Expr1, c.Expr2, c.MaxLen)
+		assert.Equal(t, c.Witness, witness, "%s %s %d", c.Expr1, c.Expr2, c.MaxLen)
+	}
+	_, _, err := WitnessMaxLen("(", "a", 1)
+	assert.Error(t, err)
+}
+
+func TestWitnesses(t *testing.T) {
+	type Case struct {
+		Expr1 string
+		Expr2 string
+	}
+	cases := []Case{
+		{"(a|b)*abb", "[ab]*b{2}[ab]*"},
+		{"a*b*c*", "(ab|c)*"},
+		{"[abc]+", "[abc]{2}"},
+		{"a|b|c", "[b-c]"},
+		{"ab", "ba"},
+		{"", "a*"},
+	}
+	for _, c := range cases {
+		// The first strings over a, b and c in the order of Witnesses.
+		re1 := regexp.MustCompile("^(?:" + c.Expr1 + ")$")
+		re2 := regexp.MustCompile("^(?:" + c.Expr2 + ")$")
+		var want []string
+		level := []string{""}
+		for n := 0; n <= 6 && len(want) < 5; n++ {
+			var next []string
+			for _, s := range level {
+				if re1.MatchString(s) && re2.MatchString(s) && len(want) < 5 {
+					want = append(want, s)
+				}
+				next = append(next, s+"a", s+"b", s+"c")
+			}
+			level = next
+		}
+
+		got, err := Witnesses(c.Expr1, c.Expr2, 5)
+		assert.NoError(t, err)
+		if len(want) == 0 {
+			want = nil
+		}
+		assert.Equal(t, want, got, "%s %s", c.Expr1, c.Expr2)
+	}
+
+	got, err := Witnesses(".*", "[0-9]{2}", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"00", "01", "02"}, got)
+	got, err = Witnesses("^a$|^b$", "^[ab]$", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, got)
+	_, err = Witnesses("(", "a", 1)
+	assert.Error(t, err)
+
+	// Running out of steps is reported along with the strings found.
+	got, err = Witnesses("[a-z]{8}", ".*", 1000000)
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.NotEmpty(t, got)
+	assert.Equal(t, "aaaaaaaa", got[0])
+}
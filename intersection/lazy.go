@@ -0,0 +1,196 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package intersection
+
+import (
+	"sort"
+	"time"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/nfa"
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// CheckNFA is like CheckDFA but searches the product of two NFAs directly.
+// The states of the product are pairs of sets of NFA states that are built
+// on the fly, so neither automaton is determinized on its own. This pays off
+// for patterns whose DFAs are large while only a small part of their product
+// is reachable. The assertions of NFAs built by hand are resolved first, see
+// nfa.ResolveAssertions.
+func CheckNFA(node1, node2 *nfa.Node, limits dfa.Limits) (Result, error) {
+	return checkNFA(nfa.ResolveAssertions(node1), nfa.ResolveAssertions(node2), Options{Limits: limits})
+}
+
+func checkNFA(node1, node2 *nfa.Node, opts Options) (Result, error) {
+	limits, readable := opts.Limits, opts.ReadableWitness
+	begin := time.Now()
+	visited := 0
+	a1, a2 := newStateSets(node1), newStateSets(node2)
+	start := &lazyNode{set1: a1.closure([]int{0}), set2: a2.closure([]int{0})}
+	nodes := map[string]*lazyNode{start.key(): start}
+	queue := []*lazyNode{start}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		visited++
+		limits.Log(dfa.Event{Kind: dfa.ProductStateVisited, State: visited})
+		if a1.final(node.set1) && a2.final(node.set2) {
+			return Result{Intersects: true, Witness: node.witness(), StatesExplored: visited}, nil
+		}
+
+		pairs := runerange.Split(append(a1.ranges(node.set1), a2.ranges(node.set2)...))
+		for i := 0; i < len(pairs); i += 2 {
+			r := restrict(pairs[i:i+2], opts.Alphabet)
+			if len(r) == 0 {
+				continue
+			}
+			set1 := a1.step(node.set1, r)
+			if len(set1) == 0 {
+				continue
+			}
+			set2 := a2.step(node.set2, r)
+			if len(set2) == 0 {
+				continue
+			}
+			next := &lazyNode{set1: set1, set2: set2, parent: node, via: pickRune(r, readable)}
+			if old, ok := nodes[next.key()]; ok {
+				// Several ranges may lead to the same node.
+				if readable && old.parent == node && readability(next.via) < readability(old.via) {
+					old.via = next.via
+				}
+				continue
+			}
+			if err := limits.Step(begin, len(nodes)+1, len(queue)); err != nil {
+				return Result{}, err
+			}
+			nodes[next.key()] = next
+			queue = append(queue, next)
+		}
+	}
+
+	if opts.Alphabet != nil {
+		opts.Alphabet = nil
+		return noWitnessInAlphabet(checkNFA(node1, node2, opts))
+	}
+	return Result{StatesExplored: visited}, nil
+}
+
+type lazyNode struct {
+	set1, set2 []int
+	parent     *lazyNode
+	via        rune
+}
+
+func (n *lazyNode) key() string {
+	buf := make([]byte, 0, 4*(len(n.set1)+len(n.set2)+1))
+	for _, i := range n.set1 {
+		buf = append(buf, byte(i>>24), byte(i>>16), byte(i>>8), byte(i))
+	}
+	// No state has this number, so it separates the sets.
+	buf = append(buf, 0xff, 0xff, 0xff, 0xff)
+	for _, i := range n.set2 {
+		buf = append(buf, byte(i>>24), byte(i>>16), byte(i>>8), byte(i))
+	}
+	return string(buf)
+}
+
+func (n *lazyNode) witness() string {
+	var rs []rune
+	for ; n.parent != nil; n = n.parent {
+		if n.via >= 0 {
+			rs = append(rs, n.via)
+		}
+	}
+	for i, j := 0, len(rs)-1; i < j; i, j = i+1, j-1 {
+		rs[i], rs[j] = rs[j], rs[i]
+	}
+	return string(rs)
+}
+
+// stateSets numbers the states of an NFA and computes sets of them. Sets are
+// sorted slices of state numbers closed under epsilon transitions.
+type stateSets struct {
+	nodes []*nfa.Node
+	index map[*nfa.Node]int
+}
+
+func newStateSets(n *nfa.Node) *stateSets {
+	s := &stateSets{index: make(map[*nfa.Node]int)}
+	s.add(n)
+	for i := 0; i < len(s.nodes); i++ {
+		for _, t := range s.nodes[i].T {
+			s.add(t.N)
+		}
+	}
+	return s
+}
+
+func (s *stateSets) add(n *nfa.Node) {
+	if _, ok := s.index[n]; !ok {
+		s.index[n] = len(s.nodes)
+		s.nodes = append(s.nodes, n)
+	}
+}
+
+func (s *stateSets) closure(set []int) []int {
+	in := make(map[int]bool, len(set))
+	for _, i := range set {
+		in[i] = true
+	}
+	for k := 0; k < len(set); k++ {
+		for _, t := range s.nodes[set[k]].T {
+			if j := s.index[t.N]; t.R == nil && !in[j] {
+				in[j] = true
+				set = append(set, j)
+			}
+		}
+	}
+	sort.Ints(set)
+	return set
+}
+
+func (s *stateSets) step(set []int, r []rune) []int {
+	var next []int
+	seen := make(map[int]bool)
+	for _, i := range set {
+		for _, t := range s.nodes[i].T {
+			if j := s.index[t.N]; t.R != nil && runerange.Contains(t.R, r) && !seen[j] {
+				seen[j] = true
+				next = append(next, j)
+			}
+		}
+	}
+	return s.closure(next)
+}
+
+func (s *stateSets) final(set []int) bool {
+	for _, i := range set {
+		if s.nodes[i].F {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *stateSets) ranges(set []int) [][]rune {
+	var ranges [][]rune
+	for _, i := range set {
+		for _, t := range s.nodes[i].T {
+			if t.R != nil {
+				ranges = append(ranges, t.R)
+			}
+		}
+	}
+	return ranges
+}
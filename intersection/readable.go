@@ -0,0 +1,77 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package intersection
+
+import "unicode"
+
+// readableRanges are the ASCII runes witnesses are spelled with by
+// preference, in order, see Options.ReadableWitness.
+var readableRanges = [][2]rune{{'a', 'z'}, {'A', 'Z'}, {'0', '9'}, {'!', '~'}, {' ', ' '}}
+
+// maxScan bounds the runes beyond ASCII pickRune looks at.
+const maxScan = 1 << 16
+
+// pickRune returns the rune a witness reads for the ranges r: their
+// smallest rune, or with readable the most readable one.
+func pickRune(r []rune, readable bool) rune {
+	if !readable {
+		return r[0]
+	}
+	best := r[0]
+	for i := 0; i < len(r); i += 2 {
+		if c := pickInRange(r[i], r[i+1]); readability(c) < readability(best) {
+			best = c
+		}
+	}
+	return best
+}
+
+// pickInRange returns the most readable rune of [lo, hi].
+func pickInRange(lo, hi rune) rune {
+	for _, rr := range readableRanges {
+		if c := max(lo, rr[0]); c <= min(hi, rr[1]) {
+			return c
+		}
+	}
+	end := min(hi, max(lo, unicode.MaxASCII+1)+maxScan)
+	for _, is := range []func(rune) bool{isLetterOrDigit, unicode.IsPrint} {
+		for c := max(lo, unicode.MaxASCII+1); c <= end; c++ {
+			if is(c) {
+				return c
+			}
+		}
+	}
+	return lo
+}
+
+// readability ranks runes by how readable pickRune finds them, the lowest
+// rank being the most readable.
+func readability(r rune) int {
+	for i, rr := range readableRanges {
+		if r >= rr[0] && r <= rr[1] {
+			return i
+		}
+	}
+	switch {
+	case r > unicode.MaxASCII && isLetterOrDigit(r):
+		return len(readableRanges)
+	case r > unicode.MaxASCII && unicode.IsPrint(r):
+		return len(readableRanges) + 1
+	}
+	return len(readableRanges) + 2
+}
+
+func isLetterOrDigit(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
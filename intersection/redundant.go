@@ -0,0 +1,68 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package intersection
+
+import (
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/nfa"
+)
+
+// Redundant returns the alternatives of the pattern's alternations that
+// match no string the other alternatives do not, such as both a in
+// (a|a|ab?). Alternatives are considered from left to right and compared
+// with the ones not reported yet, so of two equivalent alternatives the
+// first one is reported. Alternations with an alternative that cannot be
+// compiled on its own are skipped.
+func Redundant(expr string, opts Options) ([]nfa.Branch, error) {
+	if _, err := parse(expr, opts.Pattern); err != nil {
+		return nil, err
+	}
+	// Alternatives match as a whole, whatever the semantics of the pattern.
+	opts.Pattern.Prefix = false
+	opts.Pattern.Substring = false
+
+	var redundant []nfa.Branch
+outer:
+	for _, branches := range nfa.Alternations(expr) {
+		nodes := make([]*dfa.Node, len(branches))
+		for i, b := range branches {
+			n, err := Compile(b.Flags+b.Text, opts)
+			if err != nil {
+				continue outer
+			}
+			nodes[i] = n
+		}
+
+		for i := range branches {
+			var others []*dfa.Node
+			for j, n := range nodes {
+				if j != i && n != nil {
+					others = append(others, n)
+				}
+			}
+			if subset(nodes[i], dfa.Union(others...)) {
+				redundant = append(redundant, branches[i])
+				nodes[i] = nil
+			}
+		}
+	}
+	return redundant, nil
+}
+
+// subset reports whether every string node1 accepts is accepted by node2.
+func subset(node1, node2 *dfa.Node) bool {
+	s := newPartialSearch(func(final1, final2 bool) bool { return final1 && !final2 })
+	node, _ := s.run(node1, node2)
+	return node == nil
+}
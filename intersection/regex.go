@@ -0,0 +1,64 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package intersection
+
+import (
+	"sort"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// ToRegex returns a pattern matching exactly the strings both patterns
+// match, built from their minimized product automaton. Like dfa.ToRegex,
+// the pattern describes whole strings. It is dfa.NoMatch if the patterns do
+// not intersect.
+func ToRegex(expr1, expr2 string) (string, error) {
+	node1, node2, err := compilePair(expr1, expr2, Options{})
+	if err != nil {
+		return "", err
+	}
+
+	s := newSearch(func(final1, final2 bool) bool { return false })
+	if _, err := s.run(node1, node2); err != nil {
+		return "", err
+	}
+	return dfa.ToRegex(dfa.Minimize(productDFA(s.order)))
+}
+
+// productDFA returns the automaton of the product nodes of a search, given
+// in breadth-first order, starting with the first one.
+func productDFA(order []*CombineNode) *dfa.Node {
+	nodes := make(map[*CombineNode]*dfa.Node, len(order))
+	for i, n := range order {
+		nodes[n] = &dfa.Node{State: i + 1, Final: n.Final}
+	}
+	for _, n := range order {
+		node := nodes[n]
+		index := make(map[*CombineNode]int)
+		for _, t := range n.Transitions {
+			i, ok := index[t.Node]
+			if !ok {
+				i = len(node.Transitions)
+				index[t.Node] = i
+				node.Transitions = append(node.Transitions, dfa.T{Node: nodes[t.Node]})
+			}
+			node.Transitions[i].RuneRanges = runerange.Sum(node.Transitions[i].RuneRanges, t.RuneRanges)
+		}
+		sort.Slice(node.Transitions, func(i, j int) bool {
+			return node.Transitions[i].RuneRanges[0] < node.Transitions[j].RuneRanges[0]
+		})
+	}
+	return nodes[order[0]]
+}
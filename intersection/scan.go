@@ -0,0 +1,60 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package intersection
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Match is a line matching both patterns.
+type Match struct {
+	Line int    // line number, from 1
+	Text string // line without its terminator
+}
+
+// ScanReader returns the lines of r both patterns match as a whole. The
+// lines are run through the product automaton of the patterns, which reads
+// each of them once. Lines end with \n or \r\n.
+func ScanReader(r io.Reader, expr1, expr2 string) ([]Match, error) {
+	node1, node2, err := compilePair(expr1, expr2, Options{})
+	if err != nil {
+		return nil, err
+	}
+	s := newSearch(func(final1, final2 bool) bool { return false })
+	if _, err := s.run(node1, node2); err != nil {
+		return nil, err
+	}
+	product := productDFA(s.order)
+
+	var matches []Match
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		text, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return matches, err
+		}
+		if err == io.EOF && text == "" {
+			return matches, nil
+		}
+		text = strings.TrimSuffix(strings.TrimSuffix(text, "\n"), "\r")
+		if product.Match(text) {
+			matches = append(matches, Match{line, text})
+		}
+		if err == io.EOF {
+			return matches, nil
+		}
+	}
+}
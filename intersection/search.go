@@ -0,0 +1,244 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package intersection
+
+import (
+	"strings"
+	"time"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/nfa"
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// search explores the product of two DFAs breadth first. In a partial
+// search a nil node stands for the dead state of a DFA, so the product also
+// covers strings that only one of the automata accepts.
+type search struct {
+	accept   func(final1, final2 bool) bool
+	partial  bool
+	nodes    map[[2]*dfa.Node]*CombineNode
+	order    []*CombineNode // nodes in the order they were reached
+	visited  int            // nodes taken off the queue by run
+	limits   dfa.Limits
+	readable bool   // see Options.ReadableWitness
+	alphabet []rune // see Options.Alphabet
+	maxLen   int    // longest strings searched, negative for no bound
+
+	// tables holds dense ASCII transition tables when the automata are
+	// restricted to ASCII, see nfa.Options.ASCII.
+	tables map[*dfa.Node]*asciiTable
+}
+
+// asciiTable maps each ASCII rune to the next state of a DFA node.
+type asciiTable [nfa.RuneLastASCII + 1]*dfa.Node
+
+func newSearch(accept func(final1, final2 bool) bool) *search {
+	return &search{
+		accept: accept,
+		nodes:  make(map[[2]*dfa.Node]*CombineNode),
+		maxLen: -1,
+	}
+}
+
+// configure applies the limits and witness options of opts and enables the
+// dense tables for ASCII automata.
+func (s *search) configure(opts Options) {
+	s.limits = opts.Limits
+	s.readable = opts.ReadableWitness
+	s.alphabet = opts.Alphabet
+	if opts.Pattern.ASCII {
+		s.tables = make(map[*dfa.Node]*asciiTable)
+	}
+}
+
+func newPartialSearch(accept func(final1, final2 bool) bool) *search {
+	s := newSearch(accept)
+	s.partial = true
+	return s
+}
+
+// run returns the first accepted product node in breadth-first order, or nil
+// if no accepted node is reachable. It fails once the number of product
+// nodes exceeds the limits.
+func (s *search) run(node1, node2 *dfa.Node) (*CombineNode, error) {
+	start := time.Now()
+	first := s.node(node1, node2)
+	queue := []*CombineNode{first}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		s.visited++
+		s.limits.Log(dfa.Event{Kind: dfa.ProductStateVisited, State: s.visited, Pair: [2]int{stateOf(node.Node1), stateOf(node.Node2)}})
+		if s.accept(isFinal(node.Node1), isFinal(node.Node2)) {
+			return node, nil
+		}
+
+		if s.maxLen >= 0 && node.length >= s.maxLen {
+			continue
+		}
+		for _, r := range s.splitRanges(node.Node1, node.Node2) {
+			if r = restrict(r, s.alphabet); len(r) == 0 {
+				continue
+			}
+			next1, next2 := s.nextState(node.Node1, r), s.nextState(node.Node2, r)
+			if next1 == nil && next2 == nil || !s.partial && (next1 == nil || next2 == nil) {
+				continue
+			}
+			next, ok := s.nodes[[2]*dfa.Node{next1, next2}]
+			if ok && s.readable && next.parent == node && next != node {
+				// Several ranges may lead to the same node.
+				if c := pickRune(r, true); readability(c) < readability(next.via) {
+					next.via = c
+				}
+			}
+			if !ok {
+				if err := s.limits.Step(start, len(s.nodes)+1, len(queue)); err != nil {
+					return nil, err
+				}
+				next = s.node(next1, next2)
+				next.parent = node
+				next.via = pickRune(r, s.readable)
+				next.length = node.length + 1
+				queue = append(queue, next)
+			}
+			node.Transitions = append(node.Transitions, T{r, next})
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *search) node(node1, node2 *dfa.Node) *CombineNode {
+	node := createNode(node1, node2)
+	s.nodes[[2]*dfa.Node{node1, node2}] = node
+	s.order = append(s.order, node)
+	return node
+}
+
+// witness returns the string spelled by the path from the first product node
+// to node.
+func witness(node *CombineNode) string {
+	var rs []rune
+	for ; node.parent != nil; node = node.parent {
+		rs = append(rs, node.via)
+	}
+
+	var b strings.Builder
+	for i := len(rs) - 1; i >= 0; i-- {
+		b.WriteRune(rs[i])
+	}
+	return b.String()
+}
+
+// splitRanges is like the splitRanges function but scans the ASCII runes
+// of the dense tables, which is much faster than splitting their ranges.
+func (s *search) splitRanges(node1, node2 *dfa.Node) [][]rune {
+	if s.tables == nil {
+		return splitRanges(node1, node2)
+	}
+
+	var result [][]rune
+	t1, t2 := s.table(node1), s.table(node2)
+	for lo := 0; lo < len(t1); {
+		hi := lo
+		for hi+1 < len(t1) && t1[hi+1] == t1[lo] && t2[hi+1] == t2[lo] {
+			hi++
+		}
+		if t1[lo] != nil || t2[lo] != nil {
+			result = append(result, []rune{rune(lo), rune(hi)})
+		}
+		lo = hi + 1
+	}
+
+	// The runes beyond ASCII are split as usual.
+	var ranges [][]rune
+	for _, n := range []*dfa.Node{node1, node2} {
+		if n == nil {
+			continue
+		}
+		for _, t := range n.Transitions {
+			if rr := runerange.Subtract(t.RuneRanges, asciiRunes); len(rr) > 0 {
+				ranges = append(ranges, rr)
+			}
+		}
+	}
+	pairs := runerange.Split(ranges)
+	for i := 0; i < len(pairs); i += 2 {
+		result = append(result, pairs[i:i+2])
+	}
+	return result
+}
+
+var asciiRunes = []rune{0, nfa.RuneLastASCII}
+
+// nextState returns the next state of n for a pair of splitRanges.
+func (s *search) nextState(n *dfa.Node, r []rune) *dfa.Node {
+	if s.tables != nil && r[1] <= nfa.RuneLastASCII {
+		return s.table(n)[r[0]]
+	}
+	return nextState(n, r)
+}
+
+// table returns the dense table of n, which is all nil for the dead state.
+func (s *search) table(n *dfa.Node) *asciiTable {
+	if t, ok := s.tables[n]; ok {
+		return t
+	}
+	t := new(asciiTable)
+	if n != nil {
+		for _, tr := range n.Transitions {
+			rr := runerange.Intersect(tr.RuneRanges, asciiRunes)
+			for i := 0; i < len(rr); i += 2 {
+				for r := rr[i]; r <= rr[i+1]; r++ {
+					t[r] = tr.Node
+				}
+			}
+		}
+	}
+	s.tables[n] = t
+	return t
+}
+
+// splitRanges splits the transition ranges of both nodes into pairs such
+// that each pair leads to a single next state of either node.
+func splitRanges(node1, node2 *dfa.Node) [][]rune {
+	var ranges [][]rune
+	for _, n := range []*dfa.Node{node1, node2} {
+		if n == nil {
+			continue
+		}
+		for _, t := range n.Transitions {
+			ranges = append(ranges, t.RuneRanges)
+		}
+	}
+
+	pairs := runerange.Split(ranges)
+	result := make([][]rune, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		result = append(result, pairs[i:i+2])
+	}
+	return result
+}
+
+func nextState(n *dfa.Node, r []rune) *dfa.Node {
+	if n == nil {
+		return nil
+	}
+	return n.NextState(r)
+}
+
+func isFinal(n *dfa.Node) bool {
+	return n != nil && n.Final
+}
@@ -0,0 +1,188 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package intersection
+
+import (
+	"container/heap"
+	"slices"
+	"strconv"
+
+	"github.com/oulinbao/regexinter/dfa"
+)
+
+// witnessBudget bounds the number of steps Witnesses takes, as the
+// strings may be few and far apart.
+const witnessBudget = 100000
+
+// Witnesses returns up to k of the shortest strings both patterns accept,
+// shortest first and in the order of their runes for the same length. Fewer
+// strings are returned if the patterns have fewer in common. If the strings
+// are too long or too many to be found within a reasonable number of steps,
+// the ones found are returned along with an error wrapping
+// ErrBudgetExceeded.
+func Witnesses(expr1, expr2 string, k int) ([]string, error) {
+	node1, node2, err := compilePair(expr1, expr2, Options{})
+	if err != nil {
+		return nil, err
+	}
+	found, complete := shortest(dfa.Intersect(node1, node2), k)
+	if !complete {
+		return found, witnessBudgetError{}
+	}
+	return found, nil
+}
+
+// witnessBudgetError is returned by Witnesses when it runs out of steps.
+type witnessBudgetError struct{}
+
+func (witnessBudgetError) Error() string {
+	return ErrBudgetExceeded.Error() + ": more than " + strconv.Itoa(witnessBudget) + " steps"
+}
+
+func (witnessBudgetError) Unwrap() error { return ErrBudgetExceeded }
+
+// witnessItem is a string being spelled: the runes read so far, and the
+// transition to read the next one from. For final items prefix is a string
+// the automaton accepts.
+type witnessItem struct {
+	prefix []rune
+	cost   int    // length of the shortest accepted string it can lead to
+	key    []rune // orders the items of the same cost as their strings
+	final  bool
+
+	ranges []rune // ranges of the transition, then its rune the item reads
+	pair   int
+	r      rune
+	to     *dfa.Node
+}
+
+// push pushes the item on the queue, setting its cost and key.
+func (q *witnessQueue) push(it *witnessItem, dist map[*dfa.Node]int) {
+	it.cost, it.key = len(it.prefix), it.prefix
+	if !it.final {
+		it.cost += dist[it.to] + 1
+		it.key = append(it.prefix[:len(it.prefix):len(it.prefix)], it.r)
+	}
+	heap.Push(q, it)
+}
+
+type witnessQueue []*witnessItem
+
+func (q witnessQueue) Len() int { return len(q) }
+func (q witnessQueue) Less(i, j int) bool {
+	if q[i].cost != q[j].cost {
+		return q[i].cost < q[j].cost
+	}
+	return slices.Compare(q[i].key, q[j].key) < 0
+}
+func (q witnessQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *witnessQueue) Push(x any)   { *q = append(*q, x.(*witnessItem)) }
+func (q *witnessQueue) Pop() any {
+	old := *q
+	it := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return it
+}
+
+// shortest returns up to k of the shortest strings the automaton accepts,
+// and whether the search completed within witnessBudget steps. It is a
+// best-first search guided by the distance of each state to a final
+// one, which spells the runes of the ranges of transitions one at a time, so
+// that broad ranges cost nothing until their runes are needed.
+func shortest(n *dfa.Node, k int) (found []string, complete bool) {
+	dist := distances(n)
+	var q witnessQueue
+	// expand pushes the items leaving node, reached on prefix.
+	expand := func(prefix []rune, node *dfa.Node) {
+		if node.Final {
+			q.push(&witnessItem{prefix: prefix, final: true}, dist)
+		}
+		for _, t := range node.Transitions {
+			if _, ok := dist[t.Node]; ok {
+				q.push(&witnessItem{prefix: prefix, ranges: t.RuneRanges, r: t.RuneRanges[0], to: t.Node}, dist)
+			}
+		}
+	}
+	if _, ok := dist[n]; ok {
+		expand(nil, n)
+	}
+
+	var result []string
+	seen := make(map[string]bool)
+	for steps := 0; len(q) > 0 && len(result) < k; steps++ {
+		if steps == witnessBudget {
+			return result, false
+		}
+		it := heap.Pop(&q).(*witnessItem)
+		if it.final {
+			if s := string(it.prefix); !seen[s] {
+				seen[s] = true
+				result = append(result, s)
+			}
+			continue
+		}
+
+		// The next rune of the range, if any, comes after this one.
+		next := *it
+		if next.r < next.ranges[next.pair+1] {
+			next.r++
+		} else if next.pair += 2; next.pair < len(next.ranges) {
+			next.r = next.ranges[next.pair]
+		}
+		if next.pair < len(next.ranges) {
+			q.push(&next, dist)
+		}
+
+		expand(append(it.prefix[:len(it.prefix):len(it.prefix)], it.r), it.to)
+	}
+	return result, true
+}
+
+// distances returns the number of runes read on the shortest path from each
+// state to a final state, for the states with such paths.
+func distances(n *dfa.Node) map[*dfa.Node]int {
+	nodes := []*dfa.Node{n}
+	seen := map[*dfa.Node]bool{n: true}
+	from := make(map[*dfa.Node][]*dfa.Node)
+	for i := 0; i < len(nodes); i++ {
+		for _, t := range nodes[i].Transitions {
+			from[t.Node] = append(from[t.Node], nodes[i])
+			if !seen[t.Node] {
+				seen[t.Node] = true
+				nodes = append(nodes, t.Node)
+			}
+		}
+	}
+
+	// A breadth-first search from the final states backwards.
+	dist := make(map[*dfa.Node]int)
+	var queue []*dfa.Node
+	for _, node := range nodes {
+		if node.Final {
+			dist[node] = 0
+			queue = append(queue, node)
+		}
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, prev := range from[node] {
+			if _, ok := dist[prev]; !ok {
+				dist[prev] = dist[node] + 1
+				queue = append(queue, prev)
+			}
+		}
+	}
+	return dist
+}
@@ -0,0 +1,73 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package intersection
+
+import (
+	"sort"
+
+	"github.com/oulinbao/regexinter/dfa"
+)
+
+// trie holds the words of a list, see WithStrings.
+type trie struct {
+	children map[rune]*trie
+	words    []int // indexes of the words ending here
+}
+
+// WithStrings returns the words of the list the pattern accepts, in the
+// order of the list. The words are put in a trie walked along with the
+// automaton of the pattern, so prefixes they share are read only once.
+func WithStrings(expr string, words []string) ([]string, error) {
+	n, err := Compile(expr, Options{})
+	if err != nil {
+		return nil, err
+	}
+	root := &trie{}
+	for i, w := range words {
+		t := root
+		for _, r := range w {
+			c := t.children[r]
+			if c == nil {
+				if t.children == nil {
+					t.children = make(map[rune]*trie)
+				}
+				c = &trie{}
+				t.children[r] = c
+			}
+			t = c
+		}
+		t.words = append(t.words, i)
+	}
+
+	var accepted []int
+	var walk func(t *trie, n *dfa.Node)
+	walk = func(t *trie, n *dfa.Node) {
+		if n.Final {
+			accepted = append(accepted, t.words...)
+		}
+		for r, c := range t.children {
+			if next := n.NextState([]rune{r, r}); next != nil {
+				walk(c, next)
+			}
+		}
+	}
+	walk(root, n)
+	sort.Ints(accepted)
+
+	result := make([]string, len(accepted))
+	for i, w := range accepted {
+		result[i] = words[w]
+	}
+	return result, nil
+}
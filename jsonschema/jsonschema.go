@@ -0,0 +1,239 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package jsonschema compares the "pattern" keywords of JSON Schemas, which
+// are ECMA-262 regular expressions matching anywhere in strings, for schema
+// evolution tools to tell whether a value can satisfy both and whether one
+// is stricter.
+package jsonschema
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/intersection"
+	"github.com/oulinbao/regexinter/nfa"
+)
+
+// ECMA-262 character classes that differ from those of package regexp.
+const (
+	// whiteSpace is the content of the class \s, white space and line
+	// terminators.
+	whiteSpace = `\t\n\v\f\r \x{a0}\x{1680}\x{2000}-\x{200a}\x{2028}\x{2029}\x{202f}\x{205f}\x{3000}\x{feff}`
+
+	// notLineTerminator is the class . stands for.
+	notLineTerminator = `[^\n\r\x{2028}\x{2029}]`
+)
+
+// Translate returns the pattern of package regexp matching, as a whole, the
+// strings the ECMA-262 pattern matches part of. The supported subset leaves
+// out lookarounds, backreferences, word boundaries and anchors other than a
+// ^ starting and a $ ending the pattern or its top-level alternatives.
+// Patterns are read with the u flag, so characters are code points.
+func Translate(pattern string) (string, error) {
+	if found := nfa.FindUnsupported(pattern); len(found) > 0 {
+		return "", &intersection.ErrUnsupportedConstruct{Expr: pattern, Construct: found[0].Construct, Pos: found[0].Pos}
+	}
+	translated, err := translateSyntax(pattern)
+	if err != nil {
+		return "", err
+	}
+	r, err := syntax.Parse(translated, syntax.Perl)
+	if err != nil {
+		return "", fmt.Errorf("jsonschema: invalid pattern %q: %w", pattern, err)
+	}
+	r, err = search(r)
+	if err != nil {
+		return "", fmt.Errorf("jsonschema: pattern %q: %w", pattern, err)
+	}
+	return r.String(), nil
+}
+
+// translateSyntax rewrites the escapes and classes of ECMA-262 whose syntax
+// or meaning differ in package regexp.
+func translateSyntax(pattern string) (string, error) {
+	var b strings.Builder
+	inClass := false
+	for i := 0; i < len(pattern); {
+		c, size := utf8.DecodeRuneInString(pattern[i:])
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			esc := pattern[i+1]
+			n := 2
+			switch {
+			case esc == 'u' && strings.HasPrefix(pattern[i+2:], "{"):
+				end := strings.IndexByte(pattern[i:], '}')
+				if end < 0 {
+					return "", fmt.Errorf("jsonschema: unclosed \\u{ in %q", pattern)
+				}
+				b.WriteString(`\x` + pattern[i+2:i+end+1])
+				n = end + 1
+			case esc == 'u' && i+6 <= len(pattern):
+				b.WriteString(`\x{` + pattern[i+2:i+6] + `}`)
+				n = 6
+			case esc == 'c' && i+2 < len(pattern) && isASCIILetter(pattern[i+2]):
+				fmt.Fprintf(&b, `\x{%x}`, pattern[i+2]%32)
+				n = 3
+			case esc == 's' && inClass:
+				b.WriteString(whiteSpace)
+			case esc == 's':
+				b.WriteString("[" + whiteSpace + "]")
+			case esc == 'S' && !inClass:
+				b.WriteString("[^" + whiteSpace + "]")
+			case esc == 'S', esc == 'b' && !inClass, esc == 'B':
+				return "", fmt.Errorf(`jsonschema: \%c is not supported in %q`, esc, pattern)
+			case esc == 'b':
+				b.WriteString(`\x08`) // backspace in classes
+			case esc == 'd' || esc == 'D' || esc == 'w' || esc == 'W':
+				b.WriteString(pattern[i : i+2])
+			case esc == '0':
+				b.WriteString(`\x00`)
+			default:
+				_, size := utf8.DecodeRuneInString(pattern[i+1:])
+				n = 1 + size
+				b.WriteString(pattern[i : i+n])
+			}
+			i += n
+			continue
+		case inClass && c == ']':
+			inClass = false
+		case !inClass && strings.HasPrefix(pattern[i:], "[^]"):
+			b.WriteString(`(?s:.)`)
+			i += 3
+			continue
+		case !inClass && strings.HasPrefix(pattern[i:], "[]"):
+			b.WriteString(dfa.NoMatch)
+			i += 2
+			continue
+		case !inClass && c == '[':
+			inClass = true
+		case !inClass && c == '.':
+			b.WriteString(notLineTerminator)
+			i += size
+			continue
+		}
+		b.WriteRune(c)
+		i += size
+	}
+	return b.String(), nil
+}
+
+func isASCIILetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// search returns the expression matching the strings r matches part of. The
+// anchors at the ends of r or of its top-level alternatives are removed,
+// the other ends being extended to any string instead.
+func search(r *syntax.Regexp) (*syntax.Regexp, error) {
+	alternatives := []*syntax.Regexp{r}
+	if r.Op == syntax.OpAlternate {
+		alternatives = r.Sub
+	}
+	anyString := func() *syntax.Regexp {
+		return &syntax.Regexp{Op: syntax.OpStar, Sub: []*syntax.Regexp{{Op: syntax.OpAnyChar}}}
+	}
+	result := &syntax.Regexp{Op: syntax.OpAlternate}
+	for _, alt := range alternatives {
+		parts := []*syntax.Regexp{alt}
+		if alt.Op == syntax.OpConcat {
+			parts = alt.Sub
+		}
+		var begin, end bool
+		if len(parts) > 0 && parts[0].Op == syntax.OpBeginText {
+			parts, begin = parts[1:], true
+		}
+		if len(parts) > 0 && parts[len(parts)-1].Op == syntax.OpEndText {
+			parts, end = parts[:len(parts)-1], true
+		}
+		concat := &syntax.Regexp{Op: syntax.OpConcat}
+		if !begin {
+			concat.Sub = append(concat.Sub, anyString())
+		}
+		for _, p := range parts {
+			if err := checkAssertions(p); err != nil {
+				return nil, err
+			}
+			concat.Sub = append(concat.Sub, p)
+		}
+		if !end {
+			concat.Sub = append(concat.Sub, anyString())
+		}
+		result.Sub = append(result.Sub, concat)
+	}
+	if len(result.Sub) == 1 {
+		return result.Sub[0], nil
+	}
+	return result, nil
+}
+
+// checkAssertions rejects the anchors search cannot remove and makes the
+// quantifiers greedy, which changes which part a pattern matches but not
+// whether it matches.
+func checkAssertions(r *syntax.Regexp) error {
+	switch r.Op {
+	case syntax.OpBeginText, syntax.OpEndText, syntax.OpBeginLine, syntax.OpEndLine:
+		return fmt.Errorf("anchor inside the pattern is not supported")
+	}
+	r.Flags &^= syntax.NonGreedy
+	for _, sub := range r.Sub {
+		if err := checkAssertions(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Compatibility tells how the strings two patterns accept relate.
+type Compatibility struct {
+	// Satisfiable tells that some string is accepted by both patterns, the
+	// shortest of which is Witness.
+	Satisfiable bool
+	Witness     string
+
+	// AStricter tells that every string the first pattern accepts, the
+	// second accepts as well, and BStricter the converse. Both are set for
+	// equivalent patterns.
+	AStricter bool
+	BStricter bool
+}
+
+// Compare compares two JSON Schema patterns, see Translate.
+func Compare(a, b string) (Compatibility, error) {
+	nodeA, err := compile(a)
+	if err != nil {
+		return Compatibility{}, err
+	}
+	nodeB, err := compile(b)
+	if err != nil {
+		return Compatibility{}, err
+	}
+	r := intersection.CheckDFA(nodeA, nodeB)
+	return Compatibility{
+		Satisfiable: r.Intersects,
+		Witness:     r.Witness,
+		AStricter:   dfa.IsEmpty(dfa.Subtract(nodeA, nodeB)),
+		BStricter:   dfa.IsEmpty(dfa.Subtract(nodeB, nodeA)),
+	}, nil
+}
+
+func compile(pattern string) (*dfa.Node, error) {
+	expr, err := Translate(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return intersection.Compile(expr, intersection.Options{})
+}
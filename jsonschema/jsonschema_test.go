@@ -0,0 +1,97 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package jsonschema
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/oulinbao/regexinter/intersection"
+)
+
+func TestTranslate(t *testing.T) {
+	type testCase struct {
+		pattern string
+		match   []string
+		noMatch []string
+	}
+	testCases := []testCase{
+		{"^[a-z]+$", []string{"abc"}, []string{"", "ab1", "Abc"}},
+		{"[0-9]", []string{"a1b", "9"}, []string{"", "ab"}},
+		{"^a|b$", []string{"ax", "xb", "ab"}, []string{"xa", "bx"}},
+		{`^A\u{1F600}$`, []string{"A😀"}, []string{"A"}},
+		{`^\s$`, []string{" ", " ", " "}, []string{"a", ""}},
+		{`^[\s,]+$`, []string{" ,\t"}, []string{"a"}},
+		{`^.$`, []string{"a", "é"}, []string{"\n", " "}},
+		{`^[^]$`, []string{"\n"}, []string{""}},
+		{`[]`, nil, []string{"", "a"}},
+		{`^\cJ$`, []string{"\n"}, []string{"J"}},
+		{`^a+?$`, []string{"aa"}, []string{""}},
+		{`^\/api$`, []string{"/api"}, nil},
+		{`^(?<year>[0-9]{4})$`, []string{"2024"}, []string{"24"}},
+	}
+	for _, tc := range testCases {
+		expr, err := Translate(tc.pattern)
+		if err != nil {
+			t.Errorf("Translate(%q) failed: %v", tc.pattern, err)
+			continue
+		}
+		re := regexp.MustCompile("^(?:" + expr + ")$")
+		for _, s := range tc.match {
+			if !re.MatchString(s) {
+				t.Errorf("Translate(%q) = %q does not match %q", tc.pattern, expr, s)
+			}
+		}
+		for _, s := range tc.noMatch {
+			if re.MatchString(s) {
+				t.Errorf("Translate(%q) = %q matches %q", tc.pattern, expr, s)
+			}
+		}
+	}
+
+	for _, pattern := range []string{`a(?=b)`, `(a)\1`, `\bword`, `[\S]`, `(^a)`, `a^b`} {
+		if expr, err := Translate(pattern); err == nil {
+			t.Errorf("Translate(%q) = %q, want an error", pattern, expr)
+		}
+	}
+	var unsupported *intersection.ErrUnsupportedConstruct
+	if _, err := Translate(`a(?!b)`); !errors.As(err, &unsupported) {
+		t.Errorf("Translate of a lookahead returned %v", err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	type testCase struct {
+		a, b        string
+		satisfiable bool
+		witness     string
+		aStricter   bool
+		bStricter   bool
+	}
+	testCases := []testCase{
+		{"^[a-z]+$", "^[a-z0-9]+$", true, "a", true, false},
+		{"^[0-9]{3}$", "^[a-z]+$", false, "", false, false},
+		{"abc", "^abc$", true, "abc", false, true},
+		{"^a", "b$", true, "ab", false, false},
+		{"[0-9]", `\d`, true, "0", true, true},
+	}
+	for _, tc := range testCases {
+		got, err := Compare(tc.a, tc.b)
+		want := Compatibility{tc.satisfiable, tc.witness, tc.aStricter, tc.bStricter}
+		if err != nil || got != want {
+			t.Errorf("Compare(%q, %q) = %+v, %v, want %+v", tc.a, tc.b, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package lexer splits inputs into tokens with the automaton of their
+// patterns, taking the longest token at each position as lex does.
+package lexer
+
+import (
+	"github.com/oulinbao/regexinter/intersection"
+	"github.com/oulinbao/regexinter/multimatch"
+)
+
+// Token is a named kind of token.
+type Token struct {
+	Name string
+	Expr string // pattern of the tokens
+}
+
+// Lexer finds tokens of the kinds it was built with. It is safe for
+// concurrent use.
+type Lexer struct {
+	tokens  []Token
+	matcher *multimatch.Matcher
+}
+
+// New builds a lexer for the tokens. The kinds of tokens NextToken returns
+// are their indexes in tokens.
+func New(tokens []Token, opts intersection.Options) (*Lexer, error) {
+	exprs := make([]string, len(tokens))
+	for i, t := range tokens {
+		exprs[i] = t.Expr
+	}
+	m, err := multimatch.New(exprs, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Lexer{tokens: append([]Token(nil), tokens...), matcher: m}, nil
+}
+
+// NextToken returns the kind and length in bytes of the longest token
+// starting at pos in input, the first kind listed if several match it, as
+// keywords listed before identifiers should. It returns -1 and 0 if no
+// token starts there. Empty tokens are never returned.
+func (l *Lexer) NextToken(input []byte, pos int) (kind, length int) {
+	kind, length = l.matcher.Longest(input[pos:])
+	if length == 0 {
+		return -1, 0
+	}
+	return kind, length
+}
+
+// Name returns the name of a kind of token.
+func (l *Lexer) Name(kind int) string {
+	return l.tokens[kind].Name
+}
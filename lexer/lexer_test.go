@@ -0,0 +1,62 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package lexer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oulinbao/regexinter/intersection"
+)
+
+func TestNextToken(t *testing.T) {
+	l, err := New([]Token{
+		{"if", "if"},
+		{"ident", "[a-z_][a-z0-9_]*"},
+		{"number", "[0-9]+(\\.[0-9]+)?"},
+		{"op", "[-+*/=<>]|==|<=|>="},
+		{"space", "[ \t\n]+"},
+		{"optional", "x?"},
+	}, intersection.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := []byte("if iffy <= 3.14 ==x2\t? y")
+	var tokens []string
+	for pos := 0; pos < len(input); {
+		kind, length := l.NextToken(input, pos)
+		if kind < 0 {
+			tokens = append(tokens, "error:"+string(input[pos]))
+			pos++
+			continue
+		}
+		tokens = append(tokens, l.Name(kind)+":"+string(input[pos:pos+length]))
+		pos += length
+	}
+	want := "if:if|space: |ident:iffy|space: |op:<=|space: |number:3.14|space: |op:==|ident:x2|space:\t|error:?|space: |ident:y"
+	if got := strings.Join(tokens, "|"); got != want {
+		t.Errorf("tokens = %q\nwant %q", got, want)
+	}
+
+	if kind, length := l.NextToken(input, len(input)); kind != -1 || length != 0 {
+		t.Errorf("NextToken at the end = %d, %d, want -1, 0", kind, length)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New([]Token{{"bad", "("}}, intersection.Options{}); err == nil {
+		t.Errorf("New accepted an invalid pattern")
+	}
+}
@@ -0,0 +1,262 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package multimatch matches strings against many patterns at once with a
+// single automaton, the union of the automata of the patterns whose states
+// tell which of the patterns accept.
+package multimatch
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/intersection"
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// Matcher matches strings against a set of patterns. It is safe for
+// concurrent use.
+type Matcher struct {
+	start    *state // nil if no pattern matches anything
+	patterns int
+	states   []*state // by number, see State
+}
+
+type state struct {
+	id     int      // number of the state, from 1
+	accept []uint64 // bit i is set if pattern i accepts the strings leading here
+	winner int      // accepting pattern of highest priority, -1 if none
+	moves  []move   // sorted by lo
+}
+
+type move struct {
+	lo, hi rune
+	to     *state
+}
+
+// Pattern is a pattern along with its priority for First.
+type Pattern struct {
+	Expr     string
+	Priority int
+}
+
+// New compiles the patterns into a matcher, the indexes of the patterns
+// identifying them in the results. The limits of the options apply to the
+// union automaton as well as to those of the patterns. The patterns have the
+// same priority, so First picks the first one matching, as routers trying
+// their routes in order do.
+func New(exprs []string, opts intersection.Options) (*Matcher, error) {
+	patterns := make([]Pattern, len(exprs))
+	for i, expr := range exprs {
+		patterns[i] = Pattern{Expr: expr}
+	}
+	return NewPrioritized(patterns, opts)
+}
+
+// NewPrioritized is like New but First picks the matching pattern of highest
+// priority, the first one among those of equal priority.
+func NewPrioritized(patterns []Pattern, opts intersection.Options) (*Matcher, error) {
+	roots := make([]*dfa.Node, len(patterns))
+	for i, p := range patterns {
+		n, err := intersection.Compile(p.Expr, opts)
+		if err != nil {
+			return nil, err
+		}
+		roots[i] = n
+	}
+
+	// The states of the union are tuples of states of the patterns, nil
+	// for the patterns that cannot match anymore.
+	ids := make(map[*dfa.Node]int)
+	key := func(tuple []*dfa.Node) string {
+		var b strings.Builder
+		for _, n := range tuple {
+			id, ok := ids[n]
+			if !ok && n != nil {
+				id = len(ids) + 1
+				ids[n] = id
+			}
+			b.WriteString(strconv.Itoa(id))
+			b.WriteByte(',')
+		}
+		return b.String()
+	}
+	m := &Matcher{patterns: len(patterns)}
+	states := make(map[string]*state)
+	var tuples [][]*dfa.Node
+	var queue []*state
+	get := func(tuple []*dfa.Node) *state {
+		k := key(tuple)
+		if s, ok := states[k]; ok {
+			return s
+		}
+		s := &state{id: len(queue) + 1, accept: make([]uint64, (len(tuple)+63)/64), winner: -1}
+		for i, n := range tuple {
+			if n != nil && n.Final {
+				s.accept[i/64] |= 1 << (i % 64)
+				if s.winner < 0 || patterns[i].Priority > patterns[s.winner].Priority {
+					s.winner = i
+				}
+			}
+		}
+		states[k] = s
+		tuples = append(tuples, tuple)
+		queue = append(queue, s)
+		return s
+	}
+	if alive(roots) {
+		m.start = get(roots)
+	}
+	for i := 0; i < len(queue); i++ {
+		if err := opts.Limits.Check(len(queue)); err != nil {
+			return nil, err
+		}
+		s, tuple := queue[i], tuples[i]
+		var ranges [][]rune
+		for _, n := range tuple {
+			if n != nil {
+				for _, t := range n.Transitions {
+					ranges = append(ranges, t.RuneRanges)
+				}
+			}
+		}
+		pairs := runerange.Split(ranges)
+		for j := 0; j < len(pairs); j += 2 {
+			next := make([]*dfa.Node, len(tuple))
+			for k, n := range tuple {
+				if n != nil {
+					next[k] = n.NextState(pairs[j : j+2])
+				}
+			}
+			if alive(next) {
+				s.moves = append(s.moves, move{pairs[j], pairs[j+1], get(next)})
+			}
+		}
+		sort.Slice(s.moves, func(a, b int) bool { return s.moves[a].lo < s.moves[b].lo })
+	}
+	m.states = queue
+	return m, nil
+}
+
+func alive(tuple []*dfa.Node) bool {
+	for _, n := range tuple {
+		if n != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns the indexes of the patterns matching s as a whole, in
+// increasing order.
+func (m *Matcher) Match(s string) []int {
+	return m.accepting(m.run(s))
+}
+
+// accepting returns the indexes of the patterns accepting in st.
+func (m *Matcher) accepting(st *state) []int {
+	if st == nil {
+		return nil
+	}
+	var result []int
+	for i := 0; i < m.patterns; i++ {
+		if st.accept[i/64]&(1<<(i%64)) != 0 {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// First returns the index of the pattern that wins for s as a whole: the
+// matching pattern of highest priority, the first one among those of equal
+// priority. It reports false if no pattern matches s. Find looks for the
+// patterns inside a longer input instead.
+func (m *Matcher) First(s string) (int, bool) {
+	return winner(m.run(s))
+}
+
+func winner(st *state) (int, bool) {
+	if st == nil || st.winner < 0 {
+		return 0, false
+	}
+	return st.winner, true
+}
+
+// Longest returns the pattern First picks for the longest prefix of input
+// that a pattern matches and the length of the prefix in bytes, or -1 and 0
+// if no prefix matches. Invalid UTF-8 is read as U+FFFD a byte at a time.
+func (m *Matcher) Longest(input []byte) (pattern, length int) {
+	pattern = -1
+	st := m.start
+	for i := 0; st != nil; {
+		if st.winner >= 0 {
+			pattern, length = st.winner, i
+		}
+		if i == len(input) {
+			break
+		}
+		r, size := utf8.DecodeRune(input[i:])
+		st = st.next(r)
+		i += size
+	}
+	return pattern, length
+}
+
+// Find returns the pattern First picks for the leftmost-longest part of
+// input that a pattern matches, along with the byte offsets of the part, or
+// -1, 0 and 0 if no part matches. Invalid UTF-8 is read as by Longest.
+func (m *Matcher) Find(input []byte) (pattern, start, end int) {
+	for start = 0; ; {
+		if pattern, length := m.Longest(input[start:]); pattern >= 0 {
+			return pattern, start, start + length
+		}
+		if start == len(input) {
+			return -1, 0, 0
+		}
+		_, size := utf8.DecodeRune(input[start:])
+		start += size
+	}
+}
+
+// run returns the state reached on s, or nil if no pattern can match.
+func (m *Matcher) run(s string) *state {
+	st := m.start
+	for _, r := range s {
+		if st == nil {
+			return nil
+		}
+		st = st.next(r)
+	}
+	return st
+}
+
+func (s *state) next(r rune) *state {
+	i := sort.Search(len(s.moves), func(i int) bool { return s.moves[i].hi >= r })
+	if i < len(s.moves) && s.moves[i].lo <= r {
+		return s.moves[i].to
+	}
+	return nil
+}
+
+// Len returns the number of patterns.
+func (m *Matcher) Len() int {
+	return m.patterns
+}
+
+// States returns the number of states of the union automaton.
+func (m *Matcher) States() int {
+	return len(m.states)
+}
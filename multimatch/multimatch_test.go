@@ -0,0 +1,244 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package multimatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/intersection"
+)
+
+func TestMatch(t *testing.T) {
+	exprs := []string{"/api/v1/.*", "/api/v[0-9]+/users", "/api/v1/users", "/health", "[a-z/]+", "^/health$", `.*\bv1\b.*`}
+	m, err := New(exprs, intersection.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Len() != len(exprs) {
+		t.Errorf("Len() = %d, want %d", m.Len(), len(exprs))
+	}
+	for _, s := range []string{"/api/v1/users", "/api/v2/users", "/api/v1/x", "/health", "/healthz", "/api", "", "é"} {
+		var want []int
+		for i, e := range exprs {
+			if regexp.MustCompile("^(?:" + e + ")$").MatchString(s) {
+				want = append(want, i)
+			}
+		}
+		if got := m.Match(s); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Match(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestManyPatterns(t *testing.T) {
+	var exprs []string
+	for i := 0; i < 100; i++ {
+		exprs = append(exprs, fmt.Sprintf("x{%d}|y", i))
+	}
+	m, err := New(exprs, intersection.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Match("y"); len(got) != 100 {
+		t.Errorf("Match(y) matched %d patterns, want 100", len(got))
+	}
+	if got := m.Match("xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"); fmt.Sprint(got) != "[70]" {
+		t.Errorf("Match(x{70}) = %v, want [70]", got)
+	}
+}
+
+func TestNew(t *testing.T) {
+	m, err := New(nil, intersection.Options{})
+	if err != nil || m.Match("") != nil {
+		t.Errorf("New(nil) = %v, %v", m, err)
+	}
+	m, err = New([]string{dfa.NoMatch}, intersection.Options{})
+	if err != nil || m.Match("") != nil {
+		t.Errorf("New(NoMatch) = %v, %v", m, err)
+	}
+	if _, err := New([]string{"a", "("}, intersection.Options{}); err == nil {
+		t.Errorf("New accepted an invalid pattern")
+	}
+	opts := intersection.Options{Limits: dfa.Limits{MaxStates: 5}}
+	if _, err := New([]string{"[a-z]{3}", "[a-f]{4}"}, opts); !errors.Is(err, dfa.ErrBudgetExceeded) {
+		t.Errorf("New exceeding MaxStates returned %v", err)
+	}
+}
+
+func TestFirst(t *testing.T) {
+	exprs := []string{"/api/v1/.*", "/api/v[0-9]+/users", "/api/v1/users", "/health"}
+	m, err := New(exprs, intersection.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPrioritized([]Pattern{{exprs[0], 0}, {exprs[1], 1}, {exprs[2], 2}, {exprs[3], 0}}, intersection.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type testCase struct {
+		in          string
+		first, prio int // -1 if none
+	}
+	testCases := []testCase{
+		{"/api/v1/users", 0, 2},
+		{"/api/v2/users", 1, 1},
+		{"/api/v1/x", 0, 0},
+		{"/health", 3, 3},
+		{"/", -1, -1},
+	}
+	for _, tc := range testCases {
+		for _, c := range []struct {
+			m    *Matcher
+			want int
+		}{{m, tc.first}, {p, tc.prio}} {
+			got, ok := c.m.First(tc.in)
+			if !ok {
+				got = -1
+			}
+			if got != c.want {
+				t.Errorf("First(%q) = %d, want %d", tc.in, got, c.want)
+			}
+		}
+	}
+
+	// Prefix semantics make the patterns match paths under them, as with
+	// routers matching by prefix.
+	opts := intersection.Options{}
+	opts.Pattern.Prefix = true
+	m, err = New([]string{"/api/", "/api/v1/"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := m.First("/api/v1/users"); !ok || got != 0 {
+		t.Errorf("First(/api/v1/users) with prefixes = %d, %v, want 0", got, ok)
+	}
+}
+
+func TestLongest(t *testing.T) {
+	m, err := New([]string{"if", "[a-z]+", "[0-9]+", "[0-9]+\\.[0-9]+"}, intersection.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type testCase struct {
+		in      string
+		pattern int
+		length  int
+	}
+	testCases := []testCase{
+		{"if x", 0, 2},
+		{"iffy", 1, 4},
+		{"3.14)", 3, 4},
+		{"3.x", 2, 1},
+		{"éa", -1, 0},
+		{"", -1, 0},
+	}
+	for _, tc := range testCases {
+		if pattern, length := m.Longest([]byte(tc.in)); pattern != tc.pattern || length != tc.length {
+			t.Errorf("Longest(%q) = %d, %d, want %d, %d", tc.in, pattern, length, tc.pattern, tc.length)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	m, err := New([]string{"if", "[a-z]+", "[0-9]+", "[0-9]+\\.[0-9]+"}, intersection.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type testCase struct {
+		in         string
+		pattern    int
+		start, end int
+	}
+	testCases := []testCase{
+		{"if x", 0, 0, 2},
+		{"(iffy)", 1, 1, 5},
+		{"é = 3.14;", 3, 5, 9},
+		{"é ;", -1, 0, 0},
+		{"", -1, 0, 0},
+	}
+	for _, tc := range testCases {
+		if pattern, start, end := m.Find([]byte(tc.in)); pattern != tc.pattern || start != tc.start || end != tc.end {
+			t.Errorf("Find(%q) = %d, %d, %d, want %d, %d, %d", tc.in, pattern, start, end, tc.pattern, tc.start, tc.end)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	exprs := []string{"/api/v1/.*", "/api/v[0-9]+/users", "/é+", "[a-z/]+", ".*�"}
+	m, err := New(exprs, intersection.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another matcher compiled from the same patterns stands for another
+	// process taking over the stream.
+	other, err := New(exprs, intersection.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range []string{"/api/v1/users", "/api/v22/users", "/éé", "/é\xc3", "/x\xff", "\xe2\x82", ""} {
+		want := fmt.Sprint(m.Match(in))
+		wantFirst, wantOK := m.First(in)
+		for cut := 0; cut <= len(in); cut++ {
+			s := m.Stream()
+			s.WriteString(in[:cut])
+			data, err := json.Marshal(s.Snapshot())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var st State
+			if err := json.Unmarshal(data, &st); err != nil {
+				t.Fatal(err)
+			}
+			resumed, err := other.Resume(st)
+			if err != nil {
+				t.Fatalf("Resume(%+v) failed: %v", st, err)
+			}
+			resumed.WriteString(in[cut:])
+			if got := fmt.Sprint(resumed.Match()); got != want {
+				t.Errorf("Match() on %q cut at %d = %s, want %s", in, cut, got, want)
+			}
+			if first, ok := resumed.First(); first != wantFirst || ok != wantOK {
+				t.Errorf("First() on %q cut at %d = %d, %v, want %d, %v", in, cut, first, ok, wantFirst, wantOK)
+			}
+		}
+	}
+}
+
+func TestResume(t *testing.T) {
+	m, err := New([]string{"ab", "é"}, intersection.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range []State{
+		{State: -1},
+		{State: m.States() + 1},
+		{State: 0, Pending: []byte{0xc3}},
+		{State: 1, Pending: []byte("a")},
+		{State: 1, Pending: []byte{0xff}},
+	} {
+		if _, err := m.Resume(st); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("Resume(%+v) returned %v", st, err)
+		}
+	}
+	s := m.Stream()
+	s.WriteString("x")
+	if st := s.Snapshot(); st.State != 0 || st.Pending != nil {
+		t.Errorf("Snapshot() after a mismatch = %+v", st)
+	}
+}
@@ -0,0 +1,119 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package multimatch
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+// ErrInvalidState is returned by Resume for states the matcher cannot have
+// produced.
+var ErrInvalidState = errors.New("invalid matcher state")
+
+// Stream matches an input written in pieces, such as the buffered reads of
+// a request body, against the patterns of a Matcher. It is not safe for
+// concurrent use, but streams of the same matcher are independent.
+type Stream struct {
+	m       *Matcher
+	st      *state // nil once no pattern can match
+	pending []byte // start of a UTF-8 sequence split between writes
+}
+
+// State is the progress of a Stream, as taken by Snapshot and continued
+// from by Resume. Its fields can be stored or sent to another process: the
+// states are numbered alike by every matcher compiled from the same
+// patterns with the same options.
+type State struct {
+	State   int    // state of the union automaton, 0 once no pattern can match
+	Pending []byte // bytes of a UTF-8 sequence not complete yet
+}
+
+// Stream returns a stream at the start of the input.
+func (m *Matcher) Stream() *Stream {
+	return &Stream{m: m, st: m.start}
+}
+
+// Resume returns a stream continuing from st. It fails with
+// ErrInvalidState if st does not belong to the matcher.
+func (m *Matcher) Resume(st State) (*Stream, error) {
+	if st.State < 0 || st.State > len(m.states) || len(st.Pending) >= utf8.UTFMax ||
+		len(st.Pending) > 0 && (st.State == 0 || utf8.FullRune(st.Pending)) {
+		return nil, ErrInvalidState
+	}
+	s := &Stream{m: m, pending: append([]byte(nil), st.Pending...)}
+	if st.State > 0 {
+		s.st = m.states[st.State-1]
+	}
+	return s, nil
+}
+
+// Write reads p as the next bytes of the input. Invalid UTF-8 is read as
+// U+FFFD a byte at a time, as Longest does. It never fails.
+func (s *Stream) Write(p []byte) (int, error) {
+	buf := p
+	if len(s.pending) > 0 {
+		buf = append(s.pending, p...)
+	}
+	i := 0
+	for i < len(buf) && s.st != nil && utf8.FullRune(buf[i:]) {
+		r, size := utf8.DecodeRune(buf[i:])
+		s.st = s.st.next(r)
+		i += size
+	}
+	s.pending = nil
+	if s.st != nil && i < len(buf) {
+		s.pending = append(s.pending, buf[i:]...)
+	}
+	return len(p), nil
+}
+
+// WriteString is like Write but reads a string.
+func (s *Stream) WriteString(str string) (int, error) {
+	return s.Write([]byte(str))
+}
+
+// Snapshot returns the progress of the stream so far.
+func (s *Stream) Snapshot() State {
+	st := State{Pending: append([]byte(nil), s.pending...)}
+	if s.st != nil {
+		st.State = s.st.id
+	}
+	return st
+}
+
+// Match returns the indexes of the patterns matching the input written so
+// far, in increasing order, as Matcher.Match does.
+func (s *Stream) Match() []int {
+	return s.m.accepting(s.end())
+}
+
+// First returns the pattern that wins for the input written so far, as
+// Matcher.First does.
+func (s *Stream) First() (int, bool) {
+	return winner(s.end())
+}
+
+// end returns the state reached if the input ends here, the pending bytes
+// being invalid UTF-8 then.
+func (s *Stream) end() *state {
+	st := s.st
+	for range s.pending {
+		if st == nil {
+			break
+		}
+		st = st.next(utf8.RuneError)
+	}
+	return st
+}
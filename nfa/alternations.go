@@ -0,0 +1,109 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package nfa
+
+import "strings"
+
+// Branch is an alternative of an alternation in a pattern.
+type Branch struct {
+	Text string // text of the alternative
+	Pos  int    // byte offset of the alternative in the pattern
+
+	// Flags holds the inline flag groups in effect for the alternative,
+	// such as (?i), to put before Text to compile it on its own.
+	Flags string
+}
+
+// Alternations returns the alternations of the pattern, each as the list of
+// its branches, innermost groups first.
+func Alternations(pattern string) [][]Branch {
+	type frame struct {
+		flags    string // inline flags in effect in the group
+		start    int    // offset of the current branch
+		branches []Branch
+	}
+	var result [][]Branch
+	stack := []frame{{}}
+	end := func(i int) {
+		f := &stack[len(stack)-1]
+		f.branches = append(f.branches, Branch{Text: pattern[f.start:i], Pos: f.start, Flags: f.flags})
+		if len(f.branches) > 1 {
+			result = append(result, f.branches)
+		}
+	}
+
+	for i := 0; i < len(pattern); {
+		top := &stack[len(stack)-1]
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			i += tokenLen(pattern[i:])
+			continue
+		case c == '[':
+			i += classLen(pattern[i:])
+			continue
+		case c == '|':
+			top.branches = append(top.branches, Branch{Text: pattern[top.start:i], Pos: top.start, Flags: top.flags})
+			top.start = i + 1
+		case c == '(':
+			n, flags := groupOpening(pattern[i:])
+			if n < 0 {
+				// A group of flags applies to the rest of the group.
+				top.flags += flags
+				i += -n
+				continue
+			}
+			stack = append(stack, frame{flags: top.flags + flags, start: i + n})
+			i += n
+			continue
+		case c == ')' && len(stack) > 1:
+			end(i)
+			stack = stack[:len(stack)-1]
+		}
+		i++
+	}
+	for len(stack) > 1 {
+		end(len(pattern))
+		stack = stack[:len(stack)-1]
+	}
+	end(len(pattern))
+	return result
+}
+
+// groupOpening returns the length of the opening of the group s starts with
+// and the inline flag group it sets for the body, such as "(?i)" for (?i:.
+// For a group of flags only such as (?i), the length is negative.
+func groupOpening(s string) (int, string) {
+	if !strings.HasPrefix(s, "(?") {
+		return 1, ""
+	}
+	n := 2
+	for n < len(s) && strings.IndexByte("imsU-", s[n]) >= 0 {
+		n++
+	}
+	if n < len(s) && (s[n] == ':' || s[n] == ')') {
+		flags := ""
+		if n > 2 {
+			flags = "(?" + s[2:n] + ")"
+		}
+		if s[n] == ')' {
+			return -(n + 1), flags
+		}
+		return n + 1, flags
+	}
+	// Named groups and other openings up to the end of the name.
+	if end := strings.IndexAny(s, ">'"); end >= 0 && (strings.HasPrefix(s, "(?P<") || strings.HasPrefix(s, "(?<") || strings.HasPrefix(s, "(?'")) {
+		return end + 1, ""
+	}
+	return 2, ""
+}
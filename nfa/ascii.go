@@ -0,0 +1,56 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package nfa
+
+import (
+	"regexp/syntax"
+
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// RuneLastASCII is the last ASCII rune.
+const RuneLastASCII = 0x7f
+
+var (
+	asciiRunes     = []rune{0, RuneLastASCII}
+	printableASCII = []rune{' ', '~'}
+)
+
+// asciiOnly returns a copy of r, which must be normalized, restricted to
+// ASCII strings as described at Options.ASCII.
+func asciiOnly(r *syntax.Regexp) *syntax.Regexp {
+	nr := *r
+	nr.Sub = nil
+	for _, sub := range r.Sub {
+		nr.Sub = append(nr.Sub, asciiOnly(sub))
+	}
+
+	switch r.Op {
+	case syntax.OpLiteral:
+		for _, c := range r.Rune {
+			if c > RuneLastASCII {
+				return &syntax.Regexp{Op: syntax.OpNoMatch}
+			}
+		}
+
+	case syntax.OpCharClass:
+		nr.Rune = runerange.Intersect(r.Rune, asciiRunes)
+
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		nr.Op = syntax.OpCharClass
+		nr.Rune = printableASCII
+	}
+
+	return &nr
+}
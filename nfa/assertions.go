@@ -0,0 +1,176 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package nfa
+
+import "github.com/oulinbao/regexinter/runerange"
+
+// Classes of the positions around an assertion: the beginning of the text,
+// a newline, a word rune, any other rune, the end of the text.
+const (
+	classStart = iota
+	classNewline
+	classWord
+	classOther
+	classEnd
+)
+
+var (
+	newlineRunes = []rune{'\n', '\n'}
+	wordRunes    = []rune{'0', '9', 'A', 'Z', '_', '_', 'a', 'z'}
+	otherRunes   = runerange.Subtract(runerange.Subtract([]rune{0, RuneLast}, newlineRunes), wordRunes)
+)
+
+// runesOf returns the runes of a class of runes.
+func runesOf(class int) []rune {
+	switch class {
+	case classNewline:
+		return newlineRunes
+	case classWord:
+		return wordRunes
+	}
+	return otherRunes
+}
+
+// anyNext is the set of the classes that can follow a position.
+const anyNext = 1<<classNewline | 1<<classWord | 1<<classOther | 1<<classEnd
+
+// nonWordNext is the set of the classes that are not word runes.
+const nonWordNext = 1<<classNewline | 1<<classOther | 1<<classEnd
+
+// ResolveAssertions returns an automaton without pseudo-runes accepting the
+// strings n accepts when the assertions are checked as in Match. Its nodes
+// pair those of n with the class of the previous rune and the set of the
+// classes the next one may have, which the assertions on the way restrict.
+// Lazy transitions become epsilon transitions. n is returned if it has no
+// assertions.
+func ResolveAssertions(n *Node) *Node {
+	resolved, _ := resolveAssertions(n, 0)
+	return resolved
+}
+
+// resolveAssertions is ResolveAssertions giving up, with ok false, once the
+// automaton has more than maxNodes nodes, if maxNodes is positive.
+func resolveAssertions(n *Node, maxNodes int) (resolved *Node, ok bool) {
+	nodes, index := number(n)
+	var found, word, line bool
+	for _, nd := range nodes {
+		for _, t := range nd.T {
+			if !isAssertion(t.R) {
+				continue
+			}
+			switch t.R[0] {
+			case RuneBeginLine:
+				line = true
+			case RuneWordBoundary, RuneNoWordBoundary:
+				word = true
+			}
+			found = true
+		}
+	}
+	if !found {
+		return n, maxNodes <= 0 || len(nodes) <= maxNodes
+	}
+
+	// prev merges the classes of runes the assertions cannot tell apart.
+	prev := func(class int) int {
+		if class == classNewline && line || class == classWord && word {
+			return class
+		}
+		return classOther
+	}
+
+	type key struct {
+		i      int
+		prev   int
+		expect int
+	}
+	ctx := &context{}
+	built := make(map[key]*Node)
+	var queue []key
+	get := func(k key) *Node {
+		nn, ok := built[k]
+		if !ok {
+			nn = ctx.node()
+			nn.F = nodes[k.i].F && k.expect&(1<<classEnd) != 0
+			built[k] = nn
+			queue = append(queue, k)
+		}
+		return nn
+	}
+
+	root := get(key{0, classStart, anyNext})
+	for len(queue) > 0 {
+		if maxNodes > 0 && len(built) > maxNodes {
+			return nil, false
+		}
+		k := queue[0]
+		queue = queue[1:]
+		nn := built[k]
+		for _, t := range nodes[k.i].T {
+			next := key{index[t.N], k.prev, k.expect}
+			if !isAssertion(t.R) {
+				if t.R == nil {
+					nn.T = append(nn.T, T{N: get(next)})
+					continue
+				}
+				// Runes of the classes prev merges lead to the same
+				// node.
+				var allowed [classEnd][]rune
+				for class := classNewline; class < classEnd; class++ {
+					if k.expect&(1<<class) != 0 {
+						p := prev(class)
+						allowed[p] = runerange.Sum(allowed[p], runesOf(class))
+					}
+				}
+				for p, runes := range allowed {
+					if rr := runerange.Intersect(t.R, runes); len(rr) > 0 {
+						nn.T = append(nn.T, T{R: rr, N: get(key{index[t.N], p, anyNext})})
+					}
+				}
+				continue
+			}
+
+			switch t.R[0] {
+			case RuneBeginText:
+				if k.prev != classStart {
+					continue
+				}
+			case RuneBeginLine:
+				if k.prev != classStart && k.prev != classNewline {
+					continue
+				}
+			case RuneEndText:
+				next.expect &= 1 << classEnd
+			case RuneEndLine:
+				next.expect &= 1<<classEnd | 1<<classNewline
+			case RuneWordBoundary:
+				if k.prev == classWord {
+					next.expect &= nonWordNext
+				} else {
+					next.expect &= 1 << classWord
+				}
+			case RuneNoWordBoundary:
+				if k.prev == classWord {
+					next.expect &= 1 << classWord
+				} else {
+					next.expect &= nonWordNext
+				}
+			}
+			if next.expect != 0 {
+				nn.T = append(nn.T, T{N: get(next)})
+			}
+		}
+	}
+	return root, maxNodes <= 0 || len(built) <= maxNodes
+}
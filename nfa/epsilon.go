@@ -0,0 +1,64 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package nfa
+
+// RemoveEpsilon returns an equivalent automaton without epsilon transitions.
+// Each node of the result takes over the transitions of the nodes in the
+// epsilon closure of the corresponding node of n, and is final if any of them
+// is. Only the nodes entered by a transition consuming input are kept.
+// Pseudo-runes consume input in this sense, so assertions are kept.
+func RemoveEpsilon(n *Node) *Node {
+	nodes, index := number(n)
+	ctx := &context{}
+	result := make([]*Node, len(nodes))
+	result[0] = ctx.node()
+	queue := []int{0}
+	var closure []int
+	in := newSparseSet(len(nodes))
+
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		nn := result[i]
+
+		in.clear()
+		in.add(i)
+		closure = append(closure[:0], i)
+		for k := 0; k < len(closure); k++ {
+			for _, t := range nodes[closure[k]].T {
+				if j := index[t.N]; t.R == nil && !in.contains(j) {
+					in.add(j)
+					closure = append(closure, j)
+				}
+			}
+		}
+
+		for _, k := range closure {
+			nn.F = nn.F || nodes[k].F
+			for _, t := range nodes[k].T {
+				if t.R == nil {
+					continue
+				}
+				j := index[t.N]
+				if result[j] == nil {
+					result[j] = ctx.node()
+					queue = append(queue, j)
+				}
+				nn.T = append(nn.T, T{R: t.R, N: result[j]})
+			}
+		}
+	}
+
+	return result[0]
+}
@@ -0,0 +1,428 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package nfa
+
+import (
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// escapeSet is the set of runes matched by an escape sequence package regexp
+// lacks: the runes of ranges or, if negated, all the others.
+type escapeSet struct {
+	ranges  []rune
+	negated bool
+}
+
+// escapeClass is the character class a placeholder group stands for.
+type escapeClass struct {
+	sets    []escapeSet
+	rest    string // the items of a bracketed class left to package regexp
+	negated bool   // whether the bracketed class is negated
+	text    string // the text the placeholder replaces
+}
+
+// placeholderPrefix starts the names of the placeholder groups.
+const placeholderPrefix = "reinterEscape"
+
+// placeholders returns the pattern with the escape sequences translate knows
+// replaced by empty named groups, which package regexp parses, and the
+// classes the groups stand for, which substituteEscapes puts in their place
+// in the parsed tree. A bracketed class holding such escapes is replaced
+// as a whole. The pattern is lexed as package regexp does: quoted text and
+// escaped backslashes have no escapes, and a class runs up to the first ]
+// not right after its opening bracket.
+func placeholders(pattern string, translate func(esc string) (escapeSet, bool)) (string, []escapeClass) {
+	var b strings.Builder
+	var classes []escapeClass
+	placeholder := func(c escapeClass) {
+		b.WriteString("(?P<" + placeholderPrefix + strconv.Itoa(len(classes)) + ">)")
+		classes = append(classes, c)
+	}
+
+	for i := 0; i < len(pattern); {
+		switch c := pattern[i]; {
+		case c == '\\' && strings.HasPrefix(pattern[i:], `\Q`):
+			// Quoted text runs up to \E and has no escapes.
+			end := strings.Index(pattern[i:], `\E`)
+			if end < 0 {
+				end = len(pattern) - i
+			} else {
+				end += 2
+			}
+			b.WriteString(pattern[i : i+end])
+			i += end
+
+		case c == '\\' && i+1 < len(pattern):
+			esc := pattern[i : i+escapeLen(pattern[i:])]
+			if set, ok := translate(esc); ok {
+				placeholder(escapeClass{sets: []escapeSet{set}, text: esc})
+			} else {
+				b.WriteString(esc)
+			}
+			i += len(esc)
+
+		case c == '[':
+			n, class, ok := lexClass(pattern[i:], translate)
+			if ok {
+				class.text = pattern[i : i+n]
+				placeholder(class)
+			} else {
+				b.WriteString(pattern[i : i+n])
+			}
+			i += n
+
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String(), classes
+}
+
+// lexClass lexes the bracketed class at the beginning of s and returns its
+// length. If it holds escapes translate knows, it also returns the class
+// they stand for with the other items. An escape at an end of a range
+// stands for a single rune or is left to package regexp, which rejects it.
+func lexClass(s string, translate func(esc string) (escapeSet, bool)) (n int, class escapeClass, ok bool) {
+	i := 1
+	if i < len(s) && s[i] == '^' {
+		class.negated = true
+		i++
+	}
+	// The items, a ] right after the opening bracket being a literal.
+	var items []string
+	for first := true; i < len(s) && (first || s[i] != ']'); first = false {
+		n := 1
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			n = escapeLen(s[i:])
+		case strings.HasPrefix(s[i:], "[:"):
+			if end := strings.Index(s[i:], ":]"); end >= 0 {
+				n = end + 2
+			}
+		default:
+			_, n = utf8.DecodeRuneInString(s[i:])
+		}
+		items = append(items, s[i:i+n])
+		i += n
+	}
+	if i == len(s) {
+		// Unterminated, package regexp reports it.
+		return i, class, false
+	}
+
+	var rest strings.Builder
+	for k := 0; k < len(items); k++ {
+		if k+2 < len(items) && items[k+1] == "-" {
+			lo, okLo := classRune(items[k], translate)
+			hi, okHi := classRune(items[k+2], translate)
+			_, escLo := translate(items[k])
+			_, escHi := translate(items[k+2])
+			if (escLo || escHi) && okLo && okHi && lo <= hi {
+				class.sets = append(class.sets, escapeSet{ranges: []rune{lo, hi}})
+			} else {
+				rest.WriteString(items[k] + "-" + items[k+2])
+			}
+			k += 2
+			continue
+		}
+		if set, ok := translate(items[k]); ok {
+			class.sets = append(class.sets, set)
+			continue
+		}
+		item := items[k]
+		if rest.Len() == 0 && item == "^" {
+			item = `\^`
+		}
+		rest.WriteString(item)
+	}
+	class.rest = rest.String()
+	return i + 1, class, len(class.sets) > 0
+}
+
+// classRune returns the rune an item of a bracketed class stands for, if it
+// is a single one.
+func classRune(item string, translate func(esc string) (escapeSet, bool)) (rune, bool) {
+	if set, ok := translate(item); ok {
+		if set.negated || len(set.ranges) != 2 || set.ranges[0] != set.ranges[1] {
+			return 0, false
+		}
+		return set.ranges[0], true
+	}
+	r, err := syntax.Parse("["+item+"]", syntax.Perl)
+	if err != nil || r.Op != syntax.OpLiteral || len(r.Rune) != 1 {
+		return 0, false
+	}
+	return r.Rune[0], true
+}
+
+// restoreEscapes puts the text of the placeholders back in the syntax errors
+// of package regexp.
+func restoreEscapes(err error, classes []escapeClass) error {
+	serr, ok := err.(*syntax.Error)
+	if !ok || !strings.Contains(serr.Expr, placeholderPrefix) {
+		return err
+	}
+	expr := serr.Expr
+	for i, c := range classes {
+		expr = strings.ReplaceAll(expr, "(?P<"+placeholderPrefix+strconv.Itoa(i)+">)", c.text)
+	}
+	return &syntax.Error{Code: serr.Code, Expr: expr}
+}
+
+// substituteEscapes replaces the placeholder groups of the parsed tree with
+// the classes they stand for. Under the FoldCase flag the runes of the
+// escapes are folded before being negated, as package regexp does.
+func substituteEscapes(r *syntax.Regexp, classes []escapeClass) (*syntax.Regexp, error) {
+	if r.Op == syntax.OpCapture && strings.HasPrefix(r.Name, placeholderPrefix) {
+		i, err := strconv.Atoi(strings.TrimPrefix(r.Name, placeholderPrefix))
+		if err == nil && i < len(classes) {
+			return escapeRegexp(classes[i], r.Flags)
+		}
+	}
+	for i, sub := range r.Sub {
+		sub, err := substituteEscapes(sub, classes)
+		if err != nil {
+			return nil, err
+		}
+		r.Sub[i] = sub
+	}
+	return r, nil
+}
+
+// escapeRegexp returns the character class of c under the flags.
+func escapeRegexp(c escapeClass, flags syntax.Flags) (*syntax.Regexp, error) {
+	fold := flags&syntax.FoldCase != 0
+	var ranges []rune
+	for _, set := range c.sets {
+		rr := set.ranges
+		if fold {
+			rr = runerange.Fold(rr)
+		}
+		if set.negated {
+			rr = runerange.Negate(rr)
+		}
+		ranges = runerange.Sum(ranges, rr)
+	}
+	if c.rest != "" {
+		rest, err := syntax.Parse("["+c.rest+"]", syntax.Perl|flags&syntax.FoldCase)
+		if err != nil {
+			return nil, err
+		}
+		switch rest.Op {
+		case syntax.OpCharClass:
+			ranges = runerange.Sum(ranges, rest.Rune)
+		case syntax.OpLiteral:
+			var rr []rune
+			for _, r := range rest.Rune {
+				rr = runerange.Add(rr, r)
+			}
+			if rest.Flags&syntax.FoldCase != 0 {
+				rr = runerange.Fold(rr)
+			}
+			ranges = runerange.Sum(ranges, rr)
+		}
+	}
+	if c.negated {
+		ranges = runerange.Negate(ranges)
+	}
+	if len(ranges) == 0 {
+		return &syntax.Regexp{Op: syntax.OpNoMatch, Flags: flags}, nil
+	}
+	return &syntax.Regexp{Op: syntax.OpCharClass, Rune: ranges, Flags: flags}, nil
+}
+
+// escapeLen returns the length of the escape sequence at the beginning of s.
+func escapeLen(s string) int {
+	if len(s) < 3 || !strings.ContainsRune("pPxo", rune(s[1])) {
+		return 2
+	}
+	if s[2] == '{' {
+		if end := strings.IndexByte(s, '}'); end >= 0 {
+			return end + 1
+		}
+		return len(s)
+	}
+	switch s[1] {
+	case 'x':
+		// Up to two hex digits.
+		n := 2
+		for n < len(s) && n < 4 && strings.IndexByte(hexDigits, s[n]) >= 0 {
+			n++
+		}
+		return n
+	case 'o':
+		return 2
+	}
+	return 3
+}
+
+const hexDigits = "0123456789abcdefABCDEF"
+
+// numericEscape translates the code point escapes of PCRE that package
+// regexp lacks: \o{...} in octal and \x followed by a single hex digit.
+func numericEscape(esc string) (escapeSet, bool) {
+	switch {
+	case strings.HasPrefix(esc, `\o{`) && strings.HasSuffix(esc, "}"):
+		n, err := strconv.ParseUint(esc[3:len(esc)-1], 8, 32)
+		if err != nil || n > unicode.MaxRune {
+			return escapeSet{}, false
+		}
+		return escapeSet{ranges: []rune{rune(n), rune(n)}}, true
+	case len(esc) == 3 && esc[1] == 'x' && strings.IndexByte(hexDigits, esc[2]) >= 0:
+		n, _ := strconv.ParseUint(esc[2:], 16, 8)
+		return escapeSet{ranges: []rune{rune(n), rune(n)}}, true
+	}
+	return escapeSet{}, false
+}
+
+// blockAliases maps the short names of some Unicode blocks, in the form
+// matched by looseName, to their full names.
+var blockAliases = map[string]string{
+	"ascii":        "basiclatin",
+	"latin1":       "latin1supplement",
+	"latin1sup":    "latin1supplement",
+	"greek":        "greekandcoptic",
+	"diacriticals": "combiningdiacriticalmarks",
+	"cjk":          "cjkunifiedideographs",
+	"pua":          "privateusearea",
+	"privateuse":   "privateusearea",
+}
+
+// unicodeProperty translates the \p{...} and \P{...} escapes package regexp
+// does not know: the Unicode blocks such as \p{InGreek} or \p{Block=Arrows},
+// the Is prefix of Java such as \p{IsLatin}, the Script= and
+// General_Category= forms and names differing from the ones of package
+// unicode in case, spaces, hyphens or underscores, such as \p{old italic}.
+func unicodeProperty(esc string) (escapeSet, bool) {
+	if len(esc) < 4 || esc[1] != 'p' && esc[1] != 'P' || esc[2] != '{' || !strings.HasSuffix(esc, "}") {
+		return escapeSet{}, false
+	}
+	negated := esc[1] == 'P'
+	name := esc[3 : len(esc)-1]
+	if strings.HasPrefix(name, "^") {
+		negated = !negated
+		name = name[1:]
+	}
+	if name == "Any" || unicode.Categories[name] != nil || unicode.Scripts[name] != nil {
+		return escapeSet{}, false
+	}
+
+	key := looseName(name)
+	for _, prefix := range []string{"in", "block=", "blk="} {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if lo, hi, ok := block(strings.TrimPrefix(key, prefix)); ok {
+			return escapeSet{ranges: []rune{lo, hi}, negated: negated}, true
+		}
+	}
+	for _, prefix := range []string{"", "is", "script=", "sc=", "generalcategory=", "gc="} {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if table, ok := property(strings.TrimPrefix(key, prefix)); ok {
+			return escapeSet{ranges: tableRanges(table), negated: negated}, true
+		}
+	}
+	return escapeSet{}, false
+}
+
+// looseName returns the name in lowercase without spaces, hyphens and
+// underscores, which Unicode property names are compared without.
+func looseName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '_':
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, name)
+}
+
+// block returns the runes of the block whose loose name is given.
+func block(key string) (lo, hi rune, ok bool) {
+	if full, ok := blockAliases[key]; ok {
+		key = full
+	}
+	for _, b := range blocks {
+		if looseName(b.name) == key {
+			return b.lo, b.hi, true
+		}
+	}
+	return 0, 0, false
+}
+
+// property returns the table of the script or category whose loose name is
+// given.
+func property(key string) (*unicode.RangeTable, bool) {
+	for _, tables := range []map[string]*unicode.RangeTable{unicode.Scripts, unicode.Categories} {
+		for name, table := range tables {
+			if looseName(name) == key {
+				return table, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// tableRanges returns the runes of the tables as a range.
+func tableRanges(tables ...*unicode.RangeTable) []rune {
+	var ranges []rune
+	for _, t := range tables {
+		for _, r := range t.R16 {
+			ranges = runerange.Sum(ranges, strideRanges(rune(r.Lo), rune(r.Hi), rune(r.Stride)))
+		}
+		for _, r := range t.R32 {
+			ranges = runerange.Sum(ranges, strideRanges(rune(r.Lo), rune(r.Hi), rune(r.Stride)))
+		}
+	}
+	return ranges
+}
+
+func strideRanges(lo, hi, stride rune) []rune {
+	if stride == 1 {
+		return []rune{lo, hi}
+	}
+	var ranges []rune
+	for r := lo; r <= hi; r += stride {
+		ranges = append(ranges, r, r)
+	}
+	return ranges
+}
+
+// unicodePerlClass returns the Unicode version of a Perl class escape:
+// \d matches decimal digits, \s white space and \w letters, marks, numbers
+// and connector punctuation such as '_', following Unicode Technical
+// Standard #18.
+func unicodePerlClass(esc string) (escapeSet, bool) {
+	var ranges []rune
+	switch esc {
+	case `\d`, `\D`:
+		ranges = tableRanges(unicode.Nd)
+	case `\s`, `\S`:
+		ranges = tableRanges(unicode.White_Space)
+	case `\w`, `\W`:
+		ranges = tableRanges(unicode.L, unicode.M, unicode.N, unicode.Pc)
+	default:
+		return escapeSet{}, false
+	}
+	return escapeSet{ranges: ranges, negated: esc[1] >= 'A' && esc[1] <= 'Z'}, true
+}
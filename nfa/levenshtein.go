@@ -0,0 +1,63 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package nfa
+
+import "github.com/oulinbao/regexinter/runerange"
+
+// NewLevenshtein returns an automaton accepting every string within
+// Levenshtein distance k of word, that is every string obtained from word by
+// at most k single rune insertions, deletions or substitutions. A negative k
+// is treated as zero.
+//
+// The automaton has a state for each pair of a position in word and a number
+// of edits spent so far. Insertions and substitutions consume any rune while
+// deletions are epsilon transitions.
+func NewLevenshtein(word string, k int) *Node {
+	if k < 0 {
+		k = 0
+	}
+
+	w := []rune(word)
+	ctx := &context{}
+	nodes := make([][]*Node, len(w)+1)
+	for i := range nodes {
+		nodes[i] = make([]*Node, k+1)
+		for e := range nodes[i] {
+			nodes[i][e] = ctx.node()
+		}
+	}
+
+	anyRune := runerange.Valid([]rune{0, RuneLast})
+	for i, row := range nodes {
+		for e, n := range row {
+			n.F = i == len(w)
+			if i < len(w) {
+				n.T = append(n.T, T{R: []rune{w[i], w[i]}, N: nodes[i+1][e]})
+			}
+			if e == k {
+				continue
+			}
+			// insertion
+			n.T = append(n.T, T{R: anyRune, N: nodes[i][e+1]})
+			if i < len(w) {
+				// substitution
+				n.T = append(n.T, T{R: anyRune, N: nodes[i+1][e+1]})
+				// deletion
+				n.T = append(n.T, T{N: nodes[i+1][e+1]})
+			}
+		}
+	}
+
+	return nodes[0][0]
+}
@@ -0,0 +1,238 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package nfa
+
+import (
+	"regexp/syntax"
+	"strconv"
+
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// ErrLimitExceeded is returned for patterns exceeding the MaxNFANodes,
+// MaxRepeat or MaxNesting options.
+type ErrLimitExceeded struct {
+	Limit string // name of the option
+	Max   int    // its value
+}
+
+func (e *ErrLimitExceeded) Error() string {
+	return "pattern exceeds " + e.Limit + " of " + strconv.Itoa(e.Max)
+}
+
+// checkLimits checks the MaxRepeat and MaxNesting options on the tree parsed
+// from a pattern, before repetitions are expanded. The tree is at the given
+// depth and repeated copies times.
+func checkLimits(r *syntax.Regexp, opts Options, depth, copies int) error {
+	if opts.MaxNesting > 0 && depth > opts.MaxNesting {
+		return &ErrLimitExceeded{Limit: "MaxNesting", Max: opts.MaxNesting}
+	}
+	if r.Op == syntax.OpRepeat {
+		n := r.Max
+		if n < 0 {
+			n = r.Min
+		}
+		copies *= max(n, 1)
+		if opts.MaxRepeat > 0 && copies > opts.MaxRepeat {
+			return &ErrLimitExceeded{Limit: "MaxRepeat", Max: opts.MaxRepeat}
+		}
+	}
+	for _, sub := range r.Sub {
+		if err := checkLimits(sub, opts, depth+1, copies); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Size returns the number of nodes of the automaton NewFromRegexp builds
+// from r before resolving its assertions, which is how
+// NewFromRegexpWithOptions checks the MaxNFANodes option before allocating
+// any.
+func Size(r *syntax.Regexp) int {
+	switch r.Op {
+	case syntax.OpEmptyMatch:
+		return 1
+	case syntax.OpLiteral:
+		return 1 + len(r.Rune)
+	case syntax.OpCapture:
+		return Size(r.Sub[0])
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		// The copies of OpRepeat share the nodes of their subexpression.
+		return 2 + Size(r.Sub[0])
+	case syntax.OpConcat, syntax.OpAlternate:
+		n := 0
+		for _, sub := range r.Sub {
+			n += Size(sub)
+		}
+		if r.Op == syntax.OpAlternate {
+			n += 2
+		}
+		return n
+	}
+	return 2
+}
+
+// EstimateDFAStates estimates from r, as returned by Parse, the number of
+// states of the DFA of its automaton without building either. DFAs are about
+// as large as the NFAs of most patterns, but grow exponentially with the
+// repetitions a loop can start matching at every rune, such as the [ab]{12}
+// of [ab]*a[ab]{12}: the estimate is the size of the NFA plus 2 to the
+// number of such positions.
+func EstimateDFAStates(r *syntax.Regexp) int {
+	e := &estimator{}
+	e.walk(r, run{})
+	if e.exp >= strconv.IntSize-2 {
+		return int(^uint(0) >> 1)
+	}
+	return Size(r) + 1<<e.exp
+}
+
+// estimator walks a syntax tree for EstimateDFAStates.
+type estimator struct {
+	exp int // largest exponent of the runs
+}
+
+// run is a sequence of positions a loop before them can start matching at
+// every rune, so that as many matches may be under way as positions.
+type run struct {
+	live  []rune // runes the loop reads, nil if there is no loop
+	first []rune // runes of the first position
+	mixed bool   // the positions do not all have the runes of the first
+	wide  int    // number of positions with more than one rune
+}
+
+func (e *estimator) walk(r *syntax.Regexp, s run) run {
+	fold := func(rr []rune) []rune {
+		if r.Flags&syntax.FoldCase != 0 {
+			return runerange.Fold(rr)
+		}
+		return rr
+	}
+	switch r.Op {
+	case syntax.OpLiteral:
+		for _, c := range r.Rune {
+			s = e.position(s, fold([]rune{c, c}))
+		}
+	case syntax.OpCharClass:
+		s = e.position(s, fold(r.Rune))
+	case syntax.OpAnyCharNotNL:
+		s = e.position(s, []rune{0, 9, 11, RuneLast})
+	case syntax.OpAnyChar:
+		s = e.position(s, []rune{0, RuneLast})
+	case syntax.OpCapture:
+		s = e.walk(r.Sub[0], s)
+	case syntax.OpConcat:
+		for _, sub := range r.Sub {
+			s = e.walk(sub, s)
+		}
+	case syntax.OpAlternate:
+		var merged run
+		for _, sub := range r.Sub {
+			merged = merge(merged, e.walk(sub, s))
+		}
+		s = merged
+	case syntax.OpQuest:
+		s = merge(s, e.walk(r.Sub[0], s))
+	case syntax.OpStar, syntax.OpPlus:
+		var after run
+		if step := singleRunes(r.Sub[0]); step != nil {
+			// The matches under way meet in the loop, which starts
+			// a run of its own.
+			e.walk(r.Sub[0], run{})
+			after = run{live: runerange.Sum(s.live, step)}
+		} else {
+			after = e.walk(r.Sub[0], s)
+		}
+		if r.Op == syntax.OpStar {
+			after = merge(after, s)
+		}
+		s = after
+	}
+	return s
+}
+
+// position returns the run s continued with a position matching rr.
+func (e *estimator) position(s run, rr []rune) run {
+	if len(runerange.Intersect(s.live, rr)) == 0 {
+		return run{}
+	}
+	if s.first == nil {
+		s.first = rr
+	} else if !runerange.Contains(s.first, rr) || !runerange.Contains(rr, s.first) {
+		s.mixed = true
+	}
+	if len(rr) > 2 || rr[0] != rr[1] {
+		s.wide++
+	}
+	// Matches under way in runs of positions of the same runes only
+	// differ in how far they got, which does not multiply the states.
+	if s.mixed && s.wide > 0 {
+		e.exp = max(e.exp, s.wide+1)
+	}
+	return s
+}
+
+// merge returns a run continuing both a and b.
+func merge(a, b run) run {
+	if a.live == nil {
+		return b
+	}
+	if b.live == nil {
+		return a
+	}
+	return run{
+		live:  runerange.Sum(a.live, b.live),
+		first: a.first,
+		mixed: a.mixed || b.mixed || a.first != nil && b.first != nil && !runerange.Contains(a.first, b.first),
+		wide:  max(a.wide, b.wide),
+	}
+}
+
+// singleRunes returns the runes r matches as strings of one rune, if it
+// matches strings of one rune only.
+func singleRunes(r *syntax.Regexp) []rune {
+	switch r.Op {
+	case syntax.OpLiteral:
+		if len(r.Rune) == 1 {
+			rr := []rune{r.Rune[0], r.Rune[0]}
+			if r.Flags&syntax.FoldCase != 0 {
+				rr = runerange.Fold(rr)
+			}
+			return rr
+		}
+	case syntax.OpCharClass:
+		if r.Flags&syntax.FoldCase != 0 {
+			return runerange.Fold(r.Rune)
+		}
+		return r.Rune
+	case syntax.OpAnyCharNotNL:
+		return []rune{0, 9, 11, RuneLast}
+	case syntax.OpAnyChar:
+		return []rune{0, RuneLast}
+	case syntax.OpCapture:
+		return singleRunes(r.Sub[0])
+	case syntax.OpAlternate:
+		var rr []rune
+		for _, sub := range r.Sub {
+			step := singleRunes(sub)
+			if step == nil {
+				return nil
+			}
+			rr = runerange.Sum(rr, step)
+		}
+		return rr
+	}
+	return nil
+}
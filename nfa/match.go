@@ -0,0 +1,158 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package nfa
+
+import (
+	"unicode/utf8"
+
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// sparseSet is a set of small integers with constant time insertion, lookup
+// and clearing.
+type sparseSet struct {
+	dense  []int
+	sparse []int
+}
+
+func newSparseSet(size int) *sparseSet {
+	return &sparseSet{
+		dense:  make([]int, 0, size),
+		sparse: make([]int, size),
+	}
+}
+
+func (s *sparseSet) contains(i int) bool {
+	j := s.sparse[i]
+	return j < len(s.dense) && s.dense[j] == i
+}
+
+func (s *sparseSet) add(i int) {
+	s.sparse[i] = len(s.dense)
+	s.dense = append(s.dense, i)
+}
+
+func (s *sparseSet) clear() {
+	s.dense = s.dense[:0]
+}
+
+// number returns the nodes reachable from n. A node's index in the slice is
+// its number.
+func number(n *Node) ([]*Node, map[*Node]int) {
+	nodes := []*Node{n}
+	index := map[*Node]int{n: 0}
+	for i := 0; i < len(nodes); i++ {
+		for _, t := range nodes[i].T {
+			if _, ok := index[t.N]; !ok {
+				index[t.N] = len(nodes)
+				nodes = append(nodes, t.N)
+			}
+		}
+	}
+	return nodes, index
+}
+
+// Match reports whether the automaton accepts s as a whole. It simulates
+// the automaton on the input, following all its transitions at once, so no
+// DFA is built. The pseudo-runes of automata that ResolveAssertions has not
+// been applied to are not matched as input: the assertions they stand for
+// are checked against the surrounding runes instead.
+func Match(n *Node, s string) bool {
+	nodes, index := number(n)
+	cur, next := newSparseSet(len(nodes)), newSparseSet(len(nodes))
+	stack := make([]int, 0, len(nodes))
+	cur.add(0)
+
+	prev := rune(-1)
+	for pos := 0; ; {
+		r, size := rune(-1), 0
+		if pos < len(s) {
+			r, size = utf8.DecodeRuneInString(s[pos:])
+		}
+
+		// Add the nodes reachable by epsilon transitions and assertions
+		// holding between prev and r.
+		stack = append(stack[:0], cur.dense...)
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for _, t := range nodes[i].T {
+				follow := t.R == nil || isAssertion(t.R) && holds(t.R[0], prev, r)
+				j := index[t.N]
+				if !follow || cur.contains(j) {
+					continue
+				}
+				cur.add(j)
+				stack = append(stack, j)
+			}
+		}
+
+		if r < 0 {
+			for _, i := range cur.dense {
+				if nodes[i].F {
+					return true
+				}
+			}
+			return false
+		}
+
+		next.clear()
+		for _, i := range cur.dense {
+			for _, t := range nodes[i].T {
+				if t.R != nil && !isAssertion(t.R) && runerange.In(t.R, r) {
+					if j := index[t.N]; !next.contains(j) {
+						next.add(j)
+					}
+				}
+			}
+		}
+		if len(next.dense) == 0 {
+			return false
+		}
+
+		cur, next = next, cur
+		prev = r
+		pos += size
+	}
+}
+
+func isAssertion(rr []rune) bool {
+	return len(rr) == 2 && rr[0] < 0
+}
+
+// holds reports whether an assertion holds between the runes prev and next,
+// which are -1 at the beginning and end of the text.
+func holds(assertion, prev, next rune) bool {
+	switch assertion {
+	case RuneBeginText:
+		return prev < 0
+	case RuneEndText:
+		return next < 0
+	case RuneBeginLine:
+		return prev < 0 || prev == '\n'
+	case RuneEndLine:
+		return next < 0 || next == '\n'
+	case RuneWordBoundary:
+		return isWordRune(prev) != isWordRune(next)
+	case RuneNoWordBoundary:
+		return isWordRune(prev) == isWordRune(next)
+	case RuneLazy:
+		return true
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '_'
+}
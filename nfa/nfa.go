@@ -24,7 +24,8 @@ const (
 	// Last unicode rune
 	RuneLast = 0x10ffff
 
-	// Pseudo-runes
+	// Pseudo-runes, which only the automata that ResolveAssertions has
+	// not been applied to have
 	RuneBeginText = -100 * iota
 	RuneEndText
 	RuneBeginLine
@@ -47,11 +48,21 @@ type T struct {
 
 type context struct {
 	state int
+	chunk []Node // nodes are allocated from chunks of these
 }
 
+// maxChunk is the number of nodes of the largest chunks.
+const maxChunk = 1024
+
+// node allocates a node. Allocating them in chunks makes patterns of
+// thousands of nodes a few objects for the garbage collector.
 func (c *context) node() *Node {
 	c.state++
-	return &Node{S: c.state}
+	if len(c.chunk) == cap(c.chunk) {
+		c.chunk = make([]Node, 0, min(max(2*cap(c.chunk), 16), maxChunk))
+	}
+	c.chunk = append(c.chunk, Node{S: c.state})
+	return &c.chunk[len(c.chunk)-1]
 }
 
 func (n *Node) copy() *Node {
@@ -64,19 +75,130 @@ func (n *Node) copy() *Node {
 	return &nn
 }
 
+// Options control how patterns are translated into automata.
+type Options struct {
+	// UnicodeClasses makes the Perl classes \d, \s and \w and their
+	// negations match Unicode digits, white space and word characters. By
+	// default they match ASCII characters only, as in RE2.
+	UnicodeClasses bool
+
+	// FoldCase makes the pattern case insensitive as if it started with (?i).
+	FoldCase bool
+
+	// Approximate rewrites the constructs reported by FindUnsupported so
+	// that the automaton accepts a superset of the strings the pattern
+	// matches, instead of failing to parse them.
+	Approximate bool
+
+	// Prefix makes the automaton accept every string starting with a match
+	// of the pattern, as if (?s:.*) were appended to it.
+	Prefix bool
+
+	// Substring makes the automaton accept every string containing a match
+	// of the pattern, as if it were enclosed in (?s:.*)(?:...)(?s:.*).
+	Substring bool
+
+	// ASCII restricts the automaton to ASCII strings: character classes
+	// lose their other runes, literals with non-ASCII runes match nothing
+	// and . matches printable ASCII characters only.
+	ASCII bool
+
+	// MaxNFANodes, MaxRepeat and MaxNesting bound the size of the automata of
+	// patterns from untrusted sources, which then fail to parse with an
+	// *ErrLimitExceeded. Zero values mean no limit.
+	MaxNFANodes int // nodes of the automaton
+	MaxRepeat   int // copies counted repetitions expand to, nested ones multiplied
+	MaxNesting  int // nesting depth of the syntax tree
+}
+
 func New(pattern string) (*Node, error) {
-	r, err := syntax.Parse(pattern, syntax.Perl)
+	return NewWithOptions(pattern, Options{})
+}
+
+func NewWithOptions(pattern string, opts Options) (*Node, error) {
+	r, err := Parse(pattern, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFromRegexpWithOptions(r, opts)
+}
+
+// Parse returns the simplified syntax tree NewWithOptions builds the
+// automaton of the pattern from. Besides the syntax of package regexp, it
+// accepts the PCRE escapes \o{101} in octal and \x9 with a single hex digit,
+// Unicode blocks such as \p{InGreek} and the spellings of scripts and
+// categories of other engines such as \p{IsLatin} or \p{Script=Latin}.
+func Parse(pattern string, opts Options) (*syntax.Regexp, error) {
+	if opts.Approximate {
+		pattern, _ = approximate(pattern)
+	}
+	pattern, escapes := placeholders(pattern, func(esc string) (escapeSet, bool) {
+		if set, ok := numericEscape(esc); ok {
+			return set, true
+		}
+		if set, ok := unicodeProperty(esc); ok {
+			return set, true
+		}
+		if opts.UnicodeClasses {
+			return unicodePerlClass(esc)
+		}
+		return escapeSet{}, false
+	})
+
+	flags := syntax.Perl
+	if opts.FoldCase {
+		flags |= syntax.FoldCase
+	}
+
+	r, err := syntax.Parse(pattern, flags)
 	if err != nil {
+		return nil, restoreEscapes(err, escapes)
+	}
+	if r, err = substituteEscapes(r, escapes); err != nil {
+		return nil, err
+	}
+	if err := checkLimits(r, opts, 1, 1); err != nil {
 		return nil, err
 	}
 
-	return NewFromRegexp(r.Simplify()), nil
+	r = r.Simplify()
+	anyString := func() *syntax.Regexp {
+		return &syntax.Regexp{Op: syntax.OpStar, Sub: []*syntax.Regexp{{Op: syntax.OpAnyChar}}}
+	}
+	if opts.Substring {
+		r = &syntax.Regexp{Op: syntax.OpConcat, Sub: []*syntax.Regexp{anyString(), r, anyString()}}
+	} else if opts.Prefix {
+		r = &syntax.Regexp{Op: syntax.OpConcat, Sub: []*syntax.Regexp{r, anyString()}}
+	}
+	if opts.ASCII {
+		r = asciiOnly(Normalize(r))
+	}
+	return r, nil
 }
 
+// NewFromRegexp returns the automaton of r. Its assertions are resolved by
+// ResolveAssertions, so that it has no pseudo-runes.
 func NewFromRegexp(r *syntax.Regexp) *Node {
+	n, _ := NewFromRegexpWithOptions(r, Options{})
+	return n
+}
+
+// NewFromRegexpWithOptions is like NewFromRegexp but fails with an
+// *ErrLimitExceeded if the automaton would have more than opts.MaxNFANodes
+// nodes, before or after resolving its assertions. The first is checked
+// without building anything, the second while resolving them.
+func NewFromRegexpWithOptions(r *syntax.Regexp, opts Options) (*Node, error) {
+	if opts.MaxNFANodes > 0 && Size(r) > opts.MaxNFANodes {
+		return nil, &ErrLimitExceeded{Limit: "MaxNFANodes", Max: opts.MaxNFANodes}
+	}
 	begin, end := recursiveNewFromRegexp(r, &context{})
 	end.F = true
-	return begin
+	n, ok := resolveAssertions(begin, opts.MaxNFANodes)
+	if !ok {
+		return nil, &ErrLimitExceeded{Limit: "MaxNFANodes", Max: opts.MaxNFANodes}
+	}
+	return n, nil
 }
 
 func opString(op syntax.Op) string {
@@ -128,6 +250,10 @@ func recursiveNewFromRegexp(r *syntax.Regexp, ctx *context) (begin *Node, end *N
 	nonGreedy := r.Flags&syntax.NonGreedy != 0
 
 	switch r.Op {
+	case syntax.OpNoMatch:
+		begin = ctx.node()
+		end = ctx.node()
+
 	case syntax.OpEmptyMatch:
 		begin = ctx.node()
 		end = begin
@@ -137,10 +263,13 @@ func recursiveNewFromRegexp(r *syntax.Regexp, ctx *context) (begin *Node, end *N
 		cur := begin
 		for _, r := range r.Rune {
 			end = ctx.node()
+			rr := []rune{r, r}
 			if caseInsensitive {
-				cur.T = append(cur.T, T{R: runerange.Fold([]rune{r, r}), N: end})
-			} else {
-				cur.T = append(cur.T, T{R: []rune{r, r}, N: end})
+				rr = runerange.Fold(rr)
+			}
+			// A surrogate half never matches, as in package regexp.
+			if rr = runerange.Valid(rr); len(rr) > 0 {
+				cur.T = append(cur.T, T{R: rr, N: end})
 			}
 			cur = end
 		}
@@ -148,21 +277,29 @@ func recursiveNewFromRegexp(r *syntax.Regexp, ctx *context) (begin *Node, end *N
 	case syntax.OpCharClass:
 		begin = ctx.node()
 		end = ctx.node()
+		rr := r.Rune
 		if caseInsensitive {
-			begin.T = append(begin.T, T{R: runerange.Fold(r.Rune), N: end})
-		} else {
-			begin.T = append(begin.T, T{R: r.Rune, N: end})
+			rr = runerange.Fold(rr)
+		}
+		// Negated classes include the surrogate halves, which package
+		// regexp never matches.
+		rr = runerange.Valid(rr)
+		if len(rr) == 0 {
+			// An empty class matches nothing; a transition without
+			// runes would be taken for an epsilon transition.
+			break
 		}
+		begin.T = append(begin.T, T{R: rr, N: end})
 
 	case syntax.OpAnyCharNotNL:
 		begin = ctx.node()
 		end = ctx.node()
-		begin.T = append(begin.T, T{R: []rune{0, 9, 11, RuneLast}, N: end})
+		begin.T = append(begin.T, T{R: runerange.Valid([]rune{0, 9, 11, RuneLast}), N: end})
 
 	case syntax.OpAnyChar:
 		begin = ctx.node()
 		end = ctx.node()
-		begin.T = append(begin.T, T{R: []rune{0, RuneLast}, N: end})
+		begin.T = append(begin.T, T{R: runerange.Valid([]rune{0, RuneLast}), N: end})
 
 	case syntax.OpBeginLine:
 		begin = ctx.node()
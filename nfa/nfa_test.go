@@ -0,0 +1,564 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package nfa_test
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/nfa"
+)
+
+func accepts(n *dfa.Node, s string) bool {
+	for _, r := range s {
+		if n = n.NextState([]rune{r, r}); n == nil {
+			return false
+		}
+	}
+	return n.Final
+}
+
+func TestLevenshtein(t *testing.T) {
+	type testCase struct {
+		word string
+		k    int
+		in   string
+		want bool
+	}
+	testCases := []testCase{
+		{"kitten", 0, "kitten", true},
+		{"kitten", 0, "kittens", false},
+		{"kitten", 1, "sitten", true},
+		{"kitten", 1, "sittin", false},
+		{"kitten", 2, "sittin", true},
+		{"kitten", 3, "sitting", true},
+		{"kitten", 2, "sitting", false},
+		{"kitten", 1, "kiten", true},
+		{"kitten", 1, "kittten", true},
+		{"", 1, "", true},
+		{"", 1, "x", true},
+		{"", 1, "xy", false},
+		{"ab", 2, "", true},
+		{"ab", -1, "ab", true},
+	}
+	for _, tc := range testCases {
+		n := dfa.NewFromNFA(nfa.NewLevenshtein(tc.word, tc.k))
+		if got := accepts(n, tc.in); got != tc.want {
+			t.Errorf("NewLevenshtein(%q, %d) accepts %q = %v, want %v", tc.word, tc.k, tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestNumericEscapes(t *testing.T) {
+	type testCase struct {
+		expr string
+		in   string
+		want bool
+	}
+	testCases := []testCase{
+		{`\x41`, "A", true},
+		{`\x{1F600}`, "😀", true},
+		{`[\x41-\x43]+`, "ABC", true},
+		{`[\x41-\x43]`, "D", false},
+		{`\x9`, "\t", true},
+		{`\x9A`, "\u009a", true},
+		{`[\x9]`, "\t", true},
+		{`\101`, "A", true},
+		{`\0`, "\x00", true},
+		{`\012`, "\n", true},
+		{`[\101-\103]`, "B", true},
+		{`\o{101}`, "A", true},
+		{`[\o{60}-\o{71}]+`, "2024", true},
+		{`\o{101}`, "B", false},
+		{`\\o{2}`, `\oo`, true},
+		{`\Q\x9\E`, `\x9`, true},
+		{`\\x9`, `\x9`, true},
+		{`[\\]\x9]`, "\\\t]", true},
+		{`\Q[\E\x9]`, "[\t]", true},
+		{`[\x9-\x{B}]`, "\n", true},
+		{`[\x9-\x{B}]`, "\f", false},
+		{`[^\o{101}b]`, "A", false},
+		{`[^\o{101}b]`, "c", true},
+		{`(?i)\o{101}+`, "aA", true},
+		{`(?i)[^\o{101}]`, "a", false},
+		{`\x9{2}`, "\t\t", true},
+	}
+	for _, tc := range testCases {
+		n, err := nfa.New(tc.expr)
+		if err != nil {
+			t.Errorf("New(%q) error: %v", tc.expr, err)
+			continue
+		}
+		if got := accepts(dfa.NewFromNFA(n), tc.in); got != tc.want {
+			t.Errorf("New(%q) accepts %q = %v, want %v", tc.expr, tc.in, got, tc.want)
+		}
+	}
+
+	for _, expr := range []string{`\o{8}`, `\o{}`, `\o`, `\x`, `\o{7777777777}`, `[\x9`, `[\x9-\x8]`} {
+		if _, err := nfa.New(expr); err == nil {
+			t.Errorf("New(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestUnicodeProperties(t *testing.T) {
+	type testCase struct {
+		expr string
+		in   string
+		want bool
+	}
+	testCases := []testCase{
+		{`\p{Greek}+`, "αβγ", true},
+		{`\p{Han}+`, "漢字", true},
+		{`\p{Han}`, "a", false},
+		{`\p{InGreek}+`, "αϴ", true},
+		{`\p{InGreek_and_Coptic}`, "α", true},
+		{`\p{InGreek}`, "ἀ", false},
+		{`\p{InBasicLatin}+`, "az", true},
+		{`\p{In Basic Latin}`, "é", false},
+		{`\p{Block=Arrows}`, "→", true},
+		{`\p{blk=CJK}`, "漢", true},
+		{`\P{InBasicLatin}`, "é", true},
+		{`\P{InBasicLatin}`, "e", false},
+		{`\p{^InBasicLatin}`, "é", true},
+		{`[\p{InGreek}a]+`, "aα", true},
+		{`[^\p{InGreek}]`, "α", false},
+		{`[\P{InGreek}]`, "a", true},
+		{`\p{IsLatin}+`, "éa", true},
+		{`\p{Script=Cyrillic}`, "ж", true},
+		{`\p{sc=Cyrillic}`, "a", false},
+		{`\p{old_italic}`, "𐌀", true},
+		{`\P{IsLatin}`, "a", false},
+		{`\p{gc=Lu}`, "A", true},
+		{`\p{IsLu}`, "a", false},
+		{`\p{Inherited}`, "\u0300", true},
+		{`\\p{InGreek}`, `\p{InGreek}`, true},
+		{`[^\p{InGreek}a]`, "b", true},
+		{`[^\p{InGreek}a]`, "a", false},
+		{`[^\p{InGreek}a]`, "α", false},
+		{`[\p{InGreek}-]+`, "-α", true},
+		{`[]\p{InGreek}]+`, "]α", true},
+		{`[\p{InGreek}^]`, "^", true},
+		{`[\p{InGreek}[:digit:]]+`, "α1", true},
+		{`\p{InGreek}{2}`, "αβ", true},
+		{`(?i)\p{IsLu}`, "a", true},
+		{`(?i)\P{IsLu}`, "a", false},
+		{`\Q\p{InGreek}\E`, `\p{InGreek}`, true},
+	}
+	for _, tc := range testCases {
+		n, err := nfa.New(tc.expr)
+		if err != nil {
+			t.Errorf("New(%q) error: %v", tc.expr, err)
+			continue
+		}
+		if got := accepts(dfa.NewFromNFA(n), tc.in); got != tc.want {
+			t.Errorf("New(%q) accepts %q = %v, want %v", tc.expr, tc.in, got, tc.want)
+		}
+	}
+
+	for _, expr := range []string{`\p{InKlingon}`, `\p{IsKlingon}`, `\p{Block=Latin}`, `[\p{InGreek}-z]`} {
+		if _, err := nfa.New(expr); err == nil {
+			t.Errorf("New(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestASCII(t *testing.T) {
+	type testCase struct {
+		expr string
+		in   string
+		want bool
+	}
+	testCases := []testCase{
+		{`[^a]`, "b", true},
+		{`[^a]`, "é", false},
+		{`\p{L}+`, "abc", true},
+		{`\p{L}`, "é", false},
+		{`.`, "~", true},
+		{`.`, "\x01", false},
+		{`(?s:.)`, "\n", false},
+		{`é|e`, "e", true},
+		{`é|e`, "é", false},
+		{`(?i)k`, "\u212a", false},
+		{`(?i)k`, "K", true},
+	}
+	for _, tc := range testCases {
+		n, err := nfa.NewWithOptions(tc.expr, nfa.Options{ASCII: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := accepts(dfa.NewFromNFA(n), tc.in); got != tc.want {
+			t.Errorf("NewWithOptions(%q, ASCII) accepts %q = %v, want %v", tc.expr, tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestUnicodeClasses(t *testing.T) {
+	type testCase struct {
+		expr    string
+		in      string
+		ascii   bool // default
+		unicode bool // with Options.UnicodeClasses
+	}
+	testCases := []testCase{
+		{`\d+`, "123", true, true},
+		{`\d+`, "١٢٣", false, true},
+		{`\D`, "١", true, false},
+		{`\w+`, "héllo_wörld", false, true},
+		{`[\w.-]+`, "日本.go", false, true},
+		{`[^\w]`, "é", true, false},
+		{`[\W]`, "é", true, false},
+		{`[\W]`, "!", true, true},
+		{`\s`, "　", false, true},
+		{`\S`, "　", true, false},
+		{`\\d`, `\d`, true, true},
+		{`\Q\d\E`, `\d`, true, true},
+		{`[\]\d]+`, "]١", false, true},
+		{`[^\d\s]`, "١", true, false},
+		{`[^\d\s]`, "a", true, true},
+		{`[\\d]`, "١", false, false},
+	}
+	for _, tc := range testCases {
+		n, err := nfa.New(tc.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := accepts(dfa.NewFromNFA(n), tc.in); got != tc.ascii {
+			t.Errorf("New(%q) accepts %q = %v, want %v", tc.expr, tc.in, got, tc.ascii)
+		}
+
+		n, err = nfa.NewWithOptions(tc.expr, nfa.Options{UnicodeClasses: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := accepts(dfa.NewFromNFA(n), tc.in); got != tc.unicode {
+			t.Errorf("NewWithOptions(%q, UnicodeClasses) accepts %q = %v, want %v", tc.expr, tc.in, got, tc.unicode)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	exprs := []string{
+		"", "a", "a*", "(a|b)*abb", "[a-z]+@[a-z]+\\.com", "^abc$", `\bfoo\b.*`, `.*\Bo\B.*`,
+		"(?m)a$\n^b", "a*?b", "(a*)*", "x\\d{2,3}", "(?i)hello",
+	}
+	samples := []string{
+		"", "a", "aa", "abb", "aabb", "babb", "ab", "me@example.com", "abc", "foo", "foo bar", "foobar",
+		"boo", "o", "a\nb", "aab", "b", "x12", "x1234", "HeLLo",
+	}
+	for _, e := range exprs {
+		n, err := nfa.New(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		re := regexp.MustCompile("^(?:" + e + ")$")
+		for _, s := range samples {
+			if got, want := nfa.Match(n, s), re.MatchString(s); got != want {
+				t.Errorf("Match(%q, %q) = %v, want %v", e, s, got, want)
+			}
+		}
+	}
+
+	n := nfa.NewLevenshtein("kitten", 3)
+	if !nfa.Match(n, "sitting") || nfa.Match(n, "sit") {
+		t.Errorf("Match does not agree with the Levenshtein distance")
+	}
+}
+
+func TestResolveAssertions(t *testing.T) {
+	exprs := []string{
+		"^abc$", "a^b", "a$b", `\bfoo\b.*`, `.*\Bo\B.*`, `(?m)a$\n^b`, `(?m)^$`, "a*?b", `x\b`, `\b`, `(^|/)a`,
+	}
+	samples := []string{
+		"", "a", "ab", "abc", "foo", "foo bar", "foobar", "boo", "o", "a\nb", "\n", "aab", "b", "x", "x ", "/a",
+	}
+	for _, e := range exprs {
+		n, err := nfa.New(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen := map[*nfa.Node]bool{n: true}
+		queue := []*nfa.Node{n}
+		for len(queue) > 0 {
+			node := queue[0]
+			queue = queue[1:]
+			for _, tr := range node.T {
+				if len(tr.R) > 0 && tr.R[0] < 0 {
+					t.Fatalf("New(%q) has a pseudo-rune", e)
+				}
+				if !seen[tr.N] {
+					seen[tr.N] = true
+					queue = append(queue, tr.N)
+				}
+			}
+		}
+
+		d := dfa.NewFromNFA(n)
+		re := regexp.MustCompile("^(?:" + e + ")$")
+		for _, s := range samples {
+			if got, want := accepts(d, s), re.MatchString(s); got != want {
+				t.Errorf("accepts(%q, %q) = %v, want %v", e, s, got, want)
+			}
+		}
+	}
+
+	n, err := nfa.NewWithOptions("^foo", nfa.Options{Substring: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := dfa.NewFromNFA(n); !accepts(d, "foobar") || accepts(d, "xfoo") {
+		t.Errorf("^foo as a substring is not anchored at the beginning")
+	}
+
+	n, _ = nfa.New("a+b")
+	if nfa.ResolveAssertions(n) != n {
+		t.Errorf("ResolveAssertions copied an automaton without assertions")
+	}
+}
+
+func TestRemoveEpsilon(t *testing.T) {
+	exprs := []string{"", "a*", "(a|b)*abb", "(a*)*b?", "^x(y|z)+$", "[a-z]+@[a-z]+\\.com"}
+	samples := []string{"", "a", "aaa", "abb", "babb", "b", "ab", "xyz", "xzz", "x", "me@example.com"}
+	for _, e := range exprs {
+		n, err := nfa.New(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := nfa.RemoveEpsilon(n)
+
+		seen := map[*nfa.Node]bool{m: true}
+		queue := []*nfa.Node{m}
+		for len(queue) > 0 {
+			node := queue[0]
+			queue = queue[1:]
+			for _, tr := range node.T {
+				if tr.R == nil {
+					t.Fatalf("RemoveEpsilon(%q) has an epsilon transition", e)
+				}
+				if !seen[tr.N] {
+					seen[tr.N] = true
+					queue = append(queue, tr.N)
+				}
+			}
+		}
+
+		for _, s := range samples {
+			if got, want := nfa.Match(m, s), nfa.Match(n, s); got != want {
+				t.Errorf("Match(RemoveEpsilon(%q), %q) = %v, want %v", e, s, got, want)
+			}
+		}
+	}
+}
+
+func TestFindUnsupported(t *testing.T) {
+	type testCase struct {
+		expr  string
+		found []nfa.Unsupported
+	}
+	testCases := []testCase{
+		{"a(?=b)c(?!d)", []nfa.Unsupported{{"lookahead", 1}, {"negative lookahead", 7}}},
+		{"(?<=a)(?<!b)", []nfa.Unsupported{{"lookbehind", 0}, {"negative lookbehind", 6}}},
+		{"(?>a|ab)c", []nfa.Unsupported{{"atomic group", 0}}},
+		{"(?(1)a|b)", []nfa.Unsupported{{"conditional", 0}}},
+		{"a*+b++c?+d{2}+", []nfa.Unsupported{{"possessive quantifier", 2}, {"possessive quantifier", 5}, {"possessive quantifier", 8}, {"possessive quantifier", 13}}},
+		{`(a)\1(?<x>b)\k<x>\g{1}\g-1\18`, []nfa.Unsupported{{"backreference", 3}, {"backreference", 12}, {"backreference", 17}, {"backreference", 22}, {"backreference", 26}}},
+		{"(?=a(?=b))x", []nfa.Unsupported{{"lookahead", 0}}},
+		{`a+?b*?[(?=]\Q(?=\E\\1\12\x{41}+a{b}+a{,2}+(?i)x(?:y)(?P<n>z)`, nil},
+	}
+	for _, tc := range testCases {
+		if got := nfa.FindUnsupported(tc.expr); !reflect.DeepEqual(got, tc.found) {
+			t.Errorf("FindUnsupported(%q) = %v, want %v", tc.expr, got, tc.found)
+		}
+	}
+}
+
+func TestApproximate(t *testing.T) {
+	type testCase struct {
+		expr string
+		in   string
+		want bool
+	}
+	testCases := []testCase{
+		{"a(?=b)b", "ab", true},
+		{"a(?!b)c", "ac", true},
+		{"a(?!b)c", "ab", false},
+		{"(?>a|ab)c", "abc", true},
+		{"[0-9]++", "123", true},
+		{`(a|b)\1`, "aa", true},
+		{`(a|b)\1`, "ab", true},
+		{`(a|b)\1`, "a", false},
+		{`(?P<x>[0-9]+)-\k<x>`, "12-34", true},
+		{`(?P<x>[0-9]+)-\k<x>`, "12-ab", false},
+		{`(a)(b)\g{-1}\g-2`, "abba", true},
+		{`(a)(b)\g{-1}\g-2`, "abab", false},
+		{`(?P<x>a)(?P=x)`, "aa", true},
+		{`(?P<x>a)(?P=x)`, "a", false},
+		{`(a\1)`, "axyz", true},
+		{`(?=(x))\1y`, "xy", true},
+		{`(?i)(a)(?-i)\1`, "aA", true},
+		{`(?s)(.)(?-s)\1`, "\n\n", true},
+		{`x(?(1)a|b)`, "xb", true},
+		{`x(?(1)a|b)`, "x", false},
+		{`[(?=]+`, "(?=", true},
+	}
+	for _, tc := range testCases {
+		n, err := nfa.NewWithOptions(tc.expr, nfa.Options{Approximate: true})
+		if err != nil {
+			t.Fatalf("NewWithOptions(%q, Approximate): %v", tc.expr, err)
+		}
+		if got := nfa.Match(n, tc.in); got != tc.want {
+			t.Errorf("NewWithOptions(%q, Approximate) accepts %q = %v, want %v", tc.expr, tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	exprs := []string{
+		"(a)(?P<x>b+?)", "(?i)hello|World", "(?i)[a-k]+", "(?s:(.))x", "(?m)^a$", "[^a]{2,3}", "(?i:Ǆ)",
+	}
+	for _, e := range exprs {
+		r, err := nfa.Parse(e, nfa.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		norm := nfa.Normalize(r).String()
+		n, err := nfa.New(norm)
+		if err != nil {
+			t.Fatalf("Normalize(%q) = %q: %v", e, norm, err)
+		}
+		if !dfa.Equal(dfa.Minimize(dfa.NewFromNFA(n)), dfa.Minimize(dfa.NewFromNFA(nfa.NewFromRegexp(r)))) {
+			t.Errorf("Normalize(%q) = %q changes the language", e, norm)
+		}
+	}
+}
+
+func TestAlternations(t *testing.T) {
+	type testCase struct {
+		pattern string
+		want    [][]nfa.Branch
+	}
+	testCases := []testCase{
+		{"abc", nil},
+		{"a|b", [][]nfa.Branch{{{"a", 0, ""}, {"b", 2, ""}}}},
+		{"x(a|a|ab?)", [][]nfa.Branch{{{"a", 2, ""}, {"a", 4, ""}, {"ab?", 6, ""}}}},
+		{"(a|(?:b|c))|d", [][]nfa.Branch{
+			{{"b", 6, ""}, {"c", 8, ""}},
+			{{"a", 1, ""}, {"(?:b|c)", 3, ""}},
+			{{"(a|(?:b|c))", 0, ""}, {"d", 12, ""}},
+		}},
+		{"(?i)a|b", [][]nfa.Branch{{{"(?i)a", 0, "(?i)"}, {"b", 6, "(?i)"}}}},
+		{"(?s:a|.)|c", [][]nfa.Branch{{{"a", 4, "(?s)"}, {".", 6, "(?s)"}}, {{"(?s:a|.)", 0, ""}, {"c", 9, ""}}}},
+		{`[|]|\||(?P<x>a|b)`, [][]nfa.Branch{{{"a", 13, ""}, {"b", 15, ""}}, {{"[|]", 0, ""}, {`\|`, 4, ""}, {"(?P<x>a|b)", 7, ""}}}},
+		{"(a|b", [][]nfa.Branch{{{"a", 1, ""}, {"b", 3, ""}}}},
+	}
+	for _, tc := range testCases {
+		if got := nfa.Alternations(tc.pattern); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Alternations(%q) = %v, want %v", tc.pattern, got, tc.want)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	type testCase struct {
+		expr  string
+		opts  nfa.Options
+		limit string // empty if the pattern is within the limits
+	}
+	testCases := []testCase{
+		{`a{100}`, nfa.Options{MaxRepeat: 100}, ""},
+		{`a{101}`, nfa.Options{MaxRepeat: 100}, "MaxRepeat"},
+		{`(a{10}){11}`, nfa.Options{MaxRepeat: 100}, "MaxRepeat"},
+		{`(a{10}b){10}`, nfa.Options{MaxRepeat: 100}, ""},
+		{`a{2,}`, nfa.Options{MaxRepeat: 1}, "MaxRepeat"},
+		{`((a))`, nfa.Options{MaxNesting: 3}, ""},
+		{`(((a)))`, nfa.Options{MaxNesting: 3}, "MaxNesting"},
+		{`abc`, nfa.Options{MaxNFANodes: 10}, ""},
+		{`[a-z]{20}`, nfa.Options{MaxNFANodes: 10}, "MaxNFANodes"},
+		// 14 nodes, 40 once the assertions are resolved.
+		{`(\ba|\Bb|$)*`, nfa.Options{MaxNFANodes: 20}, "MaxNFANodes"},
+		{`(\ba|\Bb|$)*`, nfa.Options{MaxNFANodes: 40}, ""},
+	}
+	for _, tc := range testCases {
+		_, err := nfa.NewWithOptions(tc.expr, tc.opts)
+		var limit *nfa.ErrLimitExceeded
+		switch {
+		case tc.limit == "" && err != nil:
+			t.Errorf("NewWithOptions(%q) failed: %v", tc.expr, err)
+		case tc.limit != "" && (!errors.As(err, &limit) || limit.Limit != tc.limit):
+			t.Errorf("NewWithOptions(%q) = %v, want %s exceeded", tc.expr, err, tc.limit)
+		}
+	}
+}
+
+func TestSize(t *testing.T) {
+	for _, expr := range []string{`a`, `abc`, `a|bc`, `(ab)*c+d?`, `[a-z]{2,4}`, `^(?i:x)$`, `(?:)`, `(a|b){3,}`} {
+		r, err := nfa.Parse(expr, nfa.Options{})
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", expr, err)
+		}
+		// Every node of these automata is reachable from the first one.
+		seen := make(map[*nfa.Node]bool)
+		var walk func(n *nfa.Node)
+		walk = func(n *nfa.Node) {
+			if seen[n] {
+				return
+			}
+			seen[n] = true
+			for _, tr := range n.T {
+				walk(tr.N)
+			}
+		}
+		walk(nfa.NewFromRegexp(r))
+		if got := nfa.Size(r); got != len(seen) {
+			t.Errorf("Size(%q) = %d, want %d", expr, got, len(seen))
+		}
+	}
+}
+
+func TestEstimateDFAStates(t *testing.T) {
+	exprs := []string{
+		`[ab]*a[ab]{12}`, `(?s).*a.{10}`, `[a-z]+@[a-z0-9-]+(\.[a-z]{2,6}){1,3}`, `.*/api/v1/users[0-9]`,
+		`.*[a-z]{20}.*`, `(a|b)*abb`, `(.{10})*`, `(?i)hello world`,
+	}
+	for _, expr := range exprs {
+		r, err := nfa.Parse(expr, nfa.Options{})
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", expr, err)
+		}
+		seen := make(map[*dfa.Node]bool)
+		var walk func(n *dfa.Node)
+		walk = func(n *dfa.Node) {
+			if seen[n] {
+				return
+			}
+			seen[n] = true
+			for _, tr := range n.Transitions {
+				walk(tr.Node)
+			}
+		}
+		walk(dfa.NewFromNFA(nfa.NewFromRegexp(r)))
+		// The estimate is off by a small factor at most.
+		if got := nfa.EstimateDFAStates(r); got < len(seen) || got > 4*len(seen) {
+			t.Errorf("EstimateDFAStates(%q) = %d, want about %d", expr, got, len(seen))
+		}
+	}
+
+	r, _ := nfa.Parse(`[ab]*a[ab]{100}`, nfa.Options{})
+	if got := nfa.EstimateDFAStates(r); got < 1<<40 {
+		t.Errorf("EstimateDFAStates([ab]*a[ab]{100}) = %d", got)
+	}
+}
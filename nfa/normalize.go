@@ -0,0 +1,60 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package nfa
+
+import (
+	"regexp/syntax"
+
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// Normalize returns a copy of r without capture groups and with case
+// folding expanded into character classes, as NewFromRegexp interprets
+// them.
+func Normalize(r *syntax.Regexp) *syntax.Regexp {
+	if r.Op == syntax.OpCapture {
+		return Normalize(r.Sub[0])
+	}
+
+	nr := *r
+	nr.Flags &^= syntax.FoldCase
+	nr.Sub = nil
+	for _, sub := range r.Sub {
+		nr.Sub = append(nr.Sub, Normalize(sub))
+	}
+
+	if r.Flags&syntax.FoldCase == 0 {
+		return &nr
+	}
+	switch r.Op {
+	case syntax.OpLiteral:
+		var subs []*syntax.Regexp
+		for _, c := range r.Rune {
+			sub := &syntax.Regexp{Op: syntax.OpCharClass, Flags: nr.Flags, Rune: runerange.Fold([]rune{c, c})}
+			if len(sub.Rune) == 2 && sub.Rune[0] == sub.Rune[1] {
+				sub = &syntax.Regexp{Op: syntax.OpLiteral, Flags: nr.Flags, Rune: []rune{c}}
+			}
+			subs = append(subs, sub)
+		}
+		if len(subs) == 1 {
+			return subs[0]
+		}
+		return &syntax.Regexp{Op: syntax.OpConcat, Flags: nr.Flags, Sub: subs}
+
+	case syntax.OpCharClass:
+		nr.Rune = runerange.Fold(r.Rune)
+	}
+
+	return &nr
+}
@@ -0,0 +1,426 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package nfa
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Unsupported is a construct of Perl or PCRE patterns that has no finite
+// automaton equivalent.
+type Unsupported struct {
+	Construct string // name of the construct, e.g. "lookahead"
+	Pos       int    // byte offset of the construct in the pattern
+}
+
+// FindUnsupported returns the unsupported constructs of the pattern in the
+// order they appear. Constructs nested in a lookaround are not reported.
+func FindUnsupported(pattern string) []Unsupported {
+	_, found := approximate(pattern)
+	return found
+}
+
+// lookarounds maps the openings of lookaround groups to their names.
+var lookarounds = []struct {
+	open, name string
+}{
+	{"(?=", "lookahead"},
+	{"(?!", "negative lookahead"},
+	{"(?<=", "lookbehind"},
+	{"(?<!", "negative lookbehind"},
+}
+
+// approximate rewrites the unsupported constructs of the pattern into
+// supported ones accepting a superset of strings: lookarounds are dropped,
+// atomic groups and conditionals become plain groups, possessive quantifiers
+// become greedy ones and backreferences match the language of the group they
+// refer to. It also returns the constructs it rewrote.
+func approximate(pattern string) (string, []Unsupported) {
+	a := &approximator{names: make(map[string]int), captured: make(map[int]string)}
+	a.run(pattern)
+	return string(a.buf), a.found
+}
+
+// Kinds of groups.
+const (
+	plainGroup = iota
+	captureGroup
+	lookaroundGroup
+)
+
+type group struct {
+	kind   int
+	number int // number of a capture group
+	start  int // offset of the body of the group in the output
+}
+
+type approximator struct {
+	buf      []byte
+	found    []Unsupported
+	open     []group
+	names    map[string]int // capture group numbers by name
+	captured map[int]string // bodies of the closed capture groups
+	captures int            // number of capture groups opened so far
+	flags    string         // widening inline flags used so far
+	dropped  int            // number of open lookarounds
+}
+
+func (a *approximator) report(construct string, pos int) {
+	// Constructs in a lookaround go away with it.
+	if a.dropped == 0 {
+		a.found = append(a.found, Unsupported{Construct: construct, Pos: pos})
+	}
+}
+
+func (a *approximator) write(s string) {
+	a.buf = append(a.buf, s...)
+}
+
+// push opens a group whose opening has just been written.
+func (a *approximator) push(kind int) {
+	g := group{kind: kind, start: len(a.buf)}
+	if kind == captureGroup {
+		a.captures++
+		g.number = a.captures
+	}
+	a.open = append(a.open, g)
+}
+
+func (a *approximator) run(pattern string) {
+	quantified := false // the previous token was a quantifier
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+		afterQuantifier := quantified
+		quantified = false
+
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			if ref, n := backreference(pattern[i:]); n > 0 {
+				a.report("backreference", i)
+				a.write(a.reference(ref))
+				i += n
+				continue
+			}
+			n := tokenLen(pattern[i:])
+			a.write(pattern[i : i+n])
+			i += n
+
+		case c == '[':
+			n := classLen(pattern[i:])
+			a.write(pattern[i : i+n])
+			i += n
+
+		case c == '(':
+			i += a.group(pattern, i)
+
+		case c == ')':
+			a.close()
+			i++
+
+		case c == '+' && afterQuantifier:
+			a.report("possessive quantifier", i)
+			i++
+
+		case c == '?' && afterQuantifier:
+			a.write("?")
+			i++
+
+		case c == '*' || c == '+' || c == '?':
+			a.buf = append(a.buf, c)
+			i++
+			quantified = true
+
+		case c == '{':
+			n := repeatLen(pattern[i:])
+			a.write(pattern[i : i+n])
+			i += n
+			quantified = n > 1
+
+		default:
+			a.buf = append(a.buf, c)
+			i++
+		}
+	}
+}
+
+// group handles the group opening at pattern[i] and returns the length of
+// the text it consumed.
+func (a *approximator) group(pattern string, i int) int {
+	s := pattern[i:]
+	if name := lookaround(s); name != "" {
+		a.report(name, i)
+		a.write("(?:")
+		a.push(lookaroundGroup)
+		a.dropped++
+		return len(lookaroundOpen(s))
+	}
+
+	switch {
+	case strings.HasPrefix(s, "(?P="):
+		a.report("backreference", i)
+		n := len(s)
+		if end := strings.IndexByte(s, ')'); end >= 0 {
+			n = end + 1
+		}
+		a.write(a.reference(strings.TrimSuffix(s[4:n], ")")))
+		return n
+
+	case strings.HasPrefix(s, "(?>"):
+		a.report("atomic group", i)
+		a.write("(?:")
+		a.push(plainGroup)
+		return 3
+
+	case strings.HasPrefix(s, "(?("):
+		// The alternatives of a conditional behave as the ones of a group
+		// once the condition is dropped.
+		a.report("conditional", i)
+		a.write("(?:")
+		a.push(plainGroup)
+		return 2 + groupLen(s[2:])
+
+	case strings.HasPrefix(s, "(?P<") || strings.HasPrefix(s, "(?<") || strings.HasPrefix(s, "(?'"):
+		open := strings.IndexAny(s, "<'")
+		closing := byte('>')
+		if s[open] == '\'' {
+			closing = '\''
+		}
+		end := strings.IndexByte(s[open+1:], closing)
+		if end < 0 {
+			a.write(s)
+			return len(s)
+		}
+		name := s[open+1 : open+1+end]
+		a.write("(?P<" + name + ">")
+		a.push(captureGroup)
+		a.names[name] = a.captures
+		return open + end + 2
+
+	case strings.HasPrefix(s, "(?"):
+		n := 2
+		for n < len(s) && strings.IndexByte("imsU-", s[n]) >= 0 {
+			n++
+		}
+		for _, f := range "is" {
+			if strings.ContainsRune(s[2:n], f) && !strings.ContainsRune(a.flags, f) {
+				a.flags += string(f)
+			}
+		}
+		if n < len(s) && s[n] == ')' {
+			a.write(s[:n+1])
+			return n + 1
+		}
+		a.write(s[:2])
+		a.push(plainGroup)
+		return 2
+	}
+
+	a.write("(")
+	a.push(captureGroup)
+	return 1
+}
+
+// close handles a closing parenthesis.
+func (a *approximator) close() {
+	if len(a.open) == 0 {
+		a.write(")")
+		return
+	}
+	g := a.open[len(a.open)-1]
+	a.open = a.open[:len(a.open)-1]
+
+	switch g.kind {
+	case captureGroup:
+		a.captured[g.number] = string(a.buf[g.start:])
+	case lookaroundGroup:
+		// Drop the body along with the (?: written for the opening.
+		a.buf = append(a.buf[:g.start-len("(?:")], "(?:)"...)
+		a.dropped--
+		return
+	}
+	a.write(")")
+}
+
+// reference returns the pattern standing in for a backreference to the
+// group with the given number or name. A relative number such as -1 counts
+// back from the last group opened.
+func (a *approximator) reference(ref string) string {
+	number, err := strconv.Atoi(ref)
+	switch {
+	case err != nil:
+		number = a.names[ref]
+	case number < 0:
+		number += a.captures + 1
+	}
+
+	body, ok := a.captured[number]
+	if !ok {
+		// The group is still open or does not exist.
+		return "(?s:.*)"
+	}
+	// The group may have matched under flags that no longer apply.
+	if a.flags != "" {
+		return "(?" + a.flags + ":" + body + ")"
+	}
+	return "(?:" + body + ")"
+}
+
+// lookaroundOpen returns the opening of the lookaround group s starts with.
+func lookaroundOpen(s string) string {
+	for _, l := range lookarounds {
+		if strings.HasPrefix(s, l.open) {
+			return l.open
+		}
+	}
+	return ""
+}
+
+// lookaround returns the name of the lookaround group opening s, if any.
+func lookaround(s string) string {
+	for _, l := range lookarounds {
+		if strings.HasPrefix(s, l.open) {
+			return l.name
+		}
+	}
+	return ""
+}
+
+// backreference returns the group number or name of the backreference at
+// the beginning of s, which starts with a backslash, and its length. The
+// length is 0 if there is none.
+func backreference(s string) (string, int) {
+	if len(s) < 3 {
+		if len(s) == 2 && s[1] >= '1' && s[1] <= '9' {
+			return s[1:], 2
+		}
+		return "", 0
+	}
+	switch c := s[1]; {
+	case c >= '1' && c <= '9':
+		// \1 to \7 followed by an octal digit start an octal escape.
+		if c <= '7' && s[2] >= '0' && s[2] <= '7' {
+			return "", 0
+		}
+		return s[1:2], 2
+	case c == 'k' && strings.IndexByte("<{'", s[2]) >= 0:
+		closing := map[byte]byte{'<': '>', '{': '}', '\'': '\''}[s[2]]
+		if end := strings.IndexByte(s[3:], closing); end >= 0 {
+			return s[3 : 3+end], end + 4
+		}
+		return s[3:], len(s)
+	case c == 'g' && s[2] == '{':
+		if end := strings.IndexByte(s, '}'); end >= 0 {
+			return s[3:end], end + 1
+		}
+		return s[3:], len(s)
+	case c == 'g' && (s[2] == '-' || s[2] >= '0' && s[2] <= '9'):
+		n := 3
+		for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+			n++
+		}
+		return s[2:n], n
+	}
+	return "", 0
+}
+
+// tokenLen returns the length of the escape sequence or quoted text at the
+// beginning of s.
+func tokenLen(s string) int {
+	if strings.HasPrefix(s, `\Q`) {
+		if end := strings.Index(s, `\E`); end >= 0 {
+			return end + 2
+		}
+		return len(s)
+	}
+	if len(s) > 2 && s[1] >= '0' && s[1] <= '7' {
+		// Octal escapes have up to three digits.
+		n := 2
+		for n < len(s) && n < 4 && s[n] >= '0' && s[n] <= '7' {
+			n++
+		}
+		return n
+	}
+	return escapeLen(s)
+}
+
+// classLen returns the length of the character class at the beginning of s.
+func classLen(s string) int {
+	i := 1
+	if i < len(s) && s[i] == '^' {
+		i++
+	}
+	// A ] right after the opening bracket is a literal.
+	if i < len(s) && s[i] == ']' {
+		i++
+	}
+	for i < len(s) {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i += tokenLen(s[i:])
+		case strings.HasPrefix(s[i:], "[:") && strings.Contains(s[i:], ":]"):
+			i += strings.Index(s[i:], ":]") + 2
+		case s[i] == ']':
+			return i + 1
+		default:
+			i++
+		}
+	}
+	return len(s)
+}
+
+// groupLen returns the length of the group opened at the beginning of s, up
+// to and including its closing parenthesis.
+func groupLen(s string) int {
+	depth := 0
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i += tokenLen(s[i:])
+				continue
+			}
+		case '[':
+			i += classLen(s[i:])
+			continue
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return i + 1
+			}
+		}
+		i++
+	}
+	return len(s)
+}
+
+// repeatLen returns the length of the repetition {n}, {n,} or {n,m} at the
+// beginning of s, or 1 if the brace is a literal.
+func repeatLen(s string) int {
+	end := strings.IndexByte(s, '}')
+	if end < 0 {
+		return 1
+	}
+	bounds := strings.SplitN(s[1:end], ",", 2)
+	for i, b := range bounds {
+		if b == "" && i == 1 {
+			continue
+		}
+		if b == "" || strings.Trim(b, "0123456789") != "" {
+			return 1
+		}
+	}
+	return end + 1
+}
@@ -17,7 +17,7 @@ package main
 import (
 	"flag"
 	"fmt"
-	"github.com/oulinbao/regexinter/intersection"
+	"github.com/oulinbao/regexinter/reinter"
 	"log"
 	"os"
 )
@@ -25,10 +25,22 @@ import (
 func main() {
 	log.SetFlags(0)
 
+	if len(os.Args) > 1 && os.Args[1] == "batch" {
+		batch(os.Args[2:])
+		return
+	}
+
+	options := optionFlags(flag.CommandLine)
+	html := flag.String("html", "", "write an HTML page drawing the automata to the `file`")
+
 	flag.Usage = func() {
-		fmt.Println(`Usage: regexinter regexp1 regexp2
+		fmt.Println(`Usage: regexinter [flags] regexp1 regexp2
+       regexinter batch [flags] -input file
+
+EXAMPLE: regexinter "a+b" "a*b"
 
-EXAMPLE: regexinter "a+b" "a*b"`)
+Flags:`)
+		flag.PrintDefaults()
 	}
 	flag.Parse()
 
@@ -37,6 +49,71 @@ EXAMPLE: regexinter "a+b" "a*b"`)
 		os.Exit(1)
 	}
 
-	result := intersection.HasIntersection(flag.Arg(0), flag.Arg(1))
-	fmt.Println(result)
+	opts := options()
+
+	result, err := reinter.Check(flag.Arg(0), flag.Arg(1), opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *html != "" {
+		f, err := os.Create(*html)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = reinter.WriteHTML(f, flag.Arg(0), flag.Arg(1), opts...)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	switch {
+	case result.Approximate:
+		fmt.Println(result.Intersects, "(approximate)")
+	case result.Explanation != "":
+		fmt.Println(result.Intersects, "-", result.Explanation)
+	default:
+		fmt.Println(result.Intersects)
+	}
+}
+
+// optionFlags defines the flags configuring the checks on fs. The function
+// returned converts them into options once fs is parsed.
+func optionFlags(fs *flag.FlagSet) func() []reinter.Option {
+	caseInsensitive := fs.Bool("i", false, "case insensitive matching")
+	unicode := fs.Bool("unicode", false, `Unicode semantics for \d, \s and \w`)
+	maxStates := fs.Int("max-states", 0, "maximum number of automaton states (0 for no limit)")
+	timeout := fs.Duration("timeout", 0, "maximum time to spend (0 for no limit)")
+	prefix := fs.Bool("prefix", false, "match strings starting with a match of the patterns, as routers do")
+	substring := fs.Bool("substring", false, "match strings containing a match of the patterns, as grep does")
+	approximate := fs.Bool("approximate", false, "over-approximate lookarounds and backreferences instead of rejecting them")
+	minimize := fs.Bool("minimize", false, "minimize the automata before checking them")
+	ascii := fs.Bool("ascii", false, "restrict the patterns to ASCII strings, which is faster")
+	readable := fs.Bool("readable", false, "spell witnesses with letters and digits where possible")
+	alphabet := fs.String("alphabet", "", "characters witnesses are restricted to (all if empty)")
+
+	return func() []reinter.Option {
+		semantics := reinter.FullMatch
+		if *prefix {
+			semantics = reinter.PrefixOverlap
+		}
+		if *substring {
+			semantics = reinter.SubstringMatch
+		}
+
+		return []reinter.Option{
+			reinter.MatchSemantics(semantics),
+			reinter.CaseInsensitive(*caseInsensitive),
+			reinter.UnicodeMode(*unicode),
+			reinter.MaxStates(*maxStates),
+			reinter.Timeout(*timeout),
+			reinter.OverApproximate(*approximate),
+			reinter.MinimizeFirst(*minimize),
+			reinter.ASCIIOnly(*ascii),
+			reinter.ReadableWitness(*readable),
+			reinter.WitnessAlphabet(*alphabet),
+		}
+	}
 }
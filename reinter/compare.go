@@ -0,0 +1,70 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reinter
+
+import (
+	"github.com/oulinbao/regexinter/dfa"
+)
+
+// Relation tells how the languages of two patterns relate, see Compare.
+type Relation int
+
+const (
+	Disjoint    Relation = iota // no string matches both patterns
+	Overlapping                 // some strings match both, some only either
+	Subset                      // every string of the first matches the second
+	Superset                    // every string of the second matches the first
+	Equal                       // the patterns match the same strings
+)
+
+func (r Relation) String() string {
+	switch r {
+	case Overlapping:
+		return "overlapping"
+	case Subset:
+		return "subset"
+	case Superset:
+		return "superset"
+	case Equal:
+		return "equal"
+	}
+	return "disjoint"
+}
+
+// Compare returns how the languages of the patterns relate, from two
+// containment checks and an intersection check, for route-priority logic
+// to order patterns. Containment comes first: a pattern matching nothing is
+// a Subset of any other.
+func Compare(expr1, expr2 string, opts ...Option) (Relation, error) {
+	n1, err := Compile(expr1, opts...)
+	if err != nil {
+		return Disjoint, err
+	}
+	n2, err := Compile(expr2, opts...)
+	if err != nil {
+		return Disjoint, err
+	}
+	sub, super := dfa.IsEmpty(dfa.Subtract(n1, n2)), dfa.IsEmpty(dfa.Subtract(n2, n1))
+	switch {
+	case sub && super:
+		return Equal, nil
+	case sub:
+		return Subset, nil
+	case super:
+		return Superset, nil
+	case dfa.IsEmpty(dfa.Intersect(n1, n2)):
+		return Disjoint, nil
+	}
+	return Overlapping, nil
+}
@@ -0,0 +1,59 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reinter
+
+import (
+	"github.com/oulinbao/regexinter/dfa"
+)
+
+// Info describes the strings a pattern matches.
+type Info struct {
+	// AnchoredStart tells that the strings cannot start with any text, as
+	// they can with .*abc, so searches only need to try matching at the
+	// start of inputs or where the pattern asserts.
+	AnchoredStart bool
+
+	// AnchoredEnd tells the same about the end of the strings.
+	AnchoredEnd bool
+
+	// MatchesEmpty tells whether the empty string matches.
+	MatchesEmpty bool
+
+	// MinLength and MaxLength are the lengths in runes of the shortest
+	// and longest strings matched. MaxLength is -1 if there is no bound.
+	// Both are -1 if the pattern matches nothing.
+	MinLength int
+	MaxLength int
+}
+
+// Inspect describes the strings expr matches under the given options, for
+// routers to choose how to look for them.
+func Inspect(expr string, opts ...Option) (Info, error) {
+	n, err := Compile(expr, opts...)
+	if err != nil {
+		return Info{}, err
+	}
+	anything, err := Compile("(?s:.*)", opts...)
+	if err != nil {
+		return Info{}, err
+	}
+	n = dfa.Minimize(n)
+	info := Info{
+		AnchoredStart: !dfa.Equal(dfa.Minimize(dfa.Concat(anything, n)), n),
+		AnchoredEnd:   !dfa.Equal(dfa.Minimize(dfa.Concat(n, anything)), n),
+	}
+	info.MinLength, info.MaxLength = dfa.Lengths(n)
+	info.MatchesEmpty = info.MinLength == 0
+	return info, nil
+}
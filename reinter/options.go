@@ -0,0 +1,251 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reinter
+
+import (
+	"time"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/intersection"
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// Option configures how patterns are compiled and compared.
+type Option func(*config)
+
+// Semantics tells which strings a pattern is considered to match.
+type Semantics int
+
+const (
+	// FullMatch matches strings as a whole, as if the pattern were
+	// enclosed in ^(?:...)$.
+	FullMatch Semantics = iota
+
+	// PrefixOverlap matches every string starting with a match of the
+	// pattern, as if .* were appended to it. This is how routers matching
+	// by prefix behave: /api/v1 then overlaps with /api/v1/users.
+	PrefixOverlap
+
+	// SubstringMatch matches every string containing a match of the
+	// pattern, as if it were enclosed in .*(...).*, the way grep and
+	// unanchored searches do: foo then overlaps with foobar-ish.
+	SubstringMatch
+)
+
+// EmptyPattern tells how the empty pattern "" is interpreted.
+type EmptyPattern int
+
+const (
+	// EmptyIsEmptyString makes "" match the empty string only, as in
+	// package regexp.
+	EmptyIsEmptyString EmptyPattern = iota
+
+	// EmptyIsEmptyLanguage makes "" match no string at all.
+	EmptyIsEmptyLanguage
+
+	// EmptyIsError rejects "" with ErrEmptyPattern, forcing callers to use
+	// EmptyString or EmptyLanguage instead.
+	EmptyIsError
+)
+
+type config struct {
+	intersection.Options
+	timeout   time.Duration
+	semantics Semantics
+	empty     EmptyPattern
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.timeout > 0 {
+		c.Limits.Deadline = time.Now().Add(c.timeout)
+	}
+	c.Pattern.Prefix = c.semantics == PrefixOverlap
+	c.Pattern.Substring = c.semantics == SubstringMatch
+	return c
+}
+
+// pattern returns the pattern to compile for expr.
+func (c *config) pattern(expr string) (string, error) {
+	if expr != "" {
+		return expr, nil
+	}
+	switch c.empty {
+	case EmptyIsEmptyLanguage:
+		return EmptyLanguage(), nil
+	case EmptyIsError:
+		return "", ErrEmptyPattern
+	}
+	return EmptyString(), nil
+}
+
+// MaxStates limits the number of states of each automaton built, including
+// the product of two automata when checking them for intersection.
+func MaxStates(n int) Option {
+	return func(c *config) {
+		c.Limits.MaxStates = n
+	}
+}
+
+// MaxNFANodes limits the number of nodes of the NFA of each pattern, which
+// grows with the length of patterns and the repetitions in them.
+func MaxNFANodes(n int) Option {
+	return func(c *config) {
+		c.Pattern.MaxNFANodes = n
+	}
+}
+
+// MaxRepeat limits the number of copies counted repetitions such as x{100}
+// expand to, the counts of nested repetitions being multiplied.
+func MaxRepeat(n int) Option {
+	return func(c *config) {
+		c.Pattern.MaxRepeat = n
+	}
+}
+
+// MaxNesting limits the nesting depth of groups and operators in patterns.
+func MaxNesting(n int) Option {
+	return func(c *config) {
+		c.Pattern.MaxNesting = n
+	}
+}
+
+// Progress describes a construction under way, see OnProgress.
+type Progress = dfa.Progress
+
+// OnProgress has fn called periodically while automata and their products
+// are built, with the number of states built and pending and the time spent
+// so far. An error fn returns aborts the call and is returned.
+func OnProgress(fn func(Progress) error) Option {
+	return func(c *config) {
+		c.Limits.Progress = fn
+	}
+}
+
+// Logger receives the steps of constructions, see WithLogger.
+type Logger = dfa.Logger
+
+// WithLogger passes the states created, the transitions added and the
+// product states visited to l, for debugging. Nothing is logged by default.
+func WithLogger(l Logger) Option {
+	return func(c *config) {
+		c.Limits.Logger = l
+	}
+}
+
+// Timeout limits the time spent on a single call.
+func Timeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
+
+// MatchSemantics sets which strings patterns are considered to match. The
+// default is FullMatch.
+func MatchSemantics(s Semantics) Option {
+	return func(c *config) {
+		c.semantics = s
+	}
+}
+
+// UnicodeMode makes the Perl classes \d, \s and \w match Unicode digits,
+// white space and word characters instead of ASCII ones.
+func UnicodeMode(on bool) Option {
+	return func(c *config) {
+		c.Pattern.UnicodeClasses = on
+	}
+}
+
+// CaseInsensitive makes patterns case insensitive as if they started with
+// (?i).
+func CaseInsensitive(on bool) Option {
+	return func(c *config) {
+		c.Pattern.FoldCase = on
+	}
+}
+
+// OnTheFly checks patterns for intersection by exploring the product of
+// their NFAs without determinizing each of them first. This can finish on
+// patterns whose DFAs are too large to build.
+func OnTheFly(on bool) Option {
+	return func(c *config) {
+		c.OnTheFly = on
+	}
+}
+
+// ReadableWitness spells the strings Check returns with ASCII letters and
+// digits where the patterns allow, then with other printable characters,
+// rather than with the smallest code points, for use in error messages.
+func ReadableWitness(on bool) Option {
+	return func(c *config) {
+		c.ReadableWitness = on
+	}
+}
+
+// URLSafe holds the characters URLs carry unescaped, for WitnessAlphabet.
+const URLSafe = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-._~"
+
+// WitnessAlphabet restricts the strings Check returns to the given
+// characters. Patterns intersecting only on strings with other characters
+// fail the check with ErrNoWitnessInAlphabet. An empty alphabet restricts
+// nothing.
+func WitnessAlphabet(chars string) Option {
+	return func(c *config) {
+		c.Alphabet = nil
+		for _, r := range chars {
+			c.Alphabet = runerange.Add(c.Alphabet, r)
+		}
+	}
+}
+
+// InterpretEmpty sets how the empty pattern "" is interpreted. The default is
+// EmptyIsEmptyString.
+func InterpretEmpty(e EmptyPattern) Option {
+	return func(c *config) {
+		c.empty = e
+	}
+}
+
+// OverApproximate accepts patterns with lookarounds, backreferences, atomic
+// groups, conditionals and possessive quantifiers by rewriting them into
+// patterns matching a superset of strings, and marks the results of checks
+// involving such patterns as approximate: a negative answer still holds but a
+// positive one may be wrong. Without it the patterns are rejected with an
+// ErrUnsupportedConstruct error.
+func OverApproximate(on bool) Option {
+	return func(c *config) {
+		c.Pattern.Approximate = on
+	}
+}
+
+// ASCIIOnly restricts patterns to ASCII strings: classes lose their other
+// runes and . matches printable ASCII characters only. The checks then use
+// dense transition tables, which makes them faster.
+func ASCIIOnly(on bool) Option {
+	return func(c *config) {
+		c.Pattern.ASCII = on
+	}
+}
+
+// MinimizeFirst minimizes the DFAs of the patterns before checking them for
+// intersection. This costs some time per pattern but can shrink their product
+// by orders of magnitude. It has no effect together with OnTheFly.
+func MinimizeFirst(on bool) Option {
+	return func(c *config) {
+		c.Minimize = on
+	}
+}
@@ -0,0 +1,111 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reinter
+
+// patternBits is the number of bits of a pattern ID each level of a
+// patternMap indexes.
+const patternBits = 5
+
+// patternMap maps the IDs of the patterns of a set to them. It is a trie of
+// the bits of the IDs whose changes copy the nodes on the path to the ID
+// only, so that snapshots share the rest of it.
+type patternMap struct {
+	root  *patternNode
+	depth int // levels below the root
+	len   int
+}
+
+type patternNode struct {
+	kids [1 << patternBits]*patternNode // below the last level
+	vals [1 << patternBits]*setPattern  // at the last level
+}
+
+// fits reports whether the trie is deep enough for the ID.
+func (m patternMap) fits(id PatternID) bool {
+	return m.root != nil && uint64(id)>>(patternBits*(m.depth+1)) == 0
+}
+
+// slot returns the index of the ID in the nodes at a level above the last.
+func slot(id PatternID, level int) int {
+	return int(id>>(patternBits*level)) & (1<<patternBits - 1)
+}
+
+// get returns the pattern with the ID, nil if there is none.
+func (m patternMap) get(id PatternID) *setPattern {
+	if !m.fits(id) {
+		return nil
+	}
+	n := m.root
+	for level := m.depth; level > 0 && n != nil; level-- {
+		n = n.kids[slot(id, level)]
+	}
+	if n == nil {
+		return nil
+	}
+	return n.vals[slot(id, 0)]
+}
+
+// set returns a copy of the map in which the ID maps to p, or to nothing if
+// p is nil. m is not modified.
+func (m patternMap) set(id PatternID, p *setPattern) patternMap {
+	switch had := m.get(id) != nil; {
+	case had && p == nil:
+		m.len--
+	case !had && p != nil:
+		m.len++
+	}
+	if m.root == nil {
+		m.root = &patternNode{}
+	}
+	for !m.fits(id) {
+		m.root = &patternNode{kids: [1 << patternBits]*patternNode{m.root}}
+		m.depth++
+	}
+	m.root = m.root.set(id, m.depth, p)
+	return m
+}
+
+func (n *patternNode) set(id PatternID, level int, p *setPattern) *patternNode {
+	var c patternNode
+	if n != nil {
+		c = *n
+	}
+	if level == 0 {
+		c.vals[slot(id, 0)] = p
+	} else {
+		c.kids[slot(id, level)] = c.kids[slot(id, level)].set(id, level-1, p)
+	}
+	return &c
+}
+
+// each calls f with the patterns of the map in increasing order of ID.
+func (m patternMap) each(f func(PatternID, *setPattern)) {
+	var walk func(n *patternNode, level int, base PatternID)
+	walk = func(n *patternNode, level int, base PatternID) {
+		if n == nil {
+			return
+		}
+		for i := range n.vals {
+			id := base | PatternID(i)<<(patternBits*level)
+			if level == 0 {
+				if n.vals[i] != nil {
+					f(id, n.vals[i])
+				}
+			} else {
+				walk(n.kids[i], level-1, id)
+			}
+		}
+	}
+	walk(m.root, m.depth, 0)
+}
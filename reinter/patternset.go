@@ -0,0 +1,364 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reinter
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// PatternID identifies a pattern of a PatternSet. IDs are given from 1 in
+// the order the patterns are added.
+type PatternID int
+
+// PatternSet is a set of patterns, such as the routes of a registry, along
+// with the union of their automata whose states tell which of the patterns
+// accept. Adding a pattern extends the union with the product of its part
+// overlapping the new pattern rather than rebuilding it: the states of the
+// union the new pattern cannot reach are kept as they are.
+//
+// Removing a pattern only forgets it, the union still telling when it
+// accepts. Remove rebuilds the union from the automata of the patterns left
+// once the removed patterns outnumber them.
+//
+// A PatternSet is safe for concurrent use. Queries run on a snapshot of the
+// set and never wait for Add or Remove, which copy the set on write and
+// publish the copy once it is complete.
+type PatternSet struct {
+	opts    []Option
+	mu      sync.Mutex // held by Add and Remove
+	last    PatternID
+	current atomic.Pointer[PatternSetSnapshot]
+}
+
+// PatternSetSnapshot is the state of a PatternSet at some point, which
+// changes to the set do not affect. Its methods are those of PatternSet,
+// which run on the latest snapshot.
+type PatternSetSnapshot struct {
+	opts     []Option
+	patterns patternMap
+	root     *setState // nil while the set is empty
+	states   int       // number of states of the union
+	removed  int       // number of removed patterns still in the union
+}
+
+type setPattern struct {
+	expr string
+	node *dfa.Node // minimized
+}
+
+type setState struct {
+	accept []PatternID // patterns accepting the strings leading here, sorted
+	moves  []setMove   // sorted by lo
+}
+
+type setMove struct {
+	lo, hi rune
+	to     *setState
+}
+
+// NewPatternSet returns an empty set whose patterns are compiled with the
+// options. The limits of the options apply to the union as well.
+func NewPatternSet(opts ...Option) *PatternSet {
+	s := &PatternSet{opts: opts}
+	s.current.Store(&PatternSetSnapshot{opts: opts})
+	return s
+}
+
+// Snapshot returns the current state of the set.
+func (s *PatternSet) Snapshot() *PatternSetSnapshot {
+	return s.current.Load()
+}
+
+// Add compiles the pattern, adds it to the set and returns its ID. The set is
+// left unchanged if it fails.
+func (s *PatternSet) Add(expr string) (PatternID, error) {
+	n, err := Compile(expr, s.opts...)
+	if err != nil {
+		return 0, err
+	}
+	n = dfa.Minimize(n)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap := s.current.Load()
+	id := s.last + 1
+	c := newConfig(s.opts)
+	root, err := extend(snap.root, n, id, c)
+	if err != nil {
+		return 0, err
+	}
+	states := countStates(root)
+	if err := c.Limits.Check(states); err != nil {
+		return 0, err
+	}
+	next := *snap
+	next.patterns = snap.patterns.set(id, &setPattern{expr: expr, node: n})
+	next.root = root
+	next.states = states
+	s.last = id
+	s.current.Store(&next)
+	return id, nil
+}
+
+// Remove removes the pattern with the ID from the set and reports whether it
+// was there. IDs of removed patterns are not given again.
+func (s *PatternSet) Remove(id PatternID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap := s.current.Load()
+	if snap.patterns.get(id) == nil {
+		return false
+	}
+	next := *snap
+	next.patterns = snap.patterns.set(id, nil)
+	next.removed++
+	if next.removed > next.patterns.len {
+		// The union keeps the removed patterns if rebuilding it fails.
+		if compacted, err := next.compact(newConfig(s.opts)); err == nil {
+			next = *compacted
+		}
+	}
+	s.current.Store(&next)
+	return true
+}
+
+// compact returns a copy of the snapshot with the union rebuilt without the
+// removed patterns.
+func (snap *PatternSetSnapshot) compact(c *config) (*PatternSetSnapshot, error) {
+	next := &PatternSetSnapshot{opts: snap.opts, patterns: snap.patterns}
+	var err error
+	snap.patterns.each(func(id PatternID, p *setPattern) {
+		if err == nil {
+			next.root, err = extend(next.root, p.node, id, c)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	next.states = countStates(next.root)
+	if err := c.Limits.Check(next.states); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// extend returns the union of the automaton rooted at root and that of n,
+// accepted by pattern id. The states of root are not modified. The limits
+// apply to the states it adds, which the new root all reaches.
+func extend(root *setState, n *dfa.Node, id PatternID, c *config) (*setState, error) {
+	type key struct {
+		s *setState
+		n *dfa.Node
+	}
+	built := make(map[key]*setState)
+	var queue []key
+	get := func(k key) *setState {
+		if k.n == nil {
+			return k.s
+		}
+		st, ok := built[k]
+		if !ok {
+			st = &setState{}
+			if k.s != nil {
+				st.accept = append(st.accept, k.s.accept...)
+			}
+			if k.n.Final {
+				st.accept = append(st.accept, id)
+			}
+			built[k] = st
+			queue = append(queue, k)
+		}
+		return st
+	}
+	start := get(key{root, n})
+	for i := 0; i < len(queue); i++ {
+		if err := c.Limits.Check(len(queue)); err != nil {
+			return nil, err
+		}
+		k := queue[i]
+		st := built[k]
+		var ranges [][]rune
+		if k.s != nil {
+			for _, m := range k.s.moves {
+				ranges = append(ranges, []rune{m.lo, m.hi})
+			}
+		}
+		for _, t := range k.n.Transitions {
+			ranges = append(ranges, t.RuneRanges)
+		}
+		pairs := runerange.Split(ranges)
+		for j := 0; j < len(pairs); j += 2 {
+			next := key{n: k.n.NextState(pairs[j : j+2])}
+			if k.s != nil {
+				next.s = k.s.next(pairs[j])
+			}
+			if next.s != nil || next.n != nil {
+				st.moves = append(st.moves, setMove{pairs[j], pairs[j+1], get(next)})
+			}
+		}
+		sort.Slice(st.moves, func(a, b int) bool { return st.moves[a].lo < st.moves[b].lo })
+	}
+	return start, nil
+}
+
+// countStates returns the number of states of the union rooted at root,
+// which the states extend replaced are no longer part of.
+func countStates(root *setState) int {
+	if root == nil {
+		return 0
+	}
+	seen := map[*setState]bool{root: true}
+	queue := []*setState{root}
+	for i := 0; i < len(queue); i++ {
+		for _, m := range queue[i].moves {
+			if !seen[m.to] {
+				seen[m.to] = true
+				queue = append(queue, m.to)
+			}
+		}
+	}
+	return len(queue)
+}
+
+func (st *setState) next(r rune) *setState {
+	i := sort.Search(len(st.moves), func(i int) bool { return st.moves[i].hi >= r })
+	if i < len(st.moves) && st.moves[i].lo <= r {
+		return st.moves[i].to
+	}
+	return nil
+}
+
+// Match returns the IDs of the patterns of the set accepting str, in
+// increasing order, from a single run of the union.
+func (s *PatternSet) Match(str string) []PatternID {
+	return s.Snapshot().Match(str)
+}
+
+// Match is PatternSet.Match on the snapshot.
+func (snap *PatternSetSnapshot) Match(str string) []PatternID {
+	st := snap.root
+	for _, r := range str {
+		if st == nil {
+			return nil
+		}
+		st = st.next(r)
+	}
+	if st == nil {
+		return nil
+	}
+	var ids []PatternID
+	for _, id := range st.accept {
+		if snap.patterns.get(id) != nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ConflictsWith returns the IDs of the patterns of the set some string
+// matches along with expr, in increasing order. It explores the product of
+// the automaton of expr with the union once, reading the patterns accepting
+// from the states of the union, rather than checking every pattern.
+func (s *PatternSet) ConflictsWith(expr string) ([]PatternID, error) {
+	return s.Snapshot().ConflictsWith(expr)
+}
+
+// ConflictsWith is PatternSet.ConflictsWith on the snapshot.
+func (snap *PatternSetSnapshot) ConflictsWith(expr string) ([]PatternID, error) {
+	n, err := Compile(expr, snap.opts...)
+	if err != nil {
+		return nil, err
+	}
+	c := newConfig(snap.opts)
+	type key struct {
+		s *setState
+		n *dfa.Node
+	}
+	found := make(map[PatternID]bool)
+	seen := make(map[key]bool)
+	var queue []key
+	visit := func(k key) {
+		if k.s != nil && k.n != nil && !seen[k] {
+			seen[k] = true
+			queue = append(queue, k)
+		}
+	}
+	visit(key{snap.root, n})
+	for i := 0; i < len(queue) && len(found) < snap.patterns.len; i++ {
+		if err := c.Limits.Check(len(queue)); err != nil {
+			return nil, err
+		}
+		k := queue[i]
+		if k.n.Final {
+			for _, id := range k.s.accept {
+				if snap.patterns.get(id) != nil {
+					found[id] = true
+				}
+			}
+		}
+		for _, t := range k.n.Transitions {
+			for _, m := range k.s.moves {
+				if r := runerange.Intersect(t.RuneRanges, []rune{m.lo, m.hi}); len(r) > 0 {
+					visit(key{m.to, t.Node})
+				}
+			}
+		}
+	}
+	ids := make([]PatternID, 0, len(found))
+	for id := range found {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// Pattern returns the pattern with the ID, if it is in the set.
+func (s *PatternSet) Pattern(id PatternID) (string, bool) {
+	return s.Snapshot().Pattern(id)
+}
+
+// Pattern is PatternSet.Pattern on the snapshot.
+func (snap *PatternSetSnapshot) Pattern(id PatternID) (string, bool) {
+	p := snap.patterns.get(id)
+	if p == nil {
+		return "", false
+	}
+	return p.expr, true
+}
+
+// Len returns the number of patterns.
+func (s *PatternSet) Len() int {
+	return s.Snapshot().Len()
+}
+
+// Len is PatternSet.Len on the snapshot.
+func (snap *PatternSetSnapshot) Len() int {
+	return snap.patterns.len
+}
+
+// States returns the number of states of the union automaton, including
+// those only removed patterns reach until it is rebuilt.
+func (s *PatternSet) States() int {
+	return s.Snapshot().States()
+}
+
+// States is PatternSet.States on the snapshot.
+func (snap *PatternSetSnapshot) States() int {
+	return snap.states
+}
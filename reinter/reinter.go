@@ -0,0 +1,200 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package reinter compiles regular expressions into automata and compares
+// them, configured by functional options.
+package reinter
+
+import (
+	"errors"
+	"io"
+	"iter"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/intersection"
+)
+
+// ErrBudgetExceeded is wrapped by the errors returned when an operation
+// exceeds the MaxStates or Timeout options.
+var ErrBudgetExceeded = intersection.ErrBudgetExceeded
+
+// ErrUnsupportedConstruct is returned for patterns using constructs that
+// have no finite automaton equivalent, see OverApproximate.
+type ErrUnsupportedConstruct = intersection.ErrUnsupportedConstruct
+
+// ErrLimitExceeded is returned for patterns exceeding the MaxNFANodes,
+// MaxRepeat or MaxNesting options, or the Limits given to Validate.
+type ErrLimitExceeded = intersection.ErrLimitExceeded
+
+// ErrNoWitnessInAlphabet is returned for patterns intersecting only outside
+// of the WitnessAlphabet option.
+var ErrNoWitnessInAlphabet = intersection.ErrNoWitnessInAlphabet
+
+// ErrEmptyPattern is returned for the empty pattern "" under EmptyIsError.
+var ErrEmptyPattern = errors.New("empty pattern")
+
+// EmptyLanguage returns a pattern that matches no string at all, not even
+// the empty one.
+func EmptyLanguage() string {
+	return dfa.NoMatch
+}
+
+// EmptyString returns a pattern that matches the empty string only.
+func EmptyString() string {
+	return "(?:)"
+}
+
+// Compile compiles the pattern into a DFA.
+func Compile(expr string, opts ...Option) (*dfa.Node, error) {
+	c := newConfig(opts)
+	expr, err := c.pattern(expr)
+	if err != nil {
+		return nil, err
+	}
+	return intersection.Compile(expr, c.Options)
+}
+
+// NormalizePattern returns the pattern the automaton of expr is actually
+// built from: capture groups are removed, inline flags are expanded and
+// classes are spelled out in a canonical form. Patterns with the same
+// normalized form have the same automaton, which makes it usable as a cache
+// key.
+func NormalizePattern(expr string, opts ...Option) (string, error) {
+	c := newConfig(opts)
+	expr, err := c.pattern(expr)
+	if err != nil {
+		return "", err
+	}
+	return intersection.Normalize(expr, c.Options)
+}
+
+// Simplify returns the shortest of the normalized pattern, see
+// NormalizePattern, and the pattern synthesized from the minimal DFA of expr.
+// The result matches on its own the strings expr matches under the given
+// options. RedundantAlternatives tells which alternatives could go.
+func Simplify(expr string, opts ...Option) (string, error) {
+	c := newConfig(opts)
+	expr, err := c.pattern(expr)
+	if err != nil {
+		return "", err
+	}
+	normalized, err := intersection.Normalize(expr, c.Options)
+	if err != nil {
+		return "", err
+	}
+	c.Minimize = true
+	n, err := intersection.Compile(expr, c.Options)
+	if err != nil {
+		return "", err
+	}
+	synthesized, err := dfa.ToRegex(n)
+	if err != nil || len(synthesized) >= len(normalized) {
+		return normalized, err
+	}
+	return synthesized, nil
+}
+
+// RedundantAlternatives returns the alternatives of expr that other
+// alternatives of the same alternation already cover, such as both a in
+// (a|a|ab?), from the innermost alternations out.
+func RedundantAlternatives(expr string, opts ...Option) ([]string, error) {
+	c := newConfig(opts)
+	expr, err := c.pattern(expr)
+	if err != nil {
+		return nil, err
+	}
+	branches, err := intersection.Redundant(expr, c.Options)
+	var texts []string
+	for _, b := range branches {
+		texts = append(texts, b.Text)
+	}
+	return texts, err
+}
+
+// Literals returns strings every string expr matches has to contain: their
+// longest common prefix and suffix and the longest other substrings they all
+// contain. Callers can look for these with cheap searches such as
+// strings.Index to rule out inputs before running the automaton on them.
+func Literals(expr string, opts ...Option) (prefix, suffix string, substrings []string, err error) {
+	n, err := Compile(expr, opts...)
+	if err != nil {
+		return "", "", nil, err
+	}
+	prefix, suffix, substrings = dfa.Literals(n)
+	return prefix, suffix, substrings, nil
+}
+
+// AsLiteral returns the only string expr matches, if it matches exactly one,
+// so that rule engines can look such patterns up in a map instead of running
+// automata.
+func AsLiteral(expr string, opts ...Option) (string, bool, error) {
+	n, err := Compile(expr, opts...)
+	if err != nil {
+		return "", false, err
+	}
+	s, ok := dfa.Literal(n)
+	return s, ok, nil
+}
+
+// AnyMatch returns the first of the lines expr matches, compiling it once
+// for the whole corpus, and whether there is one. Lines are matched as a
+// whole unless MatchSemantics says otherwise.
+func AnyMatch(expr string, lines iter.Seq[string], opts ...Option) (bool, string, error) {
+	n, err := Compile(expr, opts...)
+	if err != nil {
+		return false, "", err
+	}
+	table := dfa.Freeze(n)
+	for line := range lines {
+		if table.Match(line) {
+			return true, line, nil
+		}
+	}
+	return false, "", nil
+}
+
+// HasIntersection reports whether the patterns accept a common string.
+func HasIntersection(expr1, expr2 string, opts ...Option) (bool, error) {
+	result, err := Check(expr1, expr2, opts...)
+	return result.Intersects, err
+}
+
+// Check reports whether the patterns accept a common string along with the
+// shortest such string.
+func Check(expr1, expr2 string, opts ...Option) (intersection.Result, error) {
+	c := newConfig(opts)
+	expr1, err := c.pattern(expr1)
+	if err != nil {
+		return intersection.Result{}, err
+	}
+	expr2, err = c.pattern(expr2)
+	if err != nil {
+		return intersection.Result{}, err
+	}
+	return intersection.CheckWithOptions(expr1, expr2, c.Options)
+}
+
+// WriteHTML writes a self-contained HTML page drawing the DFAs of both
+// patterns and their product, with the shortest common string highlighted.
+func WriteHTML(w io.Writer, expr1, expr2 string, opts ...Option) error {
+	c := newConfig(opts)
+	expr1, err := c.pattern(expr1)
+	if err != nil {
+		return err
+	}
+	expr2, err = c.pattern(expr2)
+	if err != nil {
+		return err
+	}
+	return intersection.WriteHTML(w, expr1, expr2, c.Options)
+}
@@ -0,0 +1,643 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reinter
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptions(t *testing.T) {
+	type Case struct {
+		Expr1  string
+		Expr2  string
+		Opts   []Option
+		Expect bool
+	}
+	cases := []Case{
+		{"[A-Z]+", "[a-z]+", nil, false},
+		{"[A-Z]+", "[a-z]+", []Option{CaseInsensitive(true)}, true},
+		{`\d+`, "[٠-٩]+", nil, false},
+		{`\d+`, "[٠-٩]+", []Option{UnicodeMode(true)}, true},
+		{"a+", "a*", []Option{MaxStates(10), Timeout(time.Minute), MatchSemantics(FullMatch)}, true},
+		{"ab(?=c)", "ab", []Option{OverApproximate(true)}, true},
+		{"(a|b)*abb", "(a|b)*a(a|b)", []Option{MinimizeFirst(true)}, false},
+		{"/api/v1", "/api/v1/users", nil, false},
+		{"/api/v1", "/api/v1/users", []Option{MatchSemantics(PrefixOverlap)}, true},
+		{"/api/v1", "/api/v1/users", []Option{MatchSemantics(PrefixOverlap), OnTheFly(true)}, true},
+		{"/api/v1/users", "/api/v2", []Option{MatchSemantics(PrefixOverlap)}, false},
+		{"foo", "foobar-ish", nil, false},
+		{"foo", "foobar-ish", []Option{MatchSemantics(SubstringMatch)}, true},
+		{"foo", "x+", []Option{MatchSemantics(SubstringMatch)}, true},
+		{"^foo", "^bar$", []Option{MatchSemantics(SubstringMatch), OnTheFly(true)}, false},
+		{"bar", "[a-z]*-ish", []Option{MatchSemantics(SubstringMatch), OnTheFly(true)}, true},
+		{"^foo", "foo", nil, true},
+		{"^foo", "foo", []Option{MatchSemantics(SubstringMatch)}, true},
+		{"^foo", "foo", []Option{MatchSemantics(SubstringMatch), OnTheFly(true)}, true},
+		{"^foo$", "bar", []Option{MatchSemantics(SubstringMatch)}, false},
+		{"[^a]", "é", nil, true},
+		{"[^a]", "é", []Option{ASCIIOnly(true)}, false},
+		{"/users/[^/]+", "/users/[a-z]+", []Option{ASCIIOnly(true)}, true},
+	}
+
+	for _, c := range cases {
+		got, err := HasIntersection(c.Expr1, c.Expr2, c.Opts...)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Expect, got, "%q vs %q", c.Expr1, c.Expr2)
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	var unsupported *ErrUnsupportedConstruct
+	_, err := HasIntersection("ab(?=c)", "ab")
+	if assert.True(t, errors.As(err, &unsupported)) {
+		assert.Equal(t, "lookahead", unsupported.Construct)
+		assert.Equal(t, 2, unsupported.Pos)
+	}
+}
+
+func TestNormalizePattern(t *testing.T) {
+	type Case struct {
+		Expr   string
+		Opts   []Option
+		Expect string
+	}
+	cases := []Case{
+		{"(a)(?P<x>b+?)", nil, "ab+?"},
+		{"(?i)ab[c-e]", nil, "[Aa][Bb][C-Ec-e]"},
+		{"ab", []Option{CaseInsensitive(true)}, "[Aa][Bb]"},
+		{"(?i)1", nil, "1"},
+		{`/api/v(\d)`, nil, "/api/v[0-9]"},
+		{"(?s:(.))x", nil, "(?s:.x)"},
+		{"[a-c]{2}", nil, "[a-c][a-c]"},
+		{"ab(?=c)", []Option{OverApproximate(true)}, "ab(?:)"},
+		{"/api", []Option{MatchSemantics(PrefixOverlap)}, "(?s:/api.*)"},
+		{"", []Option{InterpretEmpty(EmptyIsEmptyLanguage)}, EmptyLanguage()},
+		{"é", []Option{ASCIIOnly(true)}, EmptyLanguage()},
+		{"a.[^a]", []Option{ASCIIOnly(true)}, "a[ -~][\\x00-`b-\\x7f]"},
+	}
+
+	for _, c := range cases {
+		got, err := NormalizePattern(c.Expr, c.Opts...)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Expect, got, c.Expr)
+	}
+
+	_, err := NormalizePattern("ab(?=c)")
+	assert.Error(t, err)
+}
+
+func TestBudget(t *testing.T) {
+	_, err := Compile("(a|b)*a(a|b)(a|b)(a|b)(a|b)", MaxStates(8))
+	assert.True(t, errors.Is(err, ErrBudgetExceeded))
+
+	_, err = HasIntersection("[a-z]*x[a-z]{3}", "[a-z]*y[a-z]{3}", MaxStates(16))
+	assert.True(t, errors.Is(err, ErrBudgetExceeded))
+
+	_, err = HasIntersection("[a-z]*x[a-z]{3}", "[a-z]*y[a-z]{3}", Timeout(time.Nanosecond))
+	assert.True(t, errors.Is(err, ErrBudgetExceeded))
+
+	_, err = HasIntersection("[a-z]*x[a-z]{3}", "[a-z]*y[a-z]{3}")
+	assert.NoError(t, err)
+}
+
+func TestOnProgress(t *testing.T) {
+	var reports []Progress
+	_, err := HasIntersection("[ab]*a[ab]{10}", "[ab]*b[ab]{10}", OnProgress(func(p Progress) error {
+		reports = append(reports, p)
+		return nil
+	}))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, reports)
+
+	abort := errors.New("abort")
+	for _, opt := range []Option{OnTheFly(false), OnTheFly(true)} {
+		_, err = HasIntersection("[ab]*a[ab]{10}", "[ab]*b[ab]{10}", opt, OnProgress(func(Progress) error { return abort }))
+		assert.Equal(t, abort, err)
+	}
+}
+
+func TestPatternLimits(t *testing.T) {
+	var limit *ErrLimitExceeded
+	_, err := Compile("(x{10}){10}", MaxRepeat(50))
+	assert.True(t, errors.As(err, &limit))
+	assert.Equal(t, "MaxRepeat", limit.Limit)
+
+	_, err = HasIntersection("a", "((((a))))", MaxNesting(3))
+	assert.True(t, errors.As(err, &limit))
+	assert.Equal(t, "MaxNesting", limit.Limit)
+
+	_, err = Compile("[a-z]{6}", MaxNFANodes(8))
+	assert.True(t, errors.As(err, &limit))
+	assert.Equal(t, "MaxNFANodes", limit.Limit)
+
+	_, err = Compile("(x{10}){10}", MaxRepeat(100), MaxNesting(5), MaxNFANodes(1000))
+	assert.NoError(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	type Case struct {
+		Expr   string
+		Limits Limits
+		Opts   []Option
+		Limit  string // empty if the pattern is admitted
+	}
+	cases := []Case{
+		{"[a-z]+@example\\.com", Limits{MaxNFANodes: 100, MaxDFAStates: 100}, nil, ""},
+		{"[a-z]{100}", Limits{MaxNFANodes: 100}, nil, "MaxNFANodes"},
+		{"(a{10}){10}", Limits{MaxRepeat: 50}, nil, "MaxRepeat"},
+		{"(a{10}){10}", Limits{}, []Option{MaxRepeat(50)}, "MaxRepeat"},
+		{"((((a))))", Limits{MaxNesting: 3}, nil, "MaxNesting"},
+		{"[ab]*a[ab]{12}", Limits{MaxNFANodes: 1000, MaxDFAStates: 1000}, nil, "MaxStates"},
+		{"[ab]*a[ab]{12}", Limits{MaxNFANodes: 1000, MaxDFAStates: 10000}, nil, ""},
+		// The DFA, of 2^41 states, is never built.
+		{"[ab]*a[ab]{40}", Limits{MaxDFAStates: 1000000}, nil, "MaxStates"},
+		{"[ab]*a[ab]{40}", Limits{MaxDFAStates: 1000000}, []Option{Timeout(time.Nanosecond)}, "MaxStates"},
+	}
+
+	for _, c := range cases {
+		err := Validate(c.Expr, c.Limits, c.Opts...)
+		if c.Limit == "" {
+			assert.NoError(t, err, c.Expr)
+			continue
+		}
+		var limit *ErrLimitExceeded
+		if assert.True(t, errors.As(err, &limit), c.Expr) {
+			assert.Equal(t, c.Limit, limit.Limit, c.Expr)
+		}
+	}
+
+	assert.Error(t, Validate("a(", Limits{}))
+}
+
+func TestEmpty(t *testing.T) {
+	type Case struct {
+		Expr1  string
+		Expr2  string
+		Opts   []Option
+		Expect bool
+	}
+	cases := []Case{
+		{"", "", nil, true},
+		{"", "a", nil, false},
+		{"", "a*", nil, true},
+		{"", "a*", []Option{InterpretEmpty(EmptyIsEmptyLanguage)}, false},
+		{EmptyString(), "a*", []Option{InterpretEmpty(EmptyIsEmptyLanguage)}, true},
+		{EmptyLanguage(), EmptyLanguage(), nil, false},
+		{EmptyLanguage(), ".*", nil, false},
+		{EmptyLanguage(), ".*", []Option{OnTheFly(true)}, false},
+		{"(?i)" + EmptyLanguage(), ".*", nil, false},
+		{"a|" + EmptyLanguage(), "a", nil, true},
+		{EmptyString(), "", nil, true},
+	}
+
+	for _, c := range cases {
+		got, err := HasIntersection(c.Expr1, c.Expr2, c.Opts...)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Expect, got, "%q vs %q", c.Expr1, c.Expr2)
+	}
+
+	_, err := HasIntersection("", "a", InterpretEmpty(EmptyIsError))
+	assert.True(t, errors.Is(err, ErrEmptyPattern))
+	_, err = Compile("", InterpretEmpty(EmptyIsError))
+	assert.True(t, errors.Is(err, ErrEmptyPattern))
+}
+
+func TestSimplify(t *testing.T) {
+	type Case struct {
+		Expr   string
+		Opts   []Option
+		Expect string
+	}
+	cases := []Case{
+		{"(a|a|ab?)", nil, "ab?"},
+		{"(?:a|b|c)+", nil, "[a-c]+"},
+		{"a*a*", nil, "a*"},
+		{"(a+)+b", nil, "a+b"},
+		{"ab", []Option{CaseInsensitive(true)}, "[Aa][Bb]"},
+	}
+	for _, c := range cases {
+		got, err := Simplify(c.Expr, c.Opts...)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Expect, got, c.Expr)
+	}
+
+	_, err := Simplify("a(")
+	assert.Error(t, err)
+}
+
+func TestRedundantAlternatives(t *testing.T) {
+	type Case struct {
+		Expr   string
+		Expect []string
+	}
+	cases := []Case{
+		{"(a|a|ab?)", []string{"a", "a"}},
+		{"a|b", nil},
+		{"/api/(v1|v[0-9])/x", []string{"v1"}},
+		{"(?i)x|X", []string{"(?i)x"}},
+		{"[a-c]+|b|d", []string{"b"}},
+		{"(b|c)|[a-z]", []string{"(b|c)"}},
+	}
+	for _, c := range cases {
+		got, err := RedundantAlternatives(c.Expr)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Expect, got, c.Expr)
+	}
+
+	_, err := RedundantAlternatives("a|(")
+	assert.Error(t, err)
+}
+
+func TestLiterals(t *testing.T) {
+	type Case struct {
+		Expr       string
+		Prefix     string
+		Suffix     string
+		Substrings []string
+	}
+	cases := []Case{
+		{"GET /api/[a-z]+\\.json", "GET /api/", ".json", nil},
+		{".*error: .*timeout.*", "", "", []string{"error: ", "timeout"}},
+		{"a|b", "", "", nil},
+	}
+	for _, c := range cases {
+		prefix, suffix, substrings, err := Literals(c.Expr)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Prefix, prefix, c.Expr)
+		assert.Equal(t, c.Suffix, suffix, c.Expr)
+		assert.Equal(t, c.Substrings, substrings, c.Expr)
+	}
+
+	_, _, _, err := Literals("(")
+	assert.Error(t, err)
+}
+
+func TestInspect(t *testing.T) {
+	type Case struct {
+		Expr   string
+		Opts   []Option
+		Expect Info
+	}
+	cases := []Case{
+		{"/api/v[0-9]", nil, Info{AnchoredStart: true, AnchoredEnd: true, MinLength: 7, MaxLength: 7}},
+		{"/api/v[0-9]", []Option{MatchSemantics(PrefixOverlap)}, Info{AnchoredStart: true, MinLength: 7, MaxLength: -1}},
+		{"(?s).*error.*", nil, Info{MinLength: 5, MaxLength: -1}},
+		{"(?s)(a|.*b)", nil, Info{AnchoredStart: true, AnchoredEnd: true, MinLength: 1, MaxLength: -1}},
+		{"(?s)x?.*", nil, Info{MatchesEmpty: true, MaxLength: -1}},
+		{"a?", nil, Info{AnchoredStart: true, AnchoredEnd: true, MatchesEmpty: true, MaxLength: 1}},
+	}
+	for _, c := range cases {
+		got, err := Inspect(c.Expr, c.Opts...)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Expect, got, c.Expr)
+	}
+
+	_, err := Inspect("(")
+	assert.Error(t, err)
+}
+
+func TestAsLiteral(t *testing.T) {
+	type Case struct {
+		Expr   string
+		Opts   []Option
+		Expect string
+		OK     bool
+	}
+	cases := []Case{
+		{"/api/v1/users", nil, "/api/v1/users", true},
+		{"/api/(v1)/users", nil, "/api/v1/users", true},
+		{"/api/v1/users", []Option{MatchSemantics(PrefixOverlap)}, "", false},
+		{"/api/v1/user", []Option{CaseInsensitive(true)}, "", false},
+		{"/api/v[12]", nil, "", false},
+	}
+	for _, c := range cases {
+		got, ok, err := AsLiteral(c.Expr, c.Opts...)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Expect, got, c.Expr)
+		assert.Equal(t, c.OK, ok, c.Expr)
+	}
+
+	_, _, err := AsLiteral("(")
+	assert.Error(t, err)
+}
+
+func TestWithLogger(t *testing.T) {
+	var events []dfa.Event
+	_, err := HasIntersection("a+", "b+", WithLogger(dfa.LoggerFunc(func(e dfa.Event) {
+		events = append(events, e)
+	})))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, events)
+}
+
+func TestAnyMatch(t *testing.T) {
+	type Case struct {
+		Expr  string
+		Opts  []Option
+		Match bool
+		Line  string
+	}
+	traffic := []string{"GET /health", "POST /api/v1/users", "GET /admin/login", "DELETE /api/v1/users/7"}
+	cases := []Case{
+		{"GET /admin/.*", nil, true, "GET /admin/login"},
+		{"(POST|DELETE) /api/.*", nil, true, "POST /api/v1/users"},
+		{"admin", nil, false, ""},
+		{"admin", []Option{MatchSemantics(SubstringMatch)}, true, "GET /admin/login"},
+		{"PUT .*", nil, false, ""},
+	}
+	for _, c := range cases {
+		ok, line, err := AnyMatch(c.Expr, slices.Values(traffic), c.Opts...)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Match, ok, c.Expr)
+		assert.Equal(t, c.Line, line, c.Expr)
+	}
+
+	// The corpus is not read past the first match.
+	read := 0
+	lines := func(yield func(string) bool) {
+		for _, l := range traffic {
+			read++
+			if !yield(l) {
+				return
+			}
+		}
+	}
+	_, _, err := AnyMatch("GET .*", lines)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, read)
+
+	_, _, err = AnyMatch("(", slices.Values(traffic))
+	assert.Error(t, err)
+}
+
+func TestReadableWitness(t *testing.T) {
+	result, err := Check(`[^a-z]+`, `.{2}`, ReadableWitness(true))
+	assert.NoError(t, err)
+	assert.Equal(t, "AA", result.Witness)
+	result, err = Check(`[^a-z]+`, `.{2}`, ReadableWitness(true), OnTheFly(true))
+	assert.NoError(t, err)
+	assert.Equal(t, "AA", result.Witness)
+}
+
+func TestWitnessAlphabet(t *testing.T) {
+	result, err := Check(`/files/.+`, `/files/[^a-z]+`, WitnessAlphabet(URLSafe+"/"))
+	assert.NoError(t, err)
+	assert.Equal(t, "/files/-", result.Witness)
+	_, err = Check(`/files/.+`, `/files/[^a-z/]+`, WitnessAlphabet("/abcdefghijklmnopqrstuvwxyz"))
+	assert.ErrorIs(t, err, ErrNoWitnessInAlphabet)
+	result, err = Check(`.+`, `[^a-z]+`, WitnessAlphabet(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "\x00", result.Witness)
+}
+
+func TestCompare(t *testing.T) {
+	type Case struct {
+		Expr1  string
+		Expr2  string
+		Opts   []Option
+		Expect Relation
+	}
+	cases := []Case{
+		{"/api/users", "/api/[a-z]+", nil, Subset},
+		{"/api/.*", "/api/v1", nil, Superset},
+		{"(a|b)*", "(a*b*)*", nil, Equal},
+		{"/api/v[12]/.*", "/api/v[23]/.*", nil, Overlapping},
+		{"/api/.*", "/health", nil, Disjoint},
+		{"/api", "/api/v1", []Option{MatchSemantics(PrefixOverlap)}, Superset},
+		{"ADMIN", "admin", []Option{CaseInsensitive(true)}, Equal},
+		{EmptyLanguage(), "a", nil, Subset},
+	}
+	for _, c := range cases {
+		got, err := Compare(c.Expr1, c.Expr2, c.Opts...)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Expect, got, "%s %s", c.Expr1, c.Expr2)
+	}
+	assert.Equal(t, "overlapping", Overlapping.String())
+
+	_, err := Compare("a", "(")
+	assert.Error(t, err)
+}
+
+func TestPatternSet(t *testing.T) {
+	type Case struct {
+		Input  string
+		Expect []PatternID
+	}
+	set := NewPatternSet()
+	for i, expr := range []string{"/api/users", "/api/[a-z]+", "/api/.*", "/health", "/api/v[0-9]+/.*", EmptyLanguage()} {
+		id, err := set.Add(expr)
+		assert.NoError(t, err)
+		assert.Equal(t, PatternID(i+1), id)
+	}
+	cases := []Case{
+		{"/api/users", []PatternID{1, 2, 3}},
+		{"/api/items", []PatternID{2, 3}},
+		{"/api/v2/items", []PatternID{3, 5}},
+		{"/api/", []PatternID{3}},
+		{"/health", []PatternID{4}},
+		{"/healthz", nil},
+		{"", nil},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.Expect, set.Match(c.Input), c.Input)
+	}
+	assert.Equal(t, 6, set.Len())
+	expr, ok := set.Pattern(2)
+	assert.True(t, ok)
+	assert.Equal(t, "/api/[a-z]+", expr)
+	_, ok = set.Pattern(7)
+	assert.False(t, ok)
+
+	// Unrelated patterns leave the states of the union alone.
+	states := set.States()
+	_, err := set.Add("/metrics")
+	assert.NoError(t, err)
+	assert.Less(t, set.States()-states, 10)
+
+	_, err = set.Add("(")
+	assert.Error(t, err)
+	limited := NewPatternSet(MaxStates(9))
+	_, err = limited.Add("/api/[a-z]+")
+	assert.NoError(t, err)
+	_, err = limited.Add("/api/[0-9]+/[a-z]+")
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.Equal(t, 1, limited.Len())
+	assert.Equal(t, []PatternID{1}, limited.Match("/api/x"))
+
+	// The limit applies to the states of the union, not to those added.
+	limited = NewPatternSet(MaxStates(9))
+	for i := 1; i <= 7; i++ {
+		_, err = limited.Add("x" + strconv.Itoa(i))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 9, limited.States())
+}
+
+func TestConflictsWith(t *testing.T) {
+	type Case struct {
+		Expr   string
+		Expect []PatternID
+	}
+	exprs := []string{"/api/users", "/api/[a-z]+", "/api/.*", "/health", "/api/v[0-9]+/.*", EmptyLanguage()}
+	set := NewPatternSet()
+	for _, expr := range exprs {
+		_, err := set.Add(expr)
+		assert.NoError(t, err)
+	}
+	cases := []Case{
+		{"/api/u.*", []PatternID{1, 2, 3}},
+		{"/api/v1/.*", []PatternID{3, 5}},
+		{"/api/[0-9]+", []PatternID{3}},
+		{"/.*", []PatternID{1, 2, 3, 4, 5}},
+		{"/metrics", []PatternID{}},
+		{EmptyLanguage(), []PatternID{}},
+	}
+	for _, c := range cases {
+		got, err := set.ConflictsWith(c.Expr)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Expect, got, c.Expr)
+
+		// The union agrees with checking the patterns one by one.
+		var want []PatternID
+		for i, expr := range exprs {
+			if ok, _ := HasIntersection(expr, c.Expr); ok {
+				want = append(want, PatternID(i+1))
+			}
+		}
+		assert.ElementsMatch(t, want, got, c.Expr)
+	}
+
+	ids, err := NewPatternSet().ConflictsWith(".*")
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+	_, err = set.ConflictsWith("(")
+	assert.Error(t, err)
+}
+
+func TestPatternSetRemove(t *testing.T) {
+	set := NewPatternSet()
+	for _, expr := range []string{"/api/users", "/api/[a-z]+", "/api/.*", "/health"} {
+		_, err := set.Add(expr)
+		assert.NoError(t, err)
+	}
+	states := set.States()
+	assert.True(t, set.Remove(1))
+	assert.False(t, set.Remove(1))
+	assert.False(t, set.Remove(9))
+	assert.True(t, set.Remove(3))
+	assert.Equal(t, []PatternID{2}, set.Match("/api/users"))
+	assert.Nil(t, set.Match("/api/"))
+	ids, err := set.ConflictsWith("/.*")
+	assert.NoError(t, err)
+	assert.Equal(t, []PatternID{2, 4}, ids)
+	_, ok := set.Pattern(3)
+	assert.False(t, ok)
+	assert.Equal(t, 2, set.Len())
+	assert.Equal(t, states, set.States())
+
+	// Once the removed patterns outnumber the others, the union is rebuilt.
+	assert.True(t, set.Remove(2))
+	ids, err = set.ConflictsWith("/.*")
+	assert.NoError(t, err)
+	assert.Equal(t, []PatternID{4}, ids)
+	assert.Less(t, set.States(), states)
+	assert.Equal(t, []PatternID{4}, set.Match("/health"))
+
+	id, err := set.Add("/api/.*")
+	assert.NoError(t, err)
+	assert.Equal(t, PatternID(5), id)
+	assert.Equal(t, []PatternID{5}, set.Match("/api/users"))
+	assert.True(t, set.Remove(4))
+	assert.True(t, set.Remove(5))
+	assert.Nil(t, set.Match("/health"))
+	assert.Equal(t, 0, set.States())
+}
+
+func TestPatternMap(t *testing.T) {
+	var m patternMap
+	var old []patternMap
+	for id := PatternID(1); id <= 2000; id++ {
+		old = append(old, m)
+		m = m.set(id, &setPattern{expr: strconv.Itoa(int(id))})
+	}
+	for id := PatternID(2); id <= 2000; id += 2 {
+		m = m.set(id, nil)
+	}
+	assert.Equal(t, 1000, m.len)
+	assert.Nil(t, m.get(2))
+	assert.Nil(t, m.get(2001))
+	assert.Equal(t, "1999", m.get(1999).expr)
+	var ids []PatternID
+	m.each(func(id PatternID, p *setPattern) {
+		assert.Equal(t, strconv.Itoa(int(id)), p.expr)
+		ids = append(ids, id)
+	})
+	assert.Len(t, ids, 1000)
+	assert.True(t, slices.IsSorted(ids))
+
+	// Earlier maps are left as they were.
+	assert.Equal(t, 1000, old[1000].len)
+	assert.Equal(t, "1000", old[1000].get(1000).expr)
+	assert.Nil(t, old[1000].get(1001))
+}
+
+func TestPatternSetConcurrentUse(t *testing.T) {
+	set := NewPatternSet()
+	base, err := set.Add("/api/.*")
+	assert.NoError(t, err)
+	snap := set.Snapshot()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			id, err := set.Add("/api/v" + strconv.Itoa(i) + "/[a-z]+")
+			assert.NoError(t, err)
+			if i%2 == 0 {
+				assert.True(t, set.Remove(id))
+			}
+		}
+	}()
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				ids, err := set.ConflictsWith("/api/v1/.*")
+				assert.NoError(t, err)
+				assert.Contains(t, ids, base)
+				assert.Contains(t, set.Match("/api/v3/x"), base)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The snapshot stays as it was taken.
+	assert.Equal(t, 1, snap.Len())
+	assert.Equal(t, []PatternID{base}, snap.Match("/api/v1/x"))
+	assert.Equal(t, 26, set.Len())
+	assert.Equal(t, []PatternID{base, 3}, set.Match("/api/v1/x"))
+	ids, err := set.ConflictsWith("/api/v[0-9]/x")
+	assert.NoError(t, err)
+	assert.Equal(t, []PatternID{base, 3, 5, 7, 9, 11}, ids)
+}
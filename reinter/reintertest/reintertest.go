@@ -0,0 +1,75 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package reintertest compares automata against golden files, for
+// regression tests of their construction.
+package reintertest
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/reinter"
+)
+
+// update makes the assertions rewrite the golden files instead of comparing
+// them, as in go test -reintertest.update.
+var update = flag.Bool("reintertest.update", false, "rewrite the golden files of package reintertest")
+
+// Render returns the canonical text form of the automaton: the table
+// dfa.DumpTable writes for its minimal automaton, whose states are numbered
+// in a fixed order. Automata accepting the same strings have the same form.
+func Render(n *dfa.Node) string {
+	var b bytes.Buffer
+	dfa.DumpTable(&b, dfa.Minimize(n))
+	return b.String()
+}
+
+// AssertGolden fails the test if the canonical form of the automaton differs
+// from the content of the golden file. With -reintertest.update it writes the
+// file instead, creating its directory if needed.
+func AssertGolden(t testing.TB, golden string, n *dfa.Node) {
+	t.Helper()
+	got := Render(n)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v (run the test with -reintertest.update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("automaton differs from %s:\n%s\nwant:\n%s", golden, got, want)
+	}
+}
+
+// AssertGoldenPattern is AssertGolden for the automaton of a pattern
+// compiled with the given options.
+func AssertGoldenPattern(t testing.TB, golden, expr string, opts ...reinter.Option) {
+	t.Helper()
+	n, err := reinter.Compile(expr, opts...)
+	if err != nil {
+		t.Fatalf("Compile(%q): %v", expr, err)
+	}
+	AssertGolden(t, golden, n)
+}
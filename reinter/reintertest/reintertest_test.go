@@ -0,0 +1,54 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reintertest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/oulinbao/regexinter/reinter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGolden(t *testing.T) {
+	type Case struct {
+		Name string
+		Expr string
+		Opts []reinter.Option
+	}
+	cases := []Case{
+		{"literal", "abc", nil},
+		{"class", "[a-f0-9]+", nil},
+		{"alternation", "foo|bar|baz", nil},
+		{"anchors", "^a*$", nil},
+		{"boundary", `a\b.*`, nil},
+		{"fold", "ab", []reinter.Option{reinter.CaseInsensitive(true)}},
+		{"prefix", "/api/v[12]", []reinter.Option{reinter.MatchSemantics(reinter.PrefixOverlap)}},
+		{"repeat", "(a|b)*a(a|b){2}", nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			AssertGoldenPattern(t, filepath.Join("testdata", c.Name+".golden"), c.Expr, c.Opts...)
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	n1, err := reinter.Compile("(a|b)*")
+	assert.NoError(t, err)
+	n2, err := reinter.Compile("(a*b*)*")
+	assert.NoError(t, err)
+	assert.Equal(t, Render(n1), Render(n2))
+}
@@ -0,0 +1,103 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package selftest cross-checks the automata of package reinter against
+// package regexp by brute force, for use in the tests of pattern corpora.
+package selftest
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/reinter"
+)
+
+// Discrepancy is a string on which package regexp and package reinter
+// disagree.
+type Discrepancy struct {
+	Expr1, Expr2 string
+	Input        string
+	Regexp       [2]bool // package regexp matches Input against the patterns
+	Automaton    [2]bool // the automata of the patterns accept Input
+	Intersects   bool    // decision of the intersection check
+}
+
+func (d *Discrepancy) String() string {
+	return fmt.Sprintf("%q vs %q on %q: regexp matches %v, automata accept %v, intersection %v",
+		d.Expr1, d.Expr2, d.Input, d.Regexp, d.Automaton, d.Intersects)
+}
+
+// CrossCheck enumerates the strings of up to maxLen runes of the alphabet
+// and compares, for each of them, package regexp with the automata of the
+// patterns and with the decision of the intersection check. It also checks
+// the witness of the intersection. The patterns are matched as a whole. It
+// returns the first discrepancy found, or nil if there is none. An error is
+// returned if a pattern does not compile.
+func CrossCheck(expr1, expr2 string, alphabet []rune, maxLen int) (*Discrepancy, error) {
+	var res [2]*regexp.Regexp
+	var nodes [2]*dfa.Node
+	for i, expr := range []string{expr1, expr2} {
+		var err error
+		if nodes[i], err = reinter.Compile(expr); err != nil {
+			return nil, err
+		}
+		if res[i], err = regexp.Compile("^(?:" + expr + ")$"); err != nil {
+			return nil, err
+		}
+	}
+	result, err := reinter.Check(expr1, expr2)
+	if err != nil {
+		return nil, err
+	}
+
+	observe := func(s string) *Discrepancy {
+		d := &Discrepancy{Expr1: expr1, Expr2: expr2, Input: s, Intersects: result.Intersects}
+		for i := range nodes {
+			d.Regexp[i] = res[i].MatchString(s)
+			d.Automaton[i] = nodes[i].Match(s)
+		}
+		return d
+	}
+
+	if result.Intersects {
+		if d := observe(result.Witness); d.Regexp != [2]bool{true, true} || d.Automaton != d.Regexp {
+			return d, nil
+		}
+	}
+
+	// Count through the strings like an odometer, shortest first.
+	digits := make([]int, 0, maxLen)
+	s := make([]rune, 0, maxLen)
+	for {
+		d := observe(string(s))
+		if d.Regexp != d.Automaton || d.Regexp == [2]bool{true, true} && !d.Intersects {
+			return d, nil
+		}
+		i := len(digits) - 1
+		for ; i >= 0 && digits[i] == len(alphabet)-1; i-- {
+			digits[i] = 0
+			s[i] = alphabet[0]
+		}
+		if i >= 0 {
+			digits[i]++
+			s[i] = alphabet[digits[i]]
+			continue
+		}
+		if len(digits) == maxLen || len(alphabet) == 0 {
+			return nil, nil
+		}
+		digits = append(digits, 0)
+		s = append(s, alphabet[0])
+	}
+}
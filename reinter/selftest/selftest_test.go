@@ -0,0 +1,46 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package selftest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrossCheck(t *testing.T) {
+	type Case struct {
+		Expr1 string
+		Expr2 string
+	}
+	cases := []Case{
+		{"", ""},
+		{"a*bba+", "b*aaab+a"},
+		{"a*bba+", "b*aaabbb+a"},
+		{"(a|b)*abb", "[ab]{3}"},
+		{"[^a]+", "b*c"},
+		{"(?i)AB", "ab|ba"},
+		{"^a", "a"},
+		{"^a$", `\ba\b`},
+		{`a\B`, "a"},
+	}
+	for _, c := range cases {
+		d, err := CrossCheck(c.Expr1, c.Expr2, []rune("abc"), 6)
+		assert.NoError(t, err)
+		assert.Nil(t, d, "%q vs %q: %v", c.Expr1, c.Expr2, d)
+	}
+
+	_, err := CrossCheck("a(", "a", []rune("a"), 1)
+	assert.Error(t, err)
+}
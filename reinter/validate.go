@@ -0,0 +1,66 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reinter
+
+import (
+	"github.com/oulinbao/regexinter/intersection"
+	"github.com/oulinbao/regexinter/nfa"
+)
+
+// Limits bound the automata of the patterns Validate admits. Zero values
+// leave the corresponding options, if any, in effect.
+type Limits struct {
+	MaxNFANodes  int // see MaxNFANodes
+	MaxDFAStates int // estimated states of the DFA, see MaxStates
+	MaxRepeat    int // see MaxRepeat
+	MaxNesting   int // see MaxNesting
+}
+
+// Validate checks that expr is a valid pattern whose automata stay within
+// the limits, for services to reject patterns before storing them. Only the
+// syntax tree is built: the repetitions, nesting and NFA size are counted on
+// it, and the number of DFA states is estimated from it with
+// nfa.EstimateDFAStates. Patterns over the limits fail with an
+// *ErrLimitExceeded.
+func Validate(expr string, limits Limits, opts ...Option) error {
+	c := newConfig(opts)
+	expr, err := c.pattern(expr)
+	if err != nil {
+		return err
+	}
+	if limits.MaxNFANodes > 0 {
+		c.Pattern.MaxNFANodes = limits.MaxNFANodes
+	}
+	if limits.MaxRepeat > 0 {
+		c.Pattern.MaxRepeat = limits.MaxRepeat
+	}
+	if limits.MaxNesting > 0 {
+		c.Pattern.MaxNesting = limits.MaxNesting
+	}
+	if limits.MaxDFAStates > 0 {
+		c.Limits.MaxStates = limits.MaxDFAStates
+	}
+
+	r, err := intersection.Parse(expr, c.Pattern)
+	if err != nil {
+		return err
+	}
+	if c.Pattern.MaxNFANodes > 0 && nfa.Size(r) > c.Pattern.MaxNFANodes {
+		return &ErrLimitExceeded{Limit: "MaxNFANodes", Max: c.Pattern.MaxNFANodes}
+	}
+	if c.Limits.MaxStates > 0 && nfa.EstimateDFAStates(r) > c.Limits.MaxStates {
+		return &ErrLimitExceeded{Limit: "MaxStates", Max: c.Limits.MaxStates}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package ingress finds the Kubernetes Ingress and Gateway API HTTPRoute
+// path rules that match the same requests, for admission checks to reject
+// rules shadowing each other.
+package ingress
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/intersection"
+)
+
+// PathType tells how a rule matches paths, as the field of the same name of
+// the Kubernetes APIs.
+type PathType string
+
+const (
+	// Exact matches the path as is.
+	Exact PathType = "Exact"
+
+	// Prefix matches the paths under the path, element by element: /foo
+	// matches /foo and /foo/bar but not /foobar. A trailing slash makes no
+	// difference.
+	Prefix PathType = "Prefix"
+
+	// RegularExpression matches the paths the regular expression matches
+	// as a whole. It is PathMatchRegularExpression of the Gateway API.
+	RegularExpression PathType = "RegularExpression"
+)
+
+// Rule is a path rule of an Ingress or an HTTPRoute.
+type Rule struct {
+	Name string   // identifies the rule in the conflicts, such as namespace/name
+	Host string   // host name, possibly a wildcard such as *.example.com; empty for all hosts
+	Path string   // path, prefix or regular expression depending on Type
+	Type PathType // Prefix if empty
+}
+
+// Conflict is a pair of rules matching a common request.
+type Conflict struct {
+	A, B Rule
+	Host string // a host both rules match
+	Path string // a path both rules match on that host
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s and %s both match %s%s", c.A.Name, c.B.Name, c.Host, c.Path)
+}
+
+// compiled holds the automata of a rule.
+type compiled struct {
+	host, path *dfa.Node
+}
+
+// Analyze returns the pairs of rules matching a common request, in the order
+// of the rules. Rules with the same host and path are reported too, as
+// whichever wins depends on the controller.
+func Analyze(rules []Rule) ([]Conflict, error) {
+	automata := make([]compiled, len(rules))
+	for i, r := range rules {
+		var err error
+		if automata[i].host, err = compile(hostExpr(r.Host)); err != nil {
+			return nil, fmt.Errorf("ingress: rule %s: host: %w", r.Name, err)
+		}
+		expr, err := pathExpr(r)
+		if err == nil {
+			automata[i].path, err = compile(expr)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ingress: rule %s: path: %w", r.Name, err)
+		}
+	}
+
+	var conflicts []Conflict
+	for i := range rules {
+		for j := i + 1; j < len(rules); j++ {
+			host := intersection.CheckDFA(automata[i].host, automata[j].host)
+			if !host.Intersects {
+				continue
+			}
+			path := intersection.CheckDFA(automata[i].path, automata[j].path)
+			if path.Intersects {
+				conflicts = append(conflicts, Conflict{A: rules[i], B: rules[j], Host: host.Witness, Path: path.Witness})
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+func compile(expr string) (*dfa.Node, error) {
+	return intersection.Compile(expr, intersection.Options{})
+}
+
+// hostExpr returns the pattern of the lower case host names matched by host.
+// Host names are case insensitive and a wildcard stands for a single label.
+func hostExpr(host string) string {
+	host = strings.ToLower(host)
+	if host == "" {
+		return ".*"
+	}
+	if rest, ok := strings.CutPrefix(host, "*."); ok {
+		return `[^.]+\.` + regexp.QuoteMeta(rest)
+	}
+	return regexp.QuoteMeta(host)
+}
+
+// pathExpr returns the pattern of the paths matched by the rule.
+func pathExpr(r Rule) (string, error) {
+	switch r.Type {
+	case Exact:
+		return regexp.QuoteMeta(r.Path), nil
+	case Prefix, "":
+		p := strings.TrimRight(r.Path, "/")
+		if p == "" {
+			return "/.*", nil
+		}
+		return regexp.QuoteMeta(p) + "(?:/.*)?", nil
+	case RegularExpression:
+		return r.Path, nil
+	}
+	return "", fmt.Errorf("unknown path type %q", r.Type)
+}
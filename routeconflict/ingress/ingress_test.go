@@ -0,0 +1,72 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ingress
+
+import (
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	rules := []Rule{
+		{Name: "api", Host: "example.com", Path: "/api", Type: Prefix},
+		{Name: "users", Host: "example.com", Path: "/api/users", Type: Exact},
+		{Name: "apix", Host: "example.com", Path: "/apix", Type: Exact},
+		{Name: "other-host", Host: "other.com", Path: "/api/users", Type: Exact},
+		{Name: "wildcard", Host: "*.other.com", Path: "/api/v[0-9]+/.*", Type: RegularExpression},
+		{Name: "www", Host: "WWW.other.com", Path: "/api/", Type: Prefix},
+		{Name: "deep", Host: "a.b.other.com", Path: "/", Type: Prefix},
+	}
+	conflicts, err := Analyze(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type pair struct{ a, b string }
+	want := []pair{{"api", "users"}, {"wildcard", "www"}}
+	if len(conflicts) != len(want) {
+		t.Fatalf("got conflicts %v, want %v", conflicts, want)
+	}
+	for i, c := range conflicts {
+		if (pair{c.A.Name, c.B.Name}) != want[i] {
+			t.Errorf("conflict %d = %v, want %v", i, c, want[i])
+		}
+	}
+	if c := conflicts[0]; c.Host != "example.com" || c.Path != "/api/users" {
+		t.Errorf("conflict 0 matches %s%s", c.Host, c.Path)
+	}
+
+	if _, err := Analyze([]Rule{{Name: "bad", Path: "/(", Type: RegularExpression}}); err == nil {
+		t.Errorf("Analyze accepted an invalid regular expression")
+	}
+	if _, err := Analyze([]Rule{{Name: "bad", Path: "/", Type: "Glob"}}); err == nil {
+		t.Errorf("Analyze accepted an unknown path type")
+	}
+}
+
+func TestPathExpr(t *testing.T) {
+	type testCase struct {
+		rule Rule
+		want string
+	}
+	testCases := []testCase{
+		{Rule{Path: "/foo/", Type: Prefix}, "/foo(?:/.*)?"},
+		{Rule{Path: "/"}, "/.*"},
+		{Rule{Path: "/a.b", Type: Exact}, `/a\.b`},
+		{Rule{Path: "/a.b", Type: RegularExpression}, "/a.b"},
+	}
+	for _, tc := range testCases {
+		if got, err := pathExpr(tc.rule); err != nil || got != tc.want {
+			t.Errorf("pathExpr(%+v) = %q, %v, want %q", tc.rule, got, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package location finds the nginx location blocks and Envoy routes that
+// overlap or can never be selected, given the order in which the servers
+// try them.
+package location
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/intersection"
+)
+
+// Modifier is the modifier of an nginx location, which tells how it matches
+// request paths.
+type Modifier string
+
+const (
+	Prefix          Modifier = ""   // paths starting with the location
+	Exact           Modifier = "="  // the location itself
+	PreferredPrefix Modifier = "^~" // like Prefix, but regular expressions are not tried
+	Regex           Modifier = "~"  // paths the regular expression matches part of
+	RegexFold       Modifier = "~*" // the same regardless of case
+)
+
+// Location is a location matcher.
+type Location struct {
+	Modifier Modifier
+	Path     string // path, prefix or regular expression
+}
+
+func (l Location) String() string {
+	if l.Modifier == Prefix {
+		return l.Path
+	}
+	return string(l.Modifier) + " " + l.Path
+}
+
+// Parse parses a location matcher as written in nginx configuration files,
+// such as "= /favicon.ico" or "location ~* \.(gif|jpg)$ {".
+func Parse(s string) (Location, error) {
+	fields := strings.Fields(s)
+	if len(fields) > 0 && fields[0] == "location" {
+		fields = fields[1:]
+	}
+	if n := len(fields); n > 0 && fields[n-1] == "{" {
+		fields = fields[:n-1]
+	}
+	switch {
+	case len(fields) == 1:
+		return Location{Prefix, fields[0]}, nil
+	case len(fields) == 2:
+		switch m := Modifier(fields[0]); m {
+		case Exact, PreferredPrefix, Regex, RegexFold:
+			return Location{m, fields[1]}, nil
+		}
+	}
+	return Location{}, fmt.Errorf("location: cannot parse %q", s)
+}
+
+// Precedence tells how a server picks the location of a request.
+type Precedence int
+
+const (
+	// Nginx picks an exact location if one matches; otherwise the longest
+	// matching prefix unless it is a plain prefix and a regular expression
+	// matches, the first one in order winning then.
+	Nginx Precedence = iota
+
+	// FirstMatch picks the first matching location in order, as Envoy does
+	// with routes. Regular expressions must match whole paths, as Envoy's
+	// safe_regex does.
+	FirstMatch
+)
+
+// Overlap is a pair of locations matching a common path.
+type Overlap struct {
+	A, B   int    // indexes of the locations, A < B
+	Path   string // the shortest path both match
+	Winner int    // index of the location selected for Path
+}
+
+// Report describes the interplay of a list of locations.
+type Report struct {
+	// Unreachable holds the indexes of the locations no path selects, as
+	// the locations taking precedence over them match all their paths.
+	Unreachable []int
+
+	// Overlaps holds the pairs of locations matching a common path.
+	Overlaps []Overlap
+}
+
+// Analyze reports which of the locations overlap and which are unreachable
+// under the precedence rules.
+func Analyze(locations []Location, precedence Precedence) (Report, error) {
+	a := &analysis{locations: locations, precedence: precedence}
+	for i, l := range locations {
+		n, err := compile(l, precedence)
+		if err != nil {
+			return Report{}, fmt.Errorf("location %d (%s): %w", i, l, err)
+		}
+		a.matches = append(a.matches, n)
+	}
+	for i := range locations {
+		a.preceding = append(a.preceding, a.precedes(i))
+	}
+
+	var report Report
+	for i := range locations {
+		if selected := dfa.Subtract(a.matches[i], a.preceding[i]); !selected.Final && len(selected.Transitions) == 0 {
+			report.Unreachable = append(report.Unreachable, i)
+		}
+		for j := i + 1; j < len(locations); j++ {
+			if r := intersection.CheckDFA(a.matches[i], a.matches[j]); r.Intersects {
+				report.Overlaps = append(report.Overlaps, Overlap{A: i, B: j, Path: r.Witness, Winner: a.selected(r.Witness)})
+			}
+		}
+	}
+	return report, nil
+}
+
+type analysis struct {
+	locations  []Location
+	precedence Precedence
+	matches    []*dfa.Node // paths each location matches
+	preceding  []*dfa.Node // paths other locations take instead, see precedes
+}
+
+// precedes returns the automaton of the paths for which some other location
+// takes precedence over location i.
+func (a *analysis) precedes(i int) *dfa.Node {
+	var nodes []*dfa.Node
+	if a.precedence == FirstMatch {
+		return dfa.Union(a.matches[:i]...)
+	}
+
+	l := a.locations[i]
+	for j, m := range a.locations {
+		switch {
+		case j == i:
+		case m.Modifier == Exact:
+			// Exact locations win, the first of identical ones.
+			if l.Modifier != Exact || j < i {
+				nodes = append(nodes, a.matches[j])
+			}
+		case isPrefix(m.Modifier) && isPrefix(l.Modifier) && a.longer(j, i):
+			nodes = append(nodes, a.matches[j])
+		case isRegex(m.Modifier) && (l.Modifier == Prefix || isRegex(l.Modifier) && j < i):
+			nodes = append(nodes, a.matches[j])
+		}
+	}
+	if isRegex(l.Modifier) {
+		// The paths whose longest matching prefix is preferred.
+		for j, m := range a.locations {
+			if m.Modifier != PreferredPrefix {
+				continue
+			}
+			var longer []*dfa.Node
+			for k, other := range a.locations {
+				if k != j && isPrefix(other.Modifier) && a.longer(k, j) {
+					longer = append(longer, a.matches[k])
+				}
+			}
+			nodes = append(nodes, dfa.Subtract(a.matches[j], dfa.Union(longer...)))
+		}
+	}
+	return dfa.Union(nodes...)
+}
+
+// longer reports whether prefix location j takes precedence over prefix
+// location i when both match: it is longer, or as long and comes first.
+func (a *analysis) longer(j, i int) bool {
+	lj, li := len(a.locations[j].Path), len(a.locations[i].Path)
+	return lj > li || lj == li && j < i
+}
+
+// selected returns the index of the location selected for path, or -1.
+func (a *analysis) selected(path string) int {
+	for i, n := range a.matches {
+		if n.Match(path) && !a.preceding[i].Match(path) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isPrefix(m Modifier) bool {
+	return m == Prefix || m == PreferredPrefix
+}
+
+func isRegex(m Modifier) bool {
+	return m == Regex || m == RegexFold
+}
+
+// compile returns the automaton of the paths the location matches.
+func compile(l Location, precedence Precedence) (*dfa.Node, error) {
+	var expr string
+	switch l.Modifier {
+	case Exact:
+		expr = regexp.QuoteMeta(l.Path)
+	case Prefix, PreferredPrefix:
+		expr = regexp.QuoteMeta(l.Path) + "(?s:.*)"
+	case Regex, RegexFold:
+		expr = l.Path
+		if precedence == Nginx {
+			// nginx looks for the pattern anywhere in the path.
+			expr = "(?s:.*)(?:" + expr + ")(?s:.*)"
+		}
+		if l.Modifier == RegexFold {
+			expr = "(?i:" + expr + ")"
+		}
+	default:
+		return nil, fmt.Errorf("unknown modifier %q", l.Modifier)
+	}
+	return intersection.Compile(expr, intersection.Options{})
+}
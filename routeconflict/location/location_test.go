@@ -0,0 +1,128 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package location
+
+import (
+	"reflect"
+	"testing"
+)
+
+func parseAll(t *testing.T, lines ...string) []Location {
+	t.Helper()
+	var locations []Location
+	for _, line := range lines {
+		l, err := Parse(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		locations = append(locations, l)
+	}
+	return locations
+}
+
+func TestParse(t *testing.T) {
+	type testCase struct {
+		s    string
+		want Location
+	}
+	testCases := []testCase{
+		{"/", Location{Prefix, "/"}},
+		{"= /favicon.ico", Location{Exact, "/favicon.ico"}},
+		{`location ~* \.(gif|jpg)$ {`, Location{RegexFold, `\.(gif|jpg)$`}},
+		{"location ^~ /images/", Location{PreferredPrefix, "/images/"}},
+	}
+	for _, tc := range testCases {
+		if got, err := Parse(tc.s); err != nil || got != tc.want {
+			t.Errorf("Parse(%q) = %v, %v, want %v", tc.s, got, err, tc.want)
+		}
+	}
+	for _, s := range []string{"", "location", "! /x", "= /a /b"} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) succeeded", s)
+		}
+	}
+}
+
+func TestAnalyzeNginx(t *testing.T) {
+	locations := parseAll(t,
+		"/",                     // 0
+		"= /",                   // 1
+		"^~ /images/",           // 2
+		`~* \.(gif|jpg|jpeg)$`,  // 3
+		"/images/icons/",        // 4
+		`~ ^/images/logo\.png$`, // 5 shadowed by ^~ /images/
+		"/documents/",           // 6 every path goes to the regex
+		"~ ^/documents/",        // 7
+		"= /documents/",         // 8
+		"= /documents/",         // 9 duplicate
+	)
+	report, err := Analyze(locations, Nginx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{5, 6, 9}; !reflect.DeepEqual(report.Unreachable, want) {
+		t.Errorf("Unreachable = %v, want %v", report.Unreachable, want)
+	}
+
+	winners := make(map[[2]int]int)
+	for _, o := range report.Overlaps {
+		winners[[2]int{o.A, o.B}] = o.Winner
+	}
+	type testCase struct {
+		a, b, winner int
+	}
+	for _, tc := range []testCase{{0, 1, 1}, {0, 3, 3}, {2, 3, 2}, {2, 4, 4}, {3, 4, 3}, {6, 7, 8}} {
+		if got, ok := winners[[2]int{tc.a, tc.b}]; !ok || got != tc.winner {
+			t.Errorf("overlap of %d and %d won by %d (found %v), want %d", tc.a, tc.b, got, ok, tc.winner)
+		}
+	}
+	if _, ok := winners[[2]int{1, 2}]; ok {
+		t.Errorf("= / and ^~ /images/ overlap")
+	}
+
+	report, err = Analyze(parseAll(t, "/x", "~ ^/a|/b$"), Nginx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Overlaps) != 1 || report.Overlaps[0].Winner != 1 {
+		t.Errorf("Overlaps of /x and ~ ^/a|/b$ = %v, want one won by the regex", report.Overlaps)
+	}
+}
+
+func TestAnalyzeFirstMatch(t *testing.T) {
+	locations := parseAll(t, "/api/", "~ /api/v[0-9]+/.*", "= /api/v1/users", "/static/")
+	report, err := Analyze(locations, FirstMatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(report.Unreachable, want) {
+		t.Errorf("Unreachable = %v, want %v", report.Unreachable, want)
+	}
+	if len(report.Overlaps) != 3 {
+		t.Errorf("Overlaps = %v, want 3 of them", report.Overlaps)
+	}
+	for _, o := range report.Overlaps {
+		if o.Winner != 0 {
+			t.Errorf("overlap %v not won by /api/", o)
+		}
+	}
+
+	report, err = Analyze(parseAll(t, "/api/", "~ ^/api/(v1|v2)$"), FirstMatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1}; !reflect.DeepEqual(report.Unreachable, want) {
+		t.Errorf("Unreachable = %v, want %v", report.Unreachable, want)
+	}
+}
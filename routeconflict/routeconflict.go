@@ -0,0 +1,201 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package routeconflict finds the paths that URL route patterns have in
+// common, comparing the patterns segment by segment so that conflicts can be
+// traced to the segments causing them. Route templates such as /users/{id}
+// are converted into patterns first.
+package routeconflict
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/intersection"
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+// Result describes how two route patterns overlap.
+type Result struct {
+	Overlap bool   // some path matches both patterns
+	Witness string // the shortest such path, segment by segment
+
+	// Segments holds the comparisons of the segments at the same positions
+	// in both patterns.
+	Segments []Segment
+
+	// Blocking is the index of the first segment the patterns cannot both
+	// match, or -1 if there is none.
+	Blocking int
+
+	// Reason tells why the patterns do not overlap, such as "segment 2:
+	// users vs [0-9]+: ...". It is empty if they do.
+	Reason string
+}
+
+// Segment is the comparison of the segments of two patterns at the same
+// position.
+type Segment struct {
+	A, B        string // patterns of the segments
+	Overlap     bool   // some segment matches both
+	Witness     string // the shortest such segment
+	Explanation string // why no segment does, see intersection.Result
+}
+
+// SegmentCheck reports whether some path matches both route patterns. The
+// patterns are regular expressions split on the slashes outside of groups
+// and classes, each part matching a single path segment: the parts never
+// match a slash, so .* in /files/.* matches a file name but not a
+// subdirectory. Patterns with slashes in groups are rejected.
+func SegmentCheck(a, b string) (Result, error) {
+	segsA, err := Split(a)
+	if err != nil {
+		return Result{}, err
+	}
+	segsB, err := Split(b)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Overlap: len(segsA) == len(segsB), Blocking: -1}
+	var witness []string
+	for i := 0; i < min(len(segsA), len(segsB)); i++ {
+		seg, err := checkSegment(segsA[i], segsB[i])
+		if err != nil {
+			return Result{}, err
+		}
+		result.Segments = append(result.Segments, seg)
+		witness = append(witness, seg.Witness)
+		if !seg.Overlap && result.Blocking < 0 {
+			result.Overlap = false
+			result.Blocking = i
+			result.Reason = fmt.Sprintf("segment %d: %s vs %s: %s", i, seg.A, seg.B, seg.Explanation)
+		}
+	}
+	switch {
+	case result.Overlap:
+		result.Witness = strings.Join(witness, "/")
+	case result.Blocking < 0:
+		result.Reason = fmt.Sprintf("%d segments vs %d", len(segsA), len(segsB))
+	}
+	return result, nil
+}
+
+func checkSegment(a, b string) (Segment, error) {
+	nodeA, err := compileSegment(a)
+	if err != nil {
+		return Segment{}, err
+	}
+	nodeB, err := compileSegment(b)
+	if err != nil {
+		return Segment{}, err
+	}
+	r := intersection.CheckDFA(nodeA, nodeB)
+	return Segment{A: a, B: b, Overlap: r.Intersects, Witness: r.Witness, Explanation: r.Explanation}, nil
+}
+
+// slash is the range of the path separator.
+var slash = []rune{'/', '/'}
+
+// compileSegment returns the automaton of the segment pattern restricted to
+// strings without slashes.
+func compileSegment(expr string) (*dfa.Node, error) {
+	n, err := intersection.Compile(expr, intersection.Options{})
+	if err != nil {
+		return nil, err
+	}
+	restricted := make(map[*dfa.Node]*dfa.Node)
+	var restrict func(n *dfa.Node) *dfa.Node
+	restrict = func(n *dfa.Node) *dfa.Node {
+		if r, ok := restricted[n]; ok {
+			return r
+		}
+		r := &dfa.Node{State: n.State, Final: n.Final}
+		restricted[n] = r
+		for _, t := range n.Transitions {
+			if rr := runerange.Subtract(t.RuneRanges, slash); len(rr) > 0 {
+				r.Transitions = append(r.Transitions, dfa.T{RuneRanges: rr, Node: restrict(t.Node)})
+			}
+		}
+		return r
+	}
+	return dfa.Trim(restrict(n)), nil
+}
+
+// Split splits a route pattern into the patterns of its segments, on the
+// slashes outside of classes, escaped or not. A leading slash gives an empty
+// first segment, as in strings.Split. Slashes in groups are an error as the
+// groups cannot be split.
+func Split(expr string) ([]string, error) {
+	var segments []string
+	var b strings.Builder
+	depth := 0
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case c == '\\' && i+1 < len(expr):
+			i++
+			if expr[i] == '/' {
+				c = '/'
+				break
+			}
+			b.WriteByte(c)
+			c = expr[i]
+		case c == '[':
+			end := classEnd(expr, i)
+			b.WriteString(expr[i:end])
+			i = end - 1
+			continue
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		}
+		if c != '/' {
+			b.WriteByte(c)
+			continue
+		}
+		if depth > 0 {
+			return nil, fmt.Errorf("routeconflict: slash inside a group at offset %d of %q", i, expr)
+		}
+		segments = append(segments, b.String())
+		b.Reset()
+	}
+	return append(segments, b.String()), nil
+}
+
+// classEnd returns the offset after the character class starting at offset
+// i of expr, or len(expr) if it is not closed.
+func classEnd(expr string, i int) int {
+	j := i + 1
+	if j < len(expr) && expr[j] == '^' {
+		j++
+	}
+	if j < len(expr) && expr[j] == ']' {
+		j++ // a leading ] is a literal
+	}
+	for ; j < len(expr); j++ {
+		switch {
+		case expr[j] == '\\':
+			j++
+		case strings.HasPrefix(expr[j:], "[:"):
+			if k := strings.Index(expr[j+2:], ":]"); k >= 0 {
+				j += k + 3
+			}
+		case expr[j] == ']':
+			return j + 1
+		}
+	}
+	return len(expr)
+}
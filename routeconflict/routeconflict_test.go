@@ -0,0 +1,128 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routeconflict
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSegmentCheck(t *testing.T) {
+	type testCase struct {
+		a, b     string
+		overlap  bool
+		witness  string
+		blocking int
+	}
+	testCases := []testCase{
+		{"/api/v1/users", "/api/v[0-9]+/users", true, "/api/v1/users", -1},
+		{"/api/v1/users/[0-9]+", "/api/v1/users/me", false, "", 4},
+		{"/api/v1/users", "/api/v2/users", false, "", 2},
+		{"/files/.*", "/files/a/b", false, "", -1},
+		{"/files/.*", "/files/readme", true, "/files/readme", -1},
+		{"/[a-z]+/[0-9]+", "/users/.*", true, "/users/0", -1},
+		{`/a\/b`, "/a/b", true, "/a/b", -1},
+		{"/[/a]x", "/ax", true, "/ax", -1},
+		{"/(a|b)/c", "/b/.*", true, "/b/c", -1},
+	}
+	for _, tc := range testCases {
+		got, err := SegmentCheck(tc.a, tc.b)
+		if err != nil {
+			t.Errorf("SegmentCheck(%q, %q) failed: %v", tc.a, tc.b, err)
+			continue
+		}
+		if got.Overlap != tc.overlap || got.Witness != tc.witness || got.Blocking != tc.blocking {
+			t.Errorf("SegmentCheck(%q, %q) = %v, %q, %d, want %v, %q, %d",
+				tc.a, tc.b, got.Overlap, got.Witness, got.Blocking, tc.overlap, tc.witness, tc.blocking)
+		}
+		if !got.Overlap && got.Reason == "" {
+			t.Errorf("SegmentCheck(%q, %q) gives no reason", tc.a, tc.b)
+		}
+	}
+
+	got, _ := SegmentCheck("/api/v1/users", "/api/v2/users")
+	if want := "segment 2: v1 vs v2: "; len(got.Reason) < len(want) || got.Reason[:len(want)] != want {
+		t.Errorf("Reason = %q, want it to start with %q", got.Reason, want)
+	}
+	if _, err := SegmentCheck("/(a/b)", "/a/b"); err == nil {
+		t.Errorf("SegmentCheck accepted a slash in a group")
+	}
+}
+
+func TestSplit(t *testing.T) {
+	type testCase struct {
+		expr string
+		want []string
+	}
+	testCases := []testCase{
+		{"/api/v1", []string{"", "api", "v1"}},
+		{"api", []string{"api"}},
+		{`/a\/b\.c`, []string{"", "a", `b\.c`}},
+		{"/[^/]+/x", []string{"", "[^/]+", "x"}},
+		{"/[]/]/x", []string{"", "[]/]", "x"}},
+		{`/(a|\))/`, []string{"", `(a|\))`, ""}},
+	}
+	for _, tc := range testCases {
+		got, err := Split(tc.expr)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Split(%q) = %q, %v, want %q", tc.expr, got, err, tc.want)
+		}
+	}
+}
+
+func TestFromTemplate(t *testing.T) {
+	type testCase struct {
+		tmpl string
+		want string
+	}
+	testCases := []testCase{
+		{"/users/{id}", "/users/[^/]+"},
+		{"/users/{id:[0-9]+}/posts", "/users/(?:[0-9]+)/posts"},
+		{"/zip/{code:[0-9]{5}}", "/zip/(?:[0-9]{5})"},
+		{"/users/:id/posts", "/users/[^/]+/posts"},
+		{"/static/*filepath", "/static/.*"},
+		{"/static/*", "/static/.*"},
+		{"/a.b/*/c", `/a\.b/\*/c`},
+		{"/time/12:30", "/time/12:30"},
+	}
+	for _, tc := range testCases {
+		if got, err := FromTemplate(tc.tmpl); err != nil || got != tc.want {
+			t.Errorf("FromTemplate(%q) = %q, %v, want %q", tc.tmpl, got, err, tc.want)
+		}
+	}
+	if _, err := FromTemplate("/users/{id"); err == nil {
+		t.Errorf("FromTemplate accepted an unclosed parameter")
+	}
+}
+
+func TestTemplateCheck(t *testing.T) {
+	type testCase struct {
+		a, b    string
+		overlap bool
+	}
+	testCases := []testCase{
+		{"/users/{id}", "/users/:name", true},
+		{"/users/{id:[0-9]+}", "/users/me", false},
+		{"/users/{id}", "/users/me", true},
+		{"/static/*filepath", "/static/css/{file}", true},
+		{"/users/{id}", "/users/{id}/posts", false},
+		{"/users/{id}", "/users/", false},
+	}
+	for _, tc := range testCases {
+		got, err := TemplateCheck(tc.a, tc.b)
+		if err != nil || got.Intersects != tc.overlap {
+			t.Errorf("TemplateCheck(%q, %q) = %v, %v, want %v", tc.a, tc.b, got.Intersects, err, tc.overlap)
+		}
+	}
+}
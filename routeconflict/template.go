@@ -0,0 +1,127 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routeconflict
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/intersection"
+)
+
+// segmentExpr matches the value of a parameter without a pattern.
+const segmentExpr = "[^/]+"
+
+// FromTemplate converts a route template into the pattern of the paths it
+// matches. Templates are literal paths with parameters spelled in any of the
+// common syntaxes:
+//
+//	{id}         a non-empty segment, as in OpenAPI
+//	{id:[0-9]+}  a match of the pattern after the colon
+//	:id          a non-empty segment, at the start of a segment as in gin
+//	*path, *     the rest of the path, slashes included, in the last segment
+func FromTemplate(tmpl string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(tmpl); {
+		atSegment := i == 0 || tmpl[i-1] == '/'
+		switch c := tmpl[i]; {
+		case c == '{':
+			end, err := braceEnd(tmpl, i)
+			if err != nil {
+				return "", err
+			}
+			param := tmpl[i+1 : end]
+			if _, expr, ok := strings.Cut(param, ":"); ok {
+				b.WriteString("(?:" + expr + ")")
+			} else {
+				b.WriteString(segmentExpr)
+			}
+			i = end + 1
+		case c == ':' && atSegment:
+			i = nextSlash(tmpl, i)
+			b.WriteString(segmentExpr)
+		case c == '*' && atSegment && nextSlash(tmpl, i) == len(tmpl):
+			i = len(tmpl)
+			b.WriteString(".*")
+		default:
+			end := nextSpecial(tmpl, i+1)
+			b.WriteString(regexp.QuoteMeta(tmpl[i:end]))
+			i = end
+		}
+	}
+	return b.String(), nil
+}
+
+// CompileTemplate returns the automaton of the paths a route template
+// matches, see FromTemplate.
+func CompileTemplate(tmpl string) (*dfa.Node, error) {
+	expr, err := FromTemplate(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	return intersection.Compile(expr, intersection.Options{})
+}
+
+// TemplateCheck reports whether some path matches both route templates,
+// with the shortest such path as the witness.
+func TemplateCheck(a, b string) (intersection.Result, error) {
+	nodeA, err := CompileTemplate(a)
+	if err != nil {
+		return intersection.Result{}, err
+	}
+	nodeB, err := CompileTemplate(b)
+	if err != nil {
+		return intersection.Result{}, err
+	}
+	return intersection.CheckDFA(nodeA, nodeB), nil
+}
+
+// braceEnd returns the offset of the brace closing the one at offset i,
+// counting the braces of repetitions such as {3} in parameter patterns.
+func braceEnd(tmpl string, i int) (int, error) {
+	depth := 0
+	for j := i; j < len(tmpl); j++ {
+		switch tmpl[j] {
+		case '\\':
+			j++
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return j, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("routeconflict: unclosed parameter at offset %d of %q", i, tmpl)
+}
+
+func nextSlash(tmpl string, i int) int {
+	if k := strings.IndexByte(tmpl[i:], '/'); k >= 0 {
+		return i + k
+	}
+	return len(tmpl)
+}
+
+// nextSpecial returns the offset of the first character from offset i that
+// may start a parameter.
+func nextSpecial(tmpl string, i int) int {
+	for j := i; j < len(tmpl); j++ {
+		if c := tmpl[j]; c == '{' || (c == ':' || c == '*') && tmpl[j-1] == '/' {
+			return j
+		}
+	}
+	return len(tmpl)
+}
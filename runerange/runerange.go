@@ -20,7 +20,10 @@
 package runerange
 
 import (
+	"iter"
 	"sort"
+	"strconv"
+	"strings"
 	"unicode"
 )
 
@@ -34,6 +37,38 @@ func In(ranges []rune, r rune) bool {
 	return false
 }
 
+// ContainsRune returns true if a rune is in the range, which must be sorted. Unlike In it takes logarithmic time.
+func ContainsRune(ranges []rune, r rune) bool {
+	i := sort.Search(len(ranges)/2, func(i int) bool { return ranges[2*i+1] >= r })
+	return i < len(ranges)/2 && ranges[2*i] <= r
+}
+
+// Count returns the number of runes in the range, whose pairs must not overlap.
+func Count(ranges []rune) int {
+	n := 0
+	for i := 0; i < len(ranges); i += 2 {
+		n += int(ranges[i+1]-ranges[i]) + 1
+	}
+	return n
+}
+
+// Runes returns an iterator over the runes of the range in order.
+func Runes(ranges []rune) iter.Seq[rune] {
+	return func(yield func(rune) bool) {
+		for i := 0; i < len(ranges); i += 2 {
+			// Stop on the last rune rather than past it, which may overflow.
+			for r := ranges[i]; ; r++ {
+				if !yield(r) {
+					return
+				}
+				if r == ranges[i+1] {
+					break
+				}
+			}
+		}
+	}
+}
+
 // Contains returns true if the range a contains the range b.
 func Contains(a, b []rune) bool {
 outer:
@@ -90,7 +125,7 @@ func (p pairs) Swap(i, j int) {
 	p[i], p[i+1], p[j], p[j+1] = p[j], p[j+1], p[i], p[i+1]
 }
 
-// Sum returns a range containing all the runes from the ranges a and b. The a and b ranges are not modified.
+// Sum returns a range containing all the runes from the ranges a and b, with overlapping and adjacent pairs merged. The a and b ranges are not modified.
 func Sum(a, b []rune) []rune {
 	if len(a) == 0 {
 		return append([]rune(nil), b...)
@@ -107,7 +142,7 @@ func Sum(a, b []rune) []rune {
 	d := make([]rune, 0, len(c))
 	d = append(d, c[:2]...)
 	for i := 2; i < len(c); i += 2 {
-		if c[i] <= d[len(d)-1] {
+		if c[i] <= d[len(d)-1]+1 {
 			if c[i+1] > d[len(d)-1] {
 				d[len(d)-1] = c[i+1]
 			}
@@ -119,6 +154,51 @@ func Sum(a, b []rune) []rune {
 	return d
 }
 
+// Intersect returns a range containing the runes that are both in the ranges a and b. The a and b ranges are not modified.
+func Intersect(a, b []rune) []rune {
+	var c []rune
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		lo, hi := a[i], a[i+1]
+		if b[j] > lo {
+			lo = b[j]
+		}
+		if b[j+1] < hi {
+			hi = b[j+1]
+		}
+		if lo <= hi {
+			c = append(c, lo, hi)
+		}
+		if a[i+1] < b[j+1] {
+			i += 2
+		} else {
+			j += 2
+		}
+	}
+	return c
+}
+
+// Subtract returns a range containing the runes of the range a that are not in the range b. The a and b ranges are not modified.
+func Subtract(a, b []rune) []rune {
+	var c []rune
+	j := 0
+	for i := 0; i < len(a); i += 2 {
+		lo, hi := a[i], a[i+1]
+		for j < len(b) && b[j+1] < lo {
+			j += 2
+		}
+		for k := j; k < len(b) && b[k] <= hi && lo <= hi; k += 2 {
+			if b[k] > lo {
+				c = append(c, lo, b[k]-1)
+			}
+			lo = b[k+1] + 1
+		}
+		if lo <= hi {
+			c = append(c, lo, hi)
+		}
+	}
+	return c
+}
+
 // Fold returns a range containing all the runes from the original range and all the runes that can be obtained from them by using unicode case folding. The original range is not modified.
 func Fold(ranges []rune) []rune {
 	if len(ranges) == 0 {
@@ -210,8 +290,8 @@ outer:
 					continue outer
 				}
 			} else {
-				if result[0] <= r0-1 {
-					queue = append(queue, result[0], r0-1)
+				if result[i] <= r0-1 {
+					queue = append(queue, result[i], r0-1)
 				}
 				queue = append(queue, r0, r1)
 				if r1+1 <= result[i+1] {
@@ -227,3 +307,70 @@ outer:
 	sort.Sort(pairs(result))
 	return result
 }
+
+// String returns the range formatted as a regular expression character class such as [0-9a-z].
+// If the complement of the range in [0, unicode.MaxRune] has fewer pairs, the negated form such as [^\n] is used instead.
+func String(ranges []rune) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	if neg := Valid(Negate(ranges)); len(neg) > 0 && len(neg) < len(ranges) {
+		b.WriteByte('^')
+		ranges = neg
+	}
+	for i := 0; i < len(ranges); i += 2 {
+		writeClassRune(&b, ranges[i])
+		if ranges[i+1] != ranges[i] {
+			if ranges[i+1] > ranges[i]+1 {
+				b.WriteByte('-')
+			}
+			writeClassRune(&b, ranges[i+1])
+		}
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func writeClassRune(b *strings.Builder, r rune) {
+	switch {
+	case strings.ContainsRune(`\-[]^`, r):
+		b.WriteByte('\\')
+		b.WriteRune(r)
+	case r == '\n':
+		b.WriteString(`\n`)
+	case r == '\t':
+		b.WriteString(`\t`)
+	case r == '\r':
+		b.WriteString(`\r`)
+	case strconv.IsPrint(r):
+		b.WriteRune(r)
+	default:
+		b.WriteString(`\x{` + strings.ToUpper(strconv.FormatInt(int64(r), 16)) + `}`)
+	}
+}
+
+// scalars are the Unicode scalar values, which exclude the surrogate halves.
+var scalars = []rune{0, 0xd7ff, 0xe000, unicode.MaxRune}
+
+// Valid returns the part of the range made of Unicode scalar values, the runes that can be encoded in UTF-8. Surrogate halves, negative
+// and out of range values are removed. The original range is not modified.
+func Valid(ranges []rune) []rune {
+	return Intersect(ranges, scalars)
+}
+
+// Negate returns a range containing all the runes in [0, unicode.MaxRune] that are not in the range. The original range is not modified.
+func Negate(ranges []rune) []rune {
+	var neg []rune
+	next := rune(0)
+	for i := 0; i < len(ranges); i += 2 {
+		if ranges[i] > next {
+			neg = append(neg, next, ranges[i]-1)
+		}
+		if ranges[i+1]+1 > next {
+			next = ranges[i+1] + 1
+		}
+	}
+	if next <= unicode.MaxRune {
+		neg = append(neg, next, unicode.MaxRune)
+	}
+	return neg
+}
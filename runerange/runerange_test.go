@@ -14,8 +14,10 @@
 package runerange
 
 import (
+	"math"
 	"reflect"
 	"testing"
+	"unicode"
 )
 
 func TestIn(t *testing.T) {
@@ -41,6 +43,83 @@ func TestIn(t *testing.T) {
 	}
 }
 
+func TestContainsRune(t *testing.T) {
+	type testCase struct {
+		a    []rune
+		r    rune
+		want bool
+	}
+	testCases := []testCase{
+		{nil, 'a', false},
+		{[]rune{'a', 'a'}, 'a', true},
+		{[]rune{'a', 'z'}, 'o', true},
+		{[]rune{'a', 'z'}, '0', false},
+		{[]rune{'0', '9', 'a', 'z'}, '0', true},
+		{[]rune{'0', '9', 'a', 'z'}, 'z', true},
+		{[]rune{'0', '9', 'a', 'z'}, '@', false},
+		{[]rune{'0', '9', 'a', 'z'}, '{', false},
+		{[]rune{-100, -100, 'a', 'a'}, -100, true},
+	}
+	for _, tc := range testCases {
+		got := ContainsRune(tc.a, tc.r)
+		if got != tc.want {
+			t.Errorf("ContainsRune(%q, '%c') = %v, want %v", string(tc.a), tc.r, got, tc.want)
+		}
+	}
+}
+
+func TestCount(t *testing.T) {
+	type testCase struct {
+		a    []rune
+		want int
+	}
+	testCases := []testCase{
+		{nil, 0},
+		{[]rune{'a', 'a'}, 1},
+		{[]rune{'0', '9', 'a', 'z'}, 36},
+		{[]rune{0, unicode.MaxRune}, unicode.MaxRune + 1},
+	}
+	for _, tc := range testCases {
+		got := Count(tc.a)
+		if got != tc.want {
+			t.Errorf("Count(%q) = %d, want %d", string(tc.a), got, tc.want)
+		}
+	}
+}
+
+func TestRunes(t *testing.T) {
+	type testCase struct {
+		a    []rune
+		want string
+	}
+	testCases := []testCase{
+		{nil, ""},
+		{[]rune{'a', 'a'}, "a"},
+		{[]rune{'0', '3', 'a', 'c'}, "0123abc"},
+		{[]rune{unicode.MaxRune, unicode.MaxRune}, string(rune(unicode.MaxRune))},
+	}
+	for _, tc := range testCases {
+		var got []rune
+		for r := range Runes(tc.a) {
+			got = append(got, r)
+		}
+		if string(got) != tc.want {
+			t.Errorf("Runes(%q) = %q, want %q", string(tc.a), string(got), tc.want)
+		}
+	}
+
+	// Stopping early must not walk the rest of a huge range.
+	n := 0
+	for range Runes([]rune{0, math.MaxInt32}) {
+		if n++; n == 10 {
+			break
+		}
+	}
+	if n != 10 {
+		t.Errorf("Runes stopped after %d runes, want 10", n)
+	}
+}
+
 func TestAdd(t *testing.T) {
 	type testCase struct {
 		a    []rune
@@ -87,6 +166,8 @@ func TestSum(t *testing.T) {
 		{[]rune{'a', 't'}, []rune{'t', 'z'}, []rune{'a', 'z'}},
 		{[]rune{'t', 'z'}, []rune{'a', 't'}, []rune{'a', 'z'}},
 		{[]rune{'a', 't'}, []rune{'x', 'z'}, []rune{'a', 't', 'x', 'z'}},
+		{[]rune{'a', 'a'}, []rune{'b', 'b'}, []rune{'a', 'b'}},
+		{[]rune{'c', 'c', 'x', 'x'}, []rune{'a', 'b', 'd', 'w'}, []rune{'a', 'x'}},
 	}
 	for _, tc := range testCases {
 		got := Sum(tc.a, tc.b)
@@ -96,6 +177,50 @@ func TestSum(t *testing.T) {
 	}
 }
 
+func TestIntersect(t *testing.T) {
+	type testCase struct {
+		a, b []rune
+		want []rune
+	}
+	testCases := []testCase{
+		{nil, []rune{'a', 'z'}, nil},
+		{[]rune{'a', 'z'}, []rune{'0', '9'}, nil},
+		{[]rune{'a', 'm'}, []rune{'h', 'z'}, []rune{'h', 'm'}},
+		{[]rune{'a', 'z'}, []rune{'b', 'b', 'x', 'y'}, []rune{'b', 'b', 'x', 'y'}},
+		{[]rune{'0', '9', 'a', 'f'}, []rune{'5', 'c'}, []rune{'5', '9', 'a', 'c'}},
+		{[]rune{'a', 'c', 'e', 'g'}, []rune{'c', 'e'}, []rune{'c', 'c', 'e', 'e'}},
+	}
+	for _, tc := range testCases {
+		got := Intersect(tc.a, tc.b)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Intersect(%q, %q) = %q, want %q", string(tc.a), string(tc.b), string(got), string(tc.want))
+		}
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	type testCase struct {
+		a, b []rune
+		want []rune
+	}
+	testCases := []testCase{
+		{nil, []rune{'a', 'z'}, nil},
+		{[]rune{'a', 'z'}, nil, []rune{'a', 'z'}},
+		{[]rune{'a', 'z'}, []rune{'0', '9'}, []rune{'a', 'z'}},
+		{[]rune{'a', 'm'}, []rune{'h', 'z'}, []rune{'a', 'g'}},
+		{[]rune{'a', 'z'}, []rune{'b', 'b', 'x', 'y'}, []rune{'a', 'a', 'c', 'w', 'z', 'z'}},
+		{[]rune{'0', '9', 'a', 'f'}, []rune{'5', 'c'}, []rune{'0', '4', 'd', 'f'}},
+		{[]rune{'a', 'c', 'e', 'g'}, []rune{'a', 'g'}, nil},
+		{[]rune{-100, -100, 'a', 'c'}, []rune{-100, -100}, []rune{'a', 'c'}},
+	}
+	for _, tc := range testCases {
+		got := Subtract(tc.a, tc.b)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Subtract(%q, %q) = %q, want %q", string(tc.a), string(tc.b), string(got), string(tc.want))
+		}
+	}
+}
+
 func TestFold(t *testing.T) {
 	type testCase struct {
 		in   []rune
@@ -117,6 +242,27 @@ func TestFold(t *testing.T) {
 	}
 }
 
+func TestValid(t *testing.T) {
+	type testCase struct {
+		in   []rune
+		want []rune
+	}
+	testCases := []testCase{
+		{nil, nil},
+		{[]rune{'a', 'z'}, []rune{'a', 'z'}},
+		{[]rune{0, unicode.MaxRune}, []rune{0, 0xd7ff, 0xe000, unicode.MaxRune}},
+		{[]rune{0xd800, 0xdfff}, nil},
+		{[]rune{-100, -100, 'a', 'a'}, []rune{'a', 'a'}},
+		{[]rune{'a', 'a', unicode.MaxRune + 1, unicode.MaxRune + 10}, []rune{'a', 'a'}},
+	}
+	for _, tc := range testCases {
+		got := Valid(tc.in)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Valid(%#v) = %#v, want %#v", tc.in, got, tc.want)
+		}
+	}
+}
+
 func TestSplit(t *testing.T) {
 	type testCase struct {
 		in   [][]rune
@@ -139,6 +285,7 @@ func TestSplit(t *testing.T) {
 		{[][]rune{{'a', 'p'}, {'n', 'z'}}, []rune{'a', 'm', 'n', 'p', 'q', 'z'}},
 		{[][]rune{{'a', 'c'}, {'d', 'f'}, {'g', 'i'}}, []rune{'a', 'c', 'd', 'f', 'g', 'i'}},
 		{[][]rune{{'a', 'd'}, {'d', 'f'}, {'f', 'i'}}, []rune{'a', 'c', 'd', 'd', 'e', 'e', 'f', 'f', 'g', 'i'}},
+		{[][]rune{{'n', 'p'}, {'m', 'z'}, {'a', 'c'}}, []rune{'a', 'c', 'm', 'm', 'n', 'p', 'q', 'z'}},
 	}
 	for _, tc := range testCases {
 		got := Split(tc.in)
@@ -147,3 +294,26 @@ func TestSplit(t *testing.T) {
 		}
 	}
 }
+
+func TestString(t *testing.T) {
+	type testCase struct {
+		in   []rune
+		want string
+	}
+	testCases := []testCase{
+		{[]rune{'a', 'a'}, "[a]"},
+		{[]rune{'0', '9', 'a', 'z'}, "[0-9a-z]"},
+		{[]rune{'a', 'b'}, "[ab]"},
+		{[]rune{'-', '-', ']', '^'}, `[\-\]\^]`},
+		{[]rune{0, '\n' - 1, '\n' + 1, unicode.MaxRune}, `[^\n]`},
+		{[]rune{0, '\n' - 1, '\n' + 1, 0xd7ff, 0xe000, unicode.MaxRune}, `[^\n]`},
+		{[]rune{0, 0x1f}, `[\x{0}-\x{1F}]`},
+		{[]rune{'α', 'ω'}, "[α-ω]"},
+	}
+	for _, tc := range testCases {
+		got := String(tc.in)
+		if got != tc.want {
+			t.Errorf("String(%q) = %s, want %s", string(tc.in), got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestTinyGoDeps(t *testing.T) {
+	// TinyGo sets the tinygo build tag; the core must then build without
+	// the packages too heavy for WebAssembly filters, even indirectly.
+	gocmd := filepath.Join(runtime.GOROOT(), "bin", "go")
+	if _, err := exec.LookPath(gocmd); err != nil {
+		t.Skip("no go command: ", err)
+	}
+	heavy := map[string]bool{"fmt": true, "log": true, "text/tabwriter": true, "html/template": true, "math/big": true}
+	for _, pkg := range []string{"dfa", "nfa", "runerange", "intersection", "multimatch"} {
+		out, err := exec.Command(gocmd, "list", "-tags", "tinygo", "-deps", "./"+pkg).Output()
+		if err != nil {
+			t.Fatalf("go list %s: %v", pkg, err)
+		}
+		for _, dep := range strings.Fields(string(out)) {
+			if heavy[dep] {
+				t.Errorf("%s depends on %s", pkg, dep)
+			}
+		}
+	}
+}
@@ -0,0 +1,384 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package triage computes the trigram queries implied by DFAs, for inverted
+// indexes to tell which stored documents could contain a match of a pattern
+// before running it on them, as codesearch does.
+package triage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/runerange"
+)
+
+const (
+	// maxClass is the largest class whose runes are tracked one by one.
+	maxClass = 4
+
+	// maxAlternatives bounds the alternatives of a query: beyond it they
+	// are replaced by the trigrams they have in common.
+	maxAlternatives = 32
+
+	// maxStates bounds the states of the automaton tracking the last two
+	// runes read. Beyond it the query matches every document.
+	maxStates = 10000
+)
+
+// Op is the operation of a query.
+type Op int
+
+const (
+	All  Op = iota // every document matches
+	None           // no document matches
+	And            // documents containing all trigrams and matching all subqueries
+	Or             // documents containing a trigram or matching a subquery
+)
+
+// Query is a boolean query on the trigrams of documents.
+type Query struct {
+	Op       Op
+	Trigrams []string
+	Sub      []*Query
+}
+
+// Eval reports whether a document matches the query, has telling whether
+// the document contains a trigram.
+func (q *Query) Eval(has func(trigram string) bool) bool {
+	switch q.Op {
+	case All:
+		return true
+	case None:
+		return false
+	case And:
+		for _, t := range q.Trigrams {
+			if !has(t) {
+				return false
+			}
+		}
+		for _, s := range q.Sub {
+			if !s.Eval(has) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, t := range q.Trigrams {
+		if has(t) {
+			return true
+		}
+	}
+	for _, s := range q.Sub {
+		if s.Eval(has) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the query in the notation of codesearch: "+" for All, "-"
+// for None, trigrams separated by spaces for And and by | in parentheses for
+// Or.
+func (q *Query) String() string {
+	switch q.Op {
+	case All:
+		return "+"
+	case None:
+		return "-"
+	}
+	var parts []string
+	for _, t := range q.Trigrams {
+		parts = append(parts, fmt.Sprintf("%q", t))
+	}
+	for _, s := range q.Sub {
+		parts = append(parts, s.String())
+	}
+	if q.Op == And {
+		return strings.Join(parts, " ")
+	}
+	return "(" + strings.Join(parts, " | ") + ")"
+}
+
+// Trigrams returns the distinct trigrams of text, sorted. Trigrams are
+// sequences of three runes, not bytes.
+func Trigrams(text string) []string {
+	runes := []rune(text)
+	seen := make(map[string]bool)
+	var result []string
+	for i := 0; i+3 <= len(runes); i++ {
+		t := string(runes[i : i+3])
+		if !seen[t] {
+			seen[t] = true
+			result = append(result, t)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// state is a state of the DFA along with the last two runes read, or fewer
+// if the runes before were not tracked.
+type state struct {
+	node *dfa.Node
+	last string
+}
+
+type edge struct {
+	to      state
+	trigram int // -1 if no trigram is completed
+}
+
+// family is a set of trigram sets, sorted, none containing another: a
+// document matches if it contains all trigrams of one of them.
+type family [][]int
+
+// DFAQuery returns a query that every document containing a string the DFA
+// accepts matches.
+func DFAQuery(n *dfa.Node) *Query {
+	var trigrams []string
+	ids := make(map[string]int)
+	id := func(t string) int {
+		if i, ok := ids[t]; ok {
+			return i
+		}
+		ids[t] = len(trigrams)
+		trigrams = append(trigrams, t)
+		return len(trigrams) - 1
+	}
+
+	start := state{n, ""}
+	edges := make(map[state][]edge)
+	preds := make(map[state][]state)
+	queue := []state{start}
+	edges[start] = nil
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		var out []edge
+		add := func(to state, trigram int) {
+			out = append(out, edge{to, trigram})
+			if _, ok := edges[to]; !ok {
+				edges[to] = nil
+				queue = append(queue, to)
+			}
+			preds[to] = append(preds[to], s)
+		}
+		for _, t := range s.node.Transitions {
+			runes := t.RuneRanges
+			if runerange.Count(runes) > maxClass {
+				add(state{t.Node, ""}, -1)
+				continue
+			}
+			for r := range runerange.Runes(runes) {
+				last := []rune(s.last + string(r))
+				trigram := -1
+				if len(last) == 3 {
+					trigram = id(string(last))
+					last = last[1:]
+				}
+				add(state{t.Node, string(last)}, trigram)
+			}
+		}
+		edges[s] = out
+		if len(edges) > maxStates {
+			return &Query{Op: All}
+		}
+	}
+
+	// The families only grow until they satisfy every state: those of final
+	// states contain the empty set and those of the others cover the
+	// families of their successors extended by the trigram on the way.
+	values := make(map[state]family)
+	work := make([]state, 0, len(edges))
+	queued := make(map[state]bool)
+	for s := range edges {
+		work = append(work, s)
+		queued[s] = true
+	}
+	for len(work) > 0 {
+		s := work[len(work)-1]
+		work = work[:len(work)-1]
+		queued[s] = false
+
+		f := append(family(nil), values[s]...)
+		if s.node.Final {
+			f = append(f, nil)
+		}
+		for _, e := range edges[s] {
+			for _, set := range values[e.to] {
+				if e.trigram >= 0 {
+					set = with(set, e.trigram)
+				}
+				f = append(f, set)
+			}
+		}
+		f = normalize(f)
+		if equal(f, values[s]) {
+			continue
+		}
+		values[s] = f
+		for _, p := range preds[s] {
+			if !queued[p] {
+				queued[p] = true
+				work = append(work, p)
+			}
+		}
+	}
+	return query(values[start], trigrams)
+}
+
+// with returns set with i added.
+func with(set []int, i int) []int {
+	j := sort.SearchInts(set, i)
+	if j < len(set) && set[j] == i {
+		return set
+	}
+	result := make([]int, 0, len(set)+1)
+	result = append(result, set[:j]...)
+	result = append(result, i)
+	return append(result, set[j:]...)
+}
+
+func subset(a, b []int) bool {
+	i := 0
+	for _, x := range b {
+		if i < len(a) && a[i] == x {
+			i++
+		}
+	}
+	return i == len(a)
+}
+
+func intersect(a, b []int) []int {
+	var result []int
+	for _, x := range a {
+		if j := sort.SearchInts(b, x); j < len(b) && b[j] == x {
+			result = append(result, x)
+		}
+	}
+	return result
+}
+
+func less(a, b []int) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+func equal(a, b family) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if less(a[i], b[i]) || less(b[i], a[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalize drops the sets containing others and replaces too many sets by
+// their intersection, which only makes the query match more documents.
+func normalize(f family) family {
+	sort.Slice(f, func(i, j int) bool {
+		if len(f[i]) != len(f[j]) {
+			return len(f[i]) < len(f[j])
+		}
+		return less(f[i], f[j])
+	})
+	var result family
+	for _, set := range f {
+		covered := false
+		for _, kept := range result {
+			if subset(kept, set) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			result = append(result, set)
+		}
+	}
+	if len(result) > maxAlternatives {
+		common := result[0]
+		for _, set := range result[1:] {
+			common = intersect(common, set)
+		}
+		result = family{common}
+	}
+	sort.Slice(result, func(i, j int) bool { return less(result[i], result[j]) })
+	return result
+}
+
+// query turns a family into a query, factoring out the trigrams its sets
+// have in common.
+func query(f family, trigrams []string) *Query {
+	if len(f) == 0 {
+		return &Query{Op: None}
+	}
+	names := func(set []int) []string {
+		result := make([]string, len(set))
+		for i, t := range set {
+			result[i] = trigrams[t]
+		}
+		sort.Strings(result)
+		return result
+	}
+	common := f[0]
+	for _, set := range f[1:] {
+		common = intersect(common, set)
+	}
+	q := &Query{Op: And, Trigrams: names(common)}
+	alternatives := len(f) > 1
+	for _, set := range f {
+		// Then the common trigrams alone satisfy the alternatives.
+		alternatives = alternatives && len(set) > len(common)
+	}
+	if alternatives {
+		or := &Query{Op: Or}
+		for _, set := range f {
+			rest := names(subtract(set, common))
+			if len(rest) == 1 {
+				or.Trigrams = append(or.Trigrams, rest[0])
+			} else {
+				or.Sub = append(or.Sub, &Query{Op: And, Trigrams: rest})
+			}
+		}
+		sort.Strings(or.Trigrams)
+		sort.Slice(or.Sub, func(i, j int) bool { return or.Sub[i].String() < or.Sub[j].String() })
+		q.Sub = []*Query{or}
+	}
+	if len(q.Trigrams) == 0 && len(q.Sub) == 0 {
+		return &Query{Op: All}
+	}
+	if len(q.Trigrams) == 0 {
+		return q.Sub[0]
+	}
+	return q
+}
+
+func subtract(a, b []int) []int {
+	var result []int
+	for _, x := range a {
+		if j := sort.SearchInts(b, x); j == len(b) || b[j] != x {
+			result = append(result, x)
+		}
+	}
+	return result
+}
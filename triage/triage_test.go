@@ -0,0 +1,90 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package triage
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/dfagen"
+	"github.com/oulinbao/regexinter/nfa"
+)
+
+func compile(t *testing.T, expr string) *dfa.Node {
+	t.Helper()
+	n, err := nfa.New(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dfa.NewFromNFA(n)
+}
+
+func TestDFAQuery(t *testing.T) {
+	type testCase struct {
+		expr string
+		want string
+	}
+	testCases := []testCase{
+		{"abc", `"abc"`},
+		{"abcd", `"abc" "bcd"`},
+		{"ab", "+"},
+		{"a.*b", "+"},
+		{dfa.NoMatch, "-"},
+		{"(abc|def)ghi", `"ghi" ("abc" "bcg" "cgh" | "def" "efg" "fgh")`},
+		{"abc|xyz", `("abc" | "xyz")`},
+		{"abc|abcd", `"abc"`},
+		{"a[bc]d", `("abd" | "acd")`},
+		{"x(abc)+y", `"abc" "bcy" "xab"`},
+		{".*Google.*", `"Goo" "gle" "ogl" "oog"`},
+		{"[a-z]+@example\\.com", `".co" "@ex" "amp" "com" "e.c" "exa" "le." "mpl" "ple" "xam"`},
+	}
+	for _, tc := range testCases {
+		if got := DFAQuery(compile(t, tc.expr)).String(); got != tc.want {
+			t.Errorf("DFAQuery(%q) = %s, want %s", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestDFAQueryMatches(t *testing.T) {
+	exprs := []string{
+		"(abc|def)ghi", "x(abc)+y", "[a-z]+@[a-z]+\\.com", "(?i)select", "a{2,5}bcd?e", "(ab|cd)*efg",
+	}
+	r := rand.New(rand.NewSource(1))
+	for _, e := range exprs {
+		n := compile(t, e)
+		q := DFAQuery(n)
+		g := dfagen.Matching(n)
+		for i := 0; i < 100; i++ {
+			s, _ := g.Generate(r, 10)
+			doc := "<" + s + ">"
+			has := make(map[string]bool)
+			for _, t := range Trigrams(doc) {
+				has[t] = true
+			}
+			if !q.Eval(func(t string) bool { return has[t] }) {
+				t.Errorf("%q containing a match of %q does not match %s", doc, e, q)
+			}
+		}
+	}
+}
+
+func TestTrigrams(t *testing.T) {
+	got := Trigrams("ababé")
+	want := []string{"aba", "abé", "bab"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Trigrams(ababé) = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,128 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Command wasm exposes the intersection checks to JavaScript, for editors to
+// validate patterns in the browser. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o reinter.wasm ./wasm
+//
+// and load it with the wasm_exec.js of the Go distribution. It defines a
+// global reinter object with three functions taking the options of
+// package reinter as an optional last argument, such as
+// {caseInsensitive: true, semantics: "prefix"}:
+//
+//	reinter.compile(expr)             {states} or {error}
+//	reinter.hasIntersection(a, b)     {intersects, approximate} or {error}
+//	reinter.witness(a, b)             {intersects, witness, explanation} or {error}
+package main
+
+import (
+	"time"
+
+	"github.com/oulinbao/regexinter/dfa"
+	"github.com/oulinbao/regexinter/reinter"
+)
+
+// The functions below take the arguments of the JavaScript calls converted
+// to Go values: strings, booleans, numbers as float64 and objects as
+// map[string]any. They return the objects handed back to JavaScript.
+
+func compile(args []any) map[string]any {
+	exprs, ok := patterns(args, 1)
+	if !ok {
+		return failure("compile takes a pattern")
+	}
+	n, err := reinter.Compile(exprs[0], options(args[1:])...)
+	if err != nil {
+		return failure(err.Error())
+	}
+	return map[string]any{"states": dfa.Freeze(n).States()}
+}
+
+func hasIntersection(args []any) map[string]any {
+	exprs, ok := patterns(args, 2)
+	if !ok {
+		return failure("hasIntersection takes two patterns")
+	}
+	result, err := reinter.Check(exprs[0], exprs[1], options(args[2:])...)
+	if err != nil {
+		return failure(err.Error())
+	}
+	return map[string]any{"intersects": result.Intersects, "approximate": result.Approximate}
+}
+
+func witness(args []any) map[string]any {
+	exprs, ok := patterns(args, 2)
+	if !ok {
+		return failure("witness takes two patterns")
+	}
+	result, err := reinter.Check(exprs[0], exprs[1], options(args[2:])...)
+	if err != nil {
+		return failure(err.Error())
+	}
+	return map[string]any{"intersects": result.Intersects, "witness": result.Witness, "explanation": result.Explanation}
+}
+
+func failure(msg string) map[string]any {
+	return map[string]any{"error": msg}
+}
+
+// patterns returns the first n arguments, and whether they are all strings.
+func patterns(args []any, n int) ([]string, bool) {
+	if len(args) < n {
+		return nil, false
+	}
+	exprs := make([]string, n)
+	for i := range exprs {
+		expr, ok := args[i].(string)
+		if !ok {
+			return nil, false
+		}
+		exprs[i] = expr
+	}
+	return exprs, true
+}
+
+// options converts the options object, if any, into options.
+func options(args []any) []reinter.Option {
+	if len(args) == 0 {
+		return nil
+	}
+	o, ok := args[0].(map[string]any)
+	if !ok {
+		return nil
+	}
+	flag := func(name string) bool {
+		v, _ := o[name].(bool)
+		return v
+	}
+	opts := []reinter.Option{
+		reinter.CaseInsensitive(flag("caseInsensitive")),
+		reinter.UnicodeMode(flag("unicode")),
+		reinter.ASCIIOnly(flag("ascii")),
+		reinter.OverApproximate(flag("approximate")),
+	}
+	switch o["semantics"] {
+	case "prefix":
+		opts = append(opts, reinter.MatchSemantics(reinter.PrefixOverlap))
+	case "substring":
+		opts = append(opts, reinter.MatchSemantics(reinter.SubstringMatch))
+	}
+	if v, ok := o["maxStates"].(float64); ok {
+		opts = append(opts, reinter.MaxStates(int(v)))
+	}
+	if v, ok := o["timeoutMs"].(float64); ok {
+		opts = append(opts, reinter.Timeout(time.Duration(v*float64(time.Millisecond))))
+	}
+	return opts
+}
@@ -0,0 +1,84 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCalls(t *testing.T) {
+	type testCase struct {
+		fn   func([]any) map[string]any
+		args []any
+		want map[string]any
+	}
+	testCases := []testCase{
+		{compile, []any{"abc"}, map[string]any{"states": 4}},
+		{compile, []any{"[a-c]{2}"}, map[string]any{"states": 3}},
+		{compile, []any{}, map[string]any{"error": "compile takes a pattern"}},
+		{compile, []any{1.0}, map[string]any{"error": "compile takes a pattern"}},
+		{hasIntersection, []any{"a+", "aa"}, map[string]any{"intersects": true, "approximate": false}},
+		{hasIntersection, []any{"a+", "b"}, map[string]any{"intersects": false, "approximate": false}},
+		{hasIntersection, []any{"a"}, map[string]any{"error": "hasIntersection takes two patterns"}},
+		{hasIntersection, []any{"a", nil}, map[string]any{"error": "hasIntersection takes two patterns"}},
+		{witness, []any{"[a-z]+[0-9]", "x[0-9]"}, map[string]any{"intersects": true, "witness": "x0", "explanation": ""}},
+		{witness, []any{"a", "b"}, map[string]any{"intersects": false, "witness": "", "explanation": "position 0: first pattern only allows [a], second only allows [b]"}},
+		{witness, []any{"a"}, map[string]any{"error": "witness takes two patterns"}},
+	}
+	for _, tc := range testCases {
+		if got := tc.fn(tc.args); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("call with %v = %v, want %v", tc.args, got, tc.want)
+		}
+	}
+
+	if got := compile([]any{"a("}); got["error"] == nil {
+		t.Errorf("compile(a() = %v, want an error", got)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	type testCase struct {
+		a, b string
+		opts any
+		want bool
+	}
+	testCases := []testCase{
+		{"abc", "ABC", nil, false},
+		{"abc", "ABC", map[string]any{"caseInsensitive": true}, true},
+		{"abc", "ABC", map[string]any{"caseInsensitive": "yes"}, false},
+		{"abc", "ABC", "caseInsensitive", false},
+		{"ab", "abc", nil, false},
+		{"ab", "abc", map[string]any{"semantics": "prefix"}, true},
+		{"b", "abc", map[string]any{"semantics": "prefix"}, false},
+		{"b", "abc", map[string]any{"semantics": "substring"}, true},
+		{"b", "abc", map[string]any{"semantics": 1.0}, false},
+	}
+	for _, tc := range testCases {
+		args := []any{tc.a, tc.b}
+		if tc.opts != nil {
+			args = append(args, tc.opts)
+		}
+		if got := hasIntersection(args); got["intersects"] != tc.want {
+			t.Errorf("hasIntersection(%q, %q, %v) = %v, want intersects %v", tc.a, tc.b, tc.opts, got, tc.want)
+		}
+	}
+
+	if got := compile([]any{"[ab]*a[ab]{8}", map[string]any{"maxStates": 10.0}}); got["error"] == nil {
+		t.Errorf("compile with maxStates 10 = %v, want an error", got)
+	}
+	if got := hasIntersection([]any{"[ab]*a[ab]{12}", "[ab]*b[ab]{12}", map[string]any{"timeoutMs": 0.001}}); got["error"] == nil {
+		t.Errorf("hasIntersection with timeoutMs 0.001 = %v, want an error", got)
+	}
+}
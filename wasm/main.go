@@ -0,0 +1,60 @@
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the Free
+// Software Foundation, either version 3 of the License, or (at your option)
+// any later version.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General
+// Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build js && wasm
+
+package main
+
+import "syscall/js"
+
+func main() {
+	js.Global().Set("reinter", js.ValueOf(map[string]any{
+		"compile":         export(compile),
+		"hasIntersection": export(hasIntersection),
+		"witness":         export(witness),
+	}))
+	select {}
+}
+
+// export wraps fn as a JavaScript function.
+func export(fn func(args []any) map[string]any) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		values := make([]any, len(args))
+		for i, arg := range args {
+			values[i] = value(arg)
+		}
+		return fn(values)
+	})
+}
+
+// value converts v into the Go values the functions of api.go take, nil for
+// the types they do not use.
+func value(v js.Value) any {
+	switch v.Type() {
+	case js.TypeString:
+		return v.String()
+	case js.TypeBoolean:
+		return v.Bool()
+	case js.TypeNumber:
+		return v.Float()
+	case js.TypeObject:
+		keys := js.Global().Get("Object").Call("keys", v)
+		o := make(map[string]any, keys.Length())
+		for i := 0; i < keys.Length(); i++ {
+			key := keys.Index(i).String()
+			o[key] = value(v.Get(key))
+		}
+		return o
+	}
+	return nil
+}